@@ -7,16 +7,21 @@ import (
 
 	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
 	"github.com/linkernetworks/vortex/src/prometheusprovider"
 	"github.com/linkernetworks/vortex/src/registry"
 )
 
 // Config is the structure for vortex
 type Config struct {
-	Mongo      *mongo.MongoConfig                   `json:"mongo"`
-	Prometheus *prometheusprovider.PrometheusConfig `json:"prometheus"`
-	Registry   *registry.Config                     `json:"registry"`
-	Logger     logger.LoggerConfig                  `json:"logger"`
+	Mongo             *mongo.MongoConfig                   `json:"mongo"`
+	Prometheus        *prometheusprovider.PrometheusConfig `json:"prometheus"`
+	Registry          *registry.Config                     `json:"registry"`
+	NetworkController *networkcontroller.Config            `json:"networkController"`
+	Logger            logger.LoggerConfig                  `json:"logger"`
+
+	// the url of vortex server which can be accessed by the pods, it's used to lease the ip addresses
+	ServerURL string `json:"serverURL"`
 
 	// the version settings of the current application
 	Version string `json:"version"`
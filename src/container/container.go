@@ -14,6 +14,7 @@
 package container
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 
@@ -139,3 +140,15 @@ func HandleDownload(resp *restful.Response, result io.ReadCloser) error {
 	}
 	return nil
 }
+
+// HandleFileDownload streams the result as the attachment with the file name
+func HandleFileDownload(resp *restful.Response, result io.ReadCloser, contentType string, fileName string) error {
+	resp.AddHeader(restful.HEADER_ContentType, contentType)
+	resp.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	defer result.Close()
+	_, err := io.Copy(resp, result)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -2,11 +2,17 @@ package deployment
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/mongo"
 	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/ipam"
+	"github.com/linkernetworks/vortex/src/kubeutils"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	np "github.com/linkernetworks/vortex/src/networkprovider"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 	"github.com/linkernetworks/vortex/src/utils"
 	appsv1 "k8s.io/api/apps/v1"
@@ -14,6 +20,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -25,12 +32,78 @@ const VolumeNamePrefix = "volume-"
 // DefaultLabel is the  label we used for our deploying application/deployment/pods
 const DefaultLabel = "vortex"
 
+// LeaseTokenLength is the bytes of the token for the init containers to lease the ip addresses
+const LeaseTokenLength = 16
+
 // CheckDeploymentParameter will Check Deployment's Parameter
 func CheckDeploymentParameter(sp *serviceprovider.Container, deploy *entity.Deployment) error {
 	session := sp.Mongo.NewSession()
 	defer session.Close()
 
 	//Check the volume
+	if err := checkVolumes(session, deploy); err != nil {
+		return err
+	}
+	if err := checkStrategy(deploy); err != nil {
+		return err
+	}
+	if err := checkAutoscaler(deploy); err != nil {
+		return err
+	}
+	if err := kubeutils.CheckResources(deploy.Containers); err != nil {
+		return err
+	}
+	if err := kubeutils.CheckContainers(deploy.Containers); err != nil {
+		return err
+	}
+
+	//Check the network
+	for _, v := range deploy.Networks {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": v.Name}, &network); err != nil {
+			if err == mgo.ErrNotFound {
+				return fmt.Errorf("the network named %s doesn't exist", v.Name)
+			}
+			return fmt.Errorf("check the network name error:%v", err)
+		}
+		//The ip address is leased from the subnets by each replica if it's not set
+		if v.IPAddress == "" && len(v.Addresses) == 0 && len(network.Subnets) == 0 {
+			return fmt.Errorf("the ip address is required since the network %s doesn't have any subnet", v.Name)
+		}
+		if v.IPAddress != "" && v.Netmask == "" && len(network.Subnets) == 0 {
+			return fmt.Errorf("the netmask is required since the network %s doesn't have any subnet", v.Name)
+		}
+		if err := checkNetworkAddresses(v); err != nil {
+			return err
+		}
+		if v.QoS != nil && network.Type != entity.OVSKernelspaceNetworkType && network.Type != entity.OVSUserspaceNetworkType {
+			return fmt.Errorf("the qos is only for the OVS networks, the network %s is %s", v.Name, network.Type)
+		}
+		//The vlan of the linux network is the sub-interface of the physical interface attached to the bridge
+		if v.VlanTag != nil && network.Type == entity.LinuxBridgeNetworkType {
+			return fmt.Errorf("the vlanTag can't be set on the linux network %s, the vlan is set by the vlanTags of the network", v.Name)
+		}
+		//The mac address is set to the virtual function by the client
+		if v.MacAddress != "" && network.Type != entity.SRIOVNetworkType {
+			return fmt.Errorf("the macAddress is only for the sriov networks, the network %s is %s", v.Name, network.Type)
+		}
+	}
+	if err := checkSharedAddresses(deploy.Networks, deploy.Replicas); err != nil {
+		return err
+	}
+
+	//Check the leases of the deployment which has the same name
+	count, err := session.Count(entity.IPLeaseCollectionName, bson.M{"namespace": deploy.Namespace, "deploymentName": deploy.Name})
+	if err != nil {
+		return fmt.Errorf("check the ip leases error:%v", err)
+	} else if count != 0 {
+		return fmt.Errorf("the deployment %s has leased the ip addresses", deploy.Name)
+	}
+
+	return nil
+}
+
+func checkVolumes(session *mongo.Session, deploy *entity.Deployment) error {
 	for _, v := range deploy.Volumes {
 		count, err := session.Count(entity.VolumeCollectionName, bson.M{"name": v.Name})
 		if err != nil {
@@ -39,17 +112,43 @@ func CheckDeploymentParameter(sp *serviceprovider.Container, deploy *entity.Depl
 			return fmt.Errorf("The volume name %s doesn't exist", v.Name)
 		}
 	}
+	return nil
+}
 
-	//Check the network
-	for _, v := range deploy.Networks {
-		count, err := session.Count(entity.NetworkCollectionName, bson.M{"name": v.Name})
-		if err != nil {
-			return fmt.Errorf("check the network name error:%v", err)
-		} else if count == 0 {
-			return fmt.Errorf("the network named %s doesn't exist", v.Name)
+// The static addresses of the networks are set to each replica, so they can't be shared by the replicas
+func checkSharedAddresses(networks []entity.DeploymentNetwork, replicas int32) error {
+	if replicas <= 1 {
+		return nil
+	}
+	for _, v := range networks {
+		if v.IPAddress != "" || len(v.Addresses) != 0 {
+			return fmt.Errorf("the static ip address of network %s can't be shared by %d replicas", v.Name, replicas)
+		}
+		if v.MacAddress != "" {
+			return fmt.Errorf("the mac address %s of network %s can't be shared by %d replicas", v.MacAddress, v.Name, replicas)
 		}
 	}
+	return nil
+}
 
+// The address family of each value should be the same as its ip address or destination
+func checkNetworkAddresses(network entity.DeploymentNetwork) error {
+	if network.IPAddress != "" {
+		//The subnets are ipv4, so the ipv6 address can't get its netmask from the lease
+		if network.Netmask == "" && utils.IPFamily(network.IPAddress) == 6 {
+			return fmt.Errorf("the prefix length of the ipv6 address %s is required", network.IPAddress)
+		}
+		if network.Netmask != "" {
+			if err := utils.CheckNetmask(network.IPAddress, network.Netmask); err != nil {
+				return err
+			}
+		}
+	}
+	for _, route := range network.RoutesGw {
+		if utils.IPFamily(route.DstCIDR) != utils.IPFamily(route.Gateway) {
+			return fmt.Errorf("the gateway %s and the destination %s aren't the same address family", route.Gateway, route.DstCIDR)
+		}
+	}
 	return nil
 }
 
@@ -98,16 +197,82 @@ func generateNodeLabels(networks []entity.Network) []string {
 	return utils.Intersections(totalNames)
 }
 
-func generateClientCommand(network entity.DeploymentNetwork) (command []string) {
-	ip := utils.IPToCIDR(network.IPAddress, network.Netmask)
+// Each address of the interface is passed by its own --ip flag, the ipv4 and ipv6 addresses can be mixed
+func generateIPArgs(network entity.DeploymentNetwork) []string {
+	args := []string{}
+	if network.LeaseURL != "" {
+		//The ip address is leased by the init container
+		args = append(args, "--ip=${IP_CIDR}")
+	} else if network.IPAddress != "" {
+		args = append(args, "--ip="+utils.IPToCIDR(network.IPAddress, network.Netmask))
+	}
+	for _, address := range network.Addresses {
+		args = append(args, "--ip="+address)
+	}
+	return args
+}
 
+//The client attaches the veth to the OVS bridge, or to the Linux bridge if it's the linux network
+func generateClientCommand(network entity.DeploymentNetwork) (command []string) {
 	command = []string{
 		"--server=unix:///tmp/vortex.sock",
 		"--bridge=" + network.BridgeName,
+	}
+	if network.NetworkType == entity.LinuxBridgeNetworkType {
+		command = append(command, "--linux-bridge")
+	}
+	command = append(command, "--nic="+network.IfName)
+	command = append(command, generateIPArgs(network)...)
+
+	if network.VlanTag != nil {
+		command = append(command, "--vlan="+strconv.Itoa((int)(*network.VlanTag)))
+	}
+	if qos := network.QoS; qos != nil {
+		if qos.IngressRate > 0 {
+			command = append(command, "--ingress-rate="+strconv.FormatInt(qos.IngressRate, 10))
+			if qos.IngressBurst > 0 {
+				command = append(command, "--ingress-burst="+strconv.FormatInt(qos.IngressBurst, 10))
+			}
+		}
+		if qos.EgressRate > 0 {
+			command = append(command, "--egress-rate="+strconv.FormatInt(qos.EgressRate, 10))
+			if qos.EgressBurst > 0 {
+				command = append(command, "--egress-burst="+strconv.FormatInt(qos.EgressBurst, 10))
+			}
+		}
+	}
+	if len(network.RoutesGw) != 0 {
+		for _, netroute := range network.RoutesGw {
+			command = append(command, "--route-gw="+netroute.DstCIDR+","+netroute.Gateway)
+		}
+	}
+	if len(network.RoutesIntf) != 0 {
+		for _, netroute := range network.RoutesIntf {
+			command = append(command, "--route-intf="+netroute.DstCIDR)
+		}
+	}
+	return
+}
+
+//The sriov init step moves a free virtual function of the physical function on the current node into the pod
+//and the physical function is chosen by the NODE_NAME
+func generateSRIOVClientCommand(network entity.DeploymentNetwork) (command []string) {
+	command = []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--sriov",
+		"--node=$(NODE_NAME)",
 		"--nic=" + network.IfName,
-		"--ip=" + ip,
 	}
+	command = append(command, generateIPArgs(network)...)
 
+	for _, node := range network.Nodes {
+		for _, phyIface := range node.PhyInterfaces {
+			command = append(command, "--pf="+node.Name+","+phyIface.Name)
+		}
+	}
+	if network.MacAddress != "" {
+		command = append(command, "--mac="+network.MacAddress)
+	}
 	if network.VlanTag != nil {
 		command = append(command, "--vlan="+strconv.Itoa((int)(*network.VlanTag)))
 	}
@@ -124,42 +289,74 @@ func generateClientCommand(network entity.DeploymentNetwork) (command []string)
 	return
 }
 
+//All replicas share the same pod template, so each replica leases its ip address from vortex at runtime
+//and the client is run by the shell with the leased ip address.
+//The wget is in the client image, see the check-client-image target of the Makefile.
+func generateLeaseScript(leaseURL string, args []string) string {
+	quoted := []string{}
+	for _, arg := range args {
+		quoted = append(quoted, strconv.Quote(arg))
+	}
+	return fmt.Sprintf("IP_CIDR=`wget -qO- --post-data='' '%s'` && exec /go/bin/client %s", leaseURL, strings.Join(quoted, " "))
+}
+
 func generateInitContainer(networks []entity.DeploymentNetwork) ([]corev1.Container, error) {
 	containers := []corev1.Container{}
 
 	ethtools := []string{}
 	for i, v := range networks {
-		containers = append(containers, corev1.Container{
-			Name:    fmt.Sprintf("init-network-client-%d", i),
-			Image:   "sdnvortex/network-controller:v0.4.8",
-			Command: []string{"/go/bin/client"},
-			Args:    generateClientCommand(v),
-			Env: []corev1.EnvVar{
-				{
-					Name: "POD_NAME",
-					ValueFrom: &corev1.EnvVarSource{
-						FieldRef: &corev1.ObjectFieldSelector{
-							FieldPath: "metadata.name",
-						},
+		args := generateClientCommand(v)
+		envVars := []corev1.EnvVar{
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.name",
 					},
 				},
-				{
-					Name: "POD_NAMESPACE",
-					ValueFrom: &corev1.EnvVarSource{
-						FieldRef: &corev1.ObjectFieldSelector{
-							FieldPath: "metadata.namespace",
-						},
+			},
+			{
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.namespace",
 					},
 				},
-				{
-					Name: "POD_UUID",
-					ValueFrom: &corev1.EnvVarSource{
-						FieldRef: &corev1.ObjectFieldSelector{
-							FieldPath: "metadata.uid",
-						},
+			},
+			{
+				Name: "POD_UUID",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.uid",
 					},
 				},
 			},
+		}
+
+		if v.NetworkType == entity.SRIOVNetworkType {
+			args = generateSRIOVClientCommand(v)
+			envVars = append(envVars, corev1.EnvVar{
+				Name: "NODE_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "spec.nodeName",
+					},
+				},
+			})
+		}
+
+		command := []string{"/go/bin/client"}
+		if v.LeaseURL != "" {
+			command = []string{"/bin/sh", "-c"}
+			args = []string{generateLeaseScript(v.LeaseURL, args)}
+		}
+
+		containers = append(containers, corev1.Container{
+			Name:    fmt.Sprintf("init-network-client-%d", i),
+			Image:   networkcontroller.ClientImage,
+			Command: command,
+			Args:    args,
+			Env:     envVars,
 			VolumeMounts: []corev1.VolumeMount{
 				{
 					Name:      "grpc-sock",
@@ -201,6 +398,10 @@ func generateNetwork(session *mongo.Session, deploy *entity.Deployment) ([]strin
 		}
 		networks = append(networks, network)
 		deploy.Networks[i].BridgeName = network.BridgeName
+		deploy.Networks[i].NetworkType = network.Type
+		if network.Type == entity.SRIOVNetworkType {
+			deploy.Networks[i].Nodes = network.Nodes
+		}
 	}
 
 	nodes := generateNodeLabels(networks)
@@ -208,6 +409,135 @@ func generateNetwork(session *mongo.Session, deploy *entity.Deployment) ([]strin
 	return nodes, containers, err
 }
 
+//The replicas lease the ip addresses from the subnets of network by the init containers if it's not set,
+//and the static ip address is leased by the deployment itself to avoid the conflict with the leased ones.
+func generateIPLeases(sp *serviceprovider.Container, session *mongo.Session, deploy *entity.Deployment) error {
+	for i, v := range deploy.Networks {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": v.Name}, &network); err != nil {
+			return err
+		}
+		//The subnets are ipv4, the ipv6 address is always static
+		if len(network.Subnets) == 0 || utils.IPFamily(v.IPAddress) == 6 {
+			continue
+		}
+
+		if v.IPAddress == "" {
+			leaseURL, err := generateLeaseURL(sp, deploy, v)
+			if err != nil {
+				return err
+			}
+			deploy.Networks[i].LeaseURL = leaseURL
+			continue
+		}
+
+		lease := entity.IPLease{
+			Namespace:      deploy.Namespace,
+			PodName:        deploy.Name,
+			IfName:         v.IfName,
+			IPAddress:      v.IPAddress,
+			DeploymentName: deploy.Name,
+		}
+		if err := ipam.Reserve(sp, &network, &lease); err != nil {
+			return err
+		}
+		if v.Netmask == "" {
+			deploy.Networks[i].Netmask = lease.Netmask
+		}
+	}
+	return nil
+}
+
+// The leased ip addresses are kept when the deployment is updated, so only the urls for leasing are generated again
+func generateLeaseURLs(sp *serviceprovider.Container, session *mongo.Session, deploy *entity.Deployment) error {
+	for i, v := range deploy.Networks {
+		if v.IPAddress != "" {
+			continue
+		}
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": v.Name}, &network); err != nil {
+			return err
+		}
+		if len(network.Subnets) == 0 {
+			continue
+		}
+		leaseURL, err := generateLeaseURL(sp, deploy, v)
+		if err != nil {
+			return err
+		}
+		deploy.Networks[i].LeaseURL = leaseURL
+	}
+	return nil
+}
+
+// The lease url has the lease token of the deployment, it's generated for the deployment created before the token
+func generateLeaseURL(sp *serviceprovider.Container, deploy *entity.Deployment, network entity.DeploymentNetwork) (string, error) {
+	if sp.Config.ServerURL == "" {
+		return "", fmt.Errorf("the serverURL isn't set for leasing the ip address of network %s", network.Name)
+	}
+	if deploy.LeaseToken == "" {
+		token, err := utils.RandomToken(LeaseTokenLength)
+		if err != nil {
+			return "", err
+		}
+		deploy.LeaseToken = token
+	}
+	//The POD_NAMESPACE and POD_NAME are expanded by the kubernetes
+	return fmt.Sprintf("%s/v1/ipam/leases?network=%s&namespace=$(POD_NAMESPACE)&pod=$(POD_NAME)&ifName=%s&deployment=%s&token=%s&output=cidr",
+		sp.Config.ServerURL,
+		url.QueryEscape(network.Name),
+		url.QueryEscape(network.IfName),
+		url.QueryEscape(deploy.Name),
+		deploy.LeaseToken,
+	), nil
+}
+
+//The pods can only be scheduled to the nodes which have enough free virtual functions for the sriov networks
+//and those nodes should have enough virtual functions for all replicas.
+func generateSRIOVNodes(sp *serviceprovider.Container, session *mongo.Session, deploy *entity.Deployment, nodeNames []string) ([]string, error) {
+	required := map[string]int{}
+	for _, v := range deploy.Networks {
+		if v.NetworkType == entity.SRIOVNetworkType {
+			required[v.Name]++
+		}
+	}
+
+	for name, count := range required {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": name}, &network); err != nil {
+			return nil, err
+		}
+		free, err := np.GetFreeVFs(sp, &network)
+		if err != nil {
+			return nil, err
+		}
+
+		names := []string{}
+		for _, node := range network.Nodes {
+			if free[node.Name] >= count {
+				names = append(names, node.Name)
+			}
+		}
+		if len(nodeNames) == 0 {
+			nodeNames = names
+		} else {
+			nodeNames = utils.Intersection(names, nodeNames)
+		}
+		if len(nodeNames) == 0 {
+			return nil, fmt.Errorf("there's no node with enough free virtual functions of the network %s", name)
+		}
+
+		capacity := 0
+		for _, nodeName := range nodeNames {
+			capacity += free[nodeName] / count
+		}
+		if capacity < int(deploy.Replicas) {
+			return nil, fmt.Errorf("the free virtual functions of the network %s are only enough for %d replicas", name, capacity)
+		}
+	}
+	return nodeNames, nil
+}
+
 func generateContainerSecurity(deploy *entity.Deployment) *corev1.SecurityContext {
 	if !deploy.Capability {
 		return &corev1.SecurityContext{}
@@ -262,11 +592,49 @@ func CreateDeployment(sp *serviceprovider.Container, deploy *entity.Deployment)
 	session := sp.Mongo.NewSession()
 	defer session.Close()
 
-	volumes, volumeMounts, err := generateVolume(session, deploy)
+	if deploy.Namespace == "" {
+		deploy.Namespace = "default"
+	}
+
+	if deploy.NetworkType == entity.DeploymentCustomNetwork {
+		if err := generateIPLeases(sp, session, deploy); err != nil {
+			ipam.ReleaseDeployment(session, deploy.Namespace, deploy.Name)
+			return err
+		}
+	}
+
+	p, err := generateDeployment(sp, session, deploy)
 	if err != nil {
+		ipam.ReleaseDeployment(session, deploy.Namespace, deploy.Name)
+		return err
+	}
+
+	if err = kubeutils.CheckNodeAllocatable(sp, &p.Spec.Template.Spec, deploy.Replicas); err != nil {
+		ipam.ReleaseDeployment(session, deploy.Namespace, deploy.Name)
 		return err
 	}
 
+	if _, err = sp.KubeCtl.CreateDeployment(p, deploy.Namespace); err != nil {
+		ipam.ReleaseDeployment(session, deploy.Namespace, deploy.Name)
+		return err
+	}
+	//The deployment isn't saved if its autoscaler fails, so it's deleted with the leases to be created again
+	if err = SetAutoscaler(sp, deploy); err != nil {
+		if err := DeleteDeployment(sp, deploy); err != nil {
+			logger.Warnf("delete the deployment %s after its autoscaler fails: %v", deploy.Name, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// The kubernetes deployment is generated by the deployment, the ip addresses of the custom networks should be leased before
+func generateDeployment(sp *serviceprovider.Container, session *mongo.Session, deploy *entity.Deployment) (*appsv1.Deployment, error) {
+	volumes, volumeMounts, err := generateVolume(session, deploy)
+	if err != nil {
+		return nil, err
+	}
+
 	nodeAffinity := deploy.NodeAffinity
 	initContainers := []corev1.Container{}
 	hostNetwork := false
@@ -279,6 +647,9 @@ func CreateDeployment(sp *serviceprovider.Container, deploy *entity.Deployment)
 		if len(tmp) != 0 {
 			nodeAffinity = utils.Intersection(nodeAffinity, tmp)
 		}
+		if err == nil {
+			nodeAffinity, err = generateSRIOVNodes(sp, session, deploy, nodeAffinity)
+		}
 	case entity.DeploymentClusterNetwork:
 		//For cluster network, we won't set the nodeAffinity and any network options.
 	default:
@@ -286,7 +657,12 @@ func CreateDeployment(sp *serviceprovider.Container, deploy *entity.Deployment)
 	}
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	strategy, err := generateStrategy(deploy.Strategy)
+	if err != nil {
+		return nil, err
 	}
 
 	volumes = append(volumes, corev1.Volume{
@@ -298,21 +674,51 @@ func CreateDeployment(sp *serviceprovider.Container, deploy *entity.Deployment)
 		},
 	})
 
+	//The hugepages are mounted only to the containers which request them
+	if volume := kubeutils.GenerateHugepageVolume(deploy.Containers); volume != nil {
+		volumes = append(volumes, *volume)
+	}
+
 	var containers []corev1.Container
 	securityContext := generateContainerSecurity(deploy)
 	envVars := generateEnvVars(deploy)
 	for _, container := range deploy.Containers {
+		resources, err := kubeutils.GenerateResources(container)
+		if err != nil {
+			return nil, err
+		}
+		livenessProbe, err := kubeutils.GenerateProbe(container.LivenessProbe)
+		if err != nil {
+			return nil, err
+		}
+		readinessProbe, err := kubeutils.GenerateProbe(container.ReadinessProbe)
+		if err != nil {
+			return nil, err
+		}
+		mounts := volumeMounts
+		if kubeutils.HasHugepages(container) {
+			mounts = append(append([]corev1.VolumeMount{}, volumeMounts...), corev1.VolumeMount{
+				Name:      kubeutils.HugepageVolumeName,
+				MountPath: kubeutils.HugepageMountPath,
+			})
+		}
 		containers = append(containers, corev1.Container{
 			Name:            container.Name,
 			Image:           container.Image,
 			Command:         container.Command,
-			VolumeMounts:    volumeMounts,
+			Args:            container.Args,
+			WorkingDir:      container.WorkingDir,
+			Ports:           kubeutils.GeneratePorts(container),
+			VolumeMounts:    mounts,
 			SecurityContext: securityContext,
-			Env:             envVars,
+			Env:             kubeutils.GenerateEnvVars(envVars, container),
+			Resources:       resources,
+			LivenessProbe:   livenessProbe,
+			ReadinessProbe:  readinessProbe,
 		})
 	}
 
-	p := appsv1.Deployment{
+	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   deploy.Name,
 			Labels: deploy.Labels,
@@ -324,9 +730,7 @@ func CreateDeployment(sp *serviceprovider.Container, deploy *entity.Deployment)
 				},
 			},
 			Replicas: &deploy.Replicas,
-			Strategy: appsv1.DeploymentStrategy{
-				Type: appsv1.RecreateDeploymentStrategyType,
-			},
+			Strategy: strategy,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -346,16 +750,19 @@ func CreateDeployment(sp *serviceprovider.Container, deploy *entity.Deployment)
 				},
 			},
 		},
-	}
-
-	if deploy.Namespace == "" {
-		deploy.Namespace = "default"
-	}
-	_, err = sp.KubeCtl.CreateDeployment(&p, deploy.Namespace)
-	return err
+	}, nil
 }
 
-// DeleteDeployment will delete a deployment
+// DeleteDeployment will delete a deployment with its autoscaler and release the ip leases of its pods
 func DeleteDeployment(sp *serviceprovider.Container, deploy *entity.Deployment) error {
-	return sp.KubeCtl.DeleteDeployment(deploy.Name, deploy.Namespace)
+	if err := DeleteAutoscaler(sp, deploy); err != nil {
+		return err
+	}
+	if err := sp.KubeCtl.DeleteDeployment(deploy.Name, deploy.Namespace); err != nil {
+		return err
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	return ipam.ReleaseDeployment(session, deploy.Namespace, deploy.Name)
 }
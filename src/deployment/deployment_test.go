@@ -10,7 +10,12 @@ import (
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 	"github.com/moby/moby/pkg/namesgenerator"
 	"github.com/stretchr/testify/suite"
+	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func init() {
@@ -66,6 +71,48 @@ func (suite *DeploymentTestSuite) TestCheckDeploymentParameter() {
 	suite.NoError(err)
 }
 
+func (suite *DeploymentTestSuite) TestCheckDeploymentParameterWithMacAddress() {
+	session := suite.sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := entity.Network{
+		ID:      bson.NewObjectId(),
+		Name:    namesgenerator.GetRandomName(0),
+		Type:    entity.SRIOVNetworkType,
+		Subnets: []string{"10.10.0.0/24"},
+	}
+	session.Insert(entity.NetworkCollectionName, network)
+	defer session.Remove(entity.NetworkCollectionName, "name", network.Name)
+
+	deploy := &entity.Deployment{
+		ID:       bson.NewObjectId(),
+		Name:     namesgenerator.GetRandomName(0),
+		Replicas: 1,
+		Networks: []entity.DeploymentNetwork{
+			{Name: network.Name, IfName: "eth1", MacAddress: "02:00:00:00:00:01"},
+		},
+	}
+	suite.NoError(CheckDeploymentParameter(suite.sp, deploy))
+
+	//Every replica would have the same mac address
+	deploy.Replicas = 2
+	suite.Error(CheckDeploymentParameter(suite.sp, deploy))
+	suite.Error(checkSharedAddresses(deploy.Networks, 2))
+
+	//The mac address isn't set on the other networks
+	other := entity.Network{
+		ID:      bson.NewObjectId(),
+		Name:    namesgenerator.GetRandomName(0),
+		Type:    entity.OVSKernelspaceNetworkType,
+		Subnets: []string{"10.11.0.0/24"},
+	}
+	session.Insert(entity.NetworkCollectionName, other)
+	defer session.Remove(entity.NetworkCollectionName, "name", other.Name)
+	deploy.Replicas = 1
+	deploy.Networks[0].Name = other.Name
+	suite.Error(CheckDeploymentParameter(suite.sp, deploy))
+}
+
 func (suite *DeploymentTestSuite) TestCheckDeploymentParameterFail() {
 	testCases := []struct {
 		caseName string
@@ -168,6 +215,70 @@ func (suite *DeploymentTestSuite) TestCreateDeployment() {
 	suite.NoError(err)
 }
 
+func (suite *DeploymentTestSuite) TestCreateDeploymentWithContainerOptions() {
+	containers := []entity.Container{
+		{
+			Name:       namesgenerator.GetRandomName(0),
+			Image:      "nginx",
+			Command:    []string{"nginx"},
+			Args:       []string{"-g", "daemon off;"},
+			WorkingDir: "/usr/share/nginx",
+			EnvVars:    map[string]string{"MODE": "container"},
+			Ports: []entity.ContainerPort{
+				{Name: "http", ContainerPort: 80},
+				{ContainerPort: 53, Protocol: "UDP"},
+			},
+			LivenessProbe:  &entity.ContainerProbe{Type: entity.ContainerProbeHTTPGet, Path: "/healthz", Port: 80, PeriodSeconds: 10},
+			ReadinessProbe: &entity.ContainerProbe{Type: entity.ContainerProbeTCPSocket, Port: 80},
+		},
+	}
+
+	deployName := namesgenerator.GetRandomName(0)
+	deploy := &entity.Deployment{
+		ID:          bson.NewObjectId(),
+		Name:        deployName,
+		Namespace:   "default",
+		Containers:  containers,
+		NetworkType: entity.DeploymentClusterNetwork,
+		EnvVars: map[string]string{
+			"MODE":   "shared",
+			"SHARED": "1",
+		},
+		Replicas: 1,
+	}
+
+	err := CheckDeploymentParameter(suite.sp, deploy)
+	suite.NoError(err)
+	err = CreateDeployment(suite.sp, deploy)
+	suite.NoError(err)
+	defer DeleteDeployment(suite.sp, deploy)
+
+	current, err := suite.sp.KubeCtl.GetDeployment(deployName, "default")
+	suite.NoError(err)
+	suite.Len(current.Spec.Template.Spec.Containers, 1)
+	container := current.Spec.Template.Spec.Containers[0]
+	suite.Equal([]string{"nginx"}, container.Command)
+	suite.Equal([]string{"-g", "daemon off;"}, container.Args)
+	suite.Equal("/usr/share/nginx", container.WorkingDir)
+	suite.Equal([]corev1.ContainerPort{
+		{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+		{ContainerPort: 53, Protocol: corev1.ProtocolUDP},
+	}, container.Ports)
+
+	suite.NotNil(container.LivenessProbe)
+	suite.Equal("/healthz", container.LivenessProbe.HTTPGet.Path)
+	suite.Equal(intstr.FromInt(80), container.LivenessProbe.HTTPGet.Port)
+	suite.Equal(int32(10), container.LivenessProbe.PeriodSeconds)
+	suite.NotNil(container.ReadinessProbe)
+	suite.Equal(intstr.FromInt(80), container.ReadinessProbe.TCPSocket.Port)
+
+	//The env of the container overrides the shared one with the same name
+	suite.Equal([]corev1.EnvVar{
+		{Name: "SHARED", Value: "1"},
+		{Name: "MODE", Value: "container"},
+	}, container.Env)
+}
+
 func (suite *DeploymentTestSuite) TestCreateDeploymentFailWithoutVolume() {
 	containers := []entity.Container{
 		{
@@ -281,6 +392,72 @@ func (suite *DeploymentTestSuite) TestGenerateClientCommand() {
 	}
 	suite.Equal(ans, command)
 
+	deployNetwork.QoS = &entity.NetworkQoS{
+		EgressRate:  20000,
+		EgressBurst: 2000,
+	}
+	command = generateClientCommand(deployNetwork)
+	ans = []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--bridge=" + bName,
+		"--nic=" + ifName,
+		"--ip=1.2.3.4/24",
+		"--vlan=123",
+		"--egress-rate=20000",
+		"--egress-burst=2000",
+	}
+	suite.Equal(ans, command)
+}
+
+func (suite *DeploymentTestSuite) TestGenerateSRIOVClientCommand() {
+	ifName := namesgenerator.GetRandomName(0)
+	var vlanTag int32
+	vlanTag = 100
+	network := entity.DeploymentNetwork{
+		Name:       "my-sriov-net",
+		IfName:     ifName,
+		IPAddress:  "1.2.3.4",
+		Netmask:    "255.255.255.0",
+		MacAddress: "aa:bb:cc:dd:ee:ff",
+		VlanTag:    &vlanTag,
+		RoutesGw: []entity.DeploymentRouteGw{
+			{
+				DstCIDR: "192.168.2.0/24",
+				Gateway: "192.168.2.254",
+			},
+		},
+		NetworkType: entity.SRIOVNetworkType,
+		Nodes: []entity.Node{
+			{
+				Name:          "node1",
+				PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 4}},
+			},
+			{
+				Name:          "node2",
+				PhyInterfaces: []entity.PhyInterface{{Name: "eth2", PCIID: "0000:03:00.0", NumVFs: 4}},
+			},
+		},
+	}
+	command := generateSRIOVClientCommand(network)
+	ans := []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--sriov",
+		"--node=$(NODE_NAME)",
+		"--nic=" + ifName,
+		"--ip=1.2.3.4/24",
+		"--pf=node1,eth1",
+		"--pf=node2,eth2",
+		"--mac=aa:bb:cc:dd:ee:ff",
+		"--vlan=100",
+		"--route-gw=192.168.2.0/24,192.168.2.254",
+	}
+	suite.Equal(ans, command)
+
+	containers, err := generateInitContainer([]entity.DeploymentNetwork{network})
+	suite.NoError(err)
+	suite.Equal(1, len(containers))
+	suite.Equal(ans, containers[0].Args)
+	suite.Equal("NODE_NAME", containers[0].Env[len(containers[0].Env)-1].Name)
 }
 
 func (suite *DeploymentTestSuite) TestGenerateNetwork() {
@@ -454,3 +631,348 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentWithNetworkTypes() {
 		})
 	}
 }
+
+func (suite *DeploymentTestSuite) TestGenerateClientCommandWithDualStack() {
+	network := entity.DeploymentNetwork{
+		Name:       "my-net",
+		IfName:     "eth1",
+		Addresses:  []string{"2001:db8::10/64"},
+		BridgeName: "system-62fc3f",
+		LeaseURL:   "http://localhost:7890/v1/ipam/leases?network=my-net",
+	}
+	command := generateClientCommand(network)
+	suite.Equal([]string{
+		"--server=unix:///tmp/vortex.sock",
+		"--bridge=system-62fc3f",
+		"--nic=eth1",
+		"--ip=${IP_CIDR}",
+		"--ip=2001:db8::10/64",
+	}, command)
+
+	//The ipv6 address isn't leased
+	network.LeaseURL = ""
+	network.IPAddress = "2001:db8::11"
+	network.Netmask = "64"
+	command = generateClientCommand(network)
+	suite.Equal("--ip=2001:db8::11/64", command[3])
+	suite.Equal("--ip=2001:db8::10/64", command[4])
+}
+
+func (suite *DeploymentTestSuite) TestGenerateStrategy() {
+	strategy, err := generateStrategy(nil)
+	suite.NoError(err)
+	suite.Equal(appsv1.RecreateDeploymentStrategyType, strategy.Type)
+	suite.Nil(strategy.RollingUpdate)
+
+	strategy, err = generateStrategy(&entity.DeploymentStrategy{
+		Type:           entity.DeploymentRollingUpdateStrategy,
+		MaxSurge:       "1",
+		MaxUnavailable: "25%",
+	})
+	suite.NoError(err)
+	suite.Equal(appsv1.RollingUpdateDeploymentStrategyType, strategy.Type)
+	suite.Equal(intstr.FromInt(1), *strategy.RollingUpdate.MaxSurge)
+	suite.Equal(intstr.FromString("25%"), *strategy.RollingUpdate.MaxUnavailable)
+
+	//The kubernetes default is used if it's not set
+	strategy, err = generateStrategy(&entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy})
+	suite.NoError(err)
+	suite.Nil(strategy.RollingUpdate.MaxSurge)
+	suite.Nil(strategy.RollingUpdate.MaxUnavailable)
+}
+
+func (suite *DeploymentTestSuite) TestCheckStrategy() {
+	deploy := &entity.Deployment{}
+	suite.NoError(checkStrategy(deploy))
+
+	deploy.Strategy = &entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy, MaxSurge: "0", MaxUnavailable: "1"}
+	suite.NoError(checkStrategy(deploy))
+
+	testCases := []struct {
+		cases    string
+		strategy entity.DeploymentStrategy
+		networks []entity.DeploymentNetwork
+	}{
+		{"RecreateWithMaxSurge", entity.DeploymentStrategy{Type: entity.DeploymentRecreateStrategy, MaxSurge: "1"}, nil},
+		{"InvalidMaxSurge", entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy, MaxSurge: "one"}, nil},
+		{"InvalidMaxUnavailable", entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy, MaxUnavailable: "-1"}, nil},
+		{"BothZero", entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy, MaxSurge: "0%", MaxUnavailable: "0"}, nil},
+		{"StaticIPAddress", entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy}, []entity.DeploymentNetwork{{Name: "my-net", IPAddress: "1.2.3.4"}}},
+	}
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			deploy := &entity.Deployment{Strategy: &tc.strategy, Networks: tc.networks}
+			suite.Error(checkStrategy(deploy))
+		})
+	}
+}
+
+func (suite *DeploymentTestSuite) TestSameNetworks() {
+	var vlanTag int32 = 100
+	old := []entity.DeploymentNetwork{
+		{
+			Name:        "my-net",
+			IfName:      "eth1",
+			VlanTag:     &vlanTag,
+			IPAddress:   "1.2.3.4",
+			Netmask:     "255.255.255.0",
+			RoutesGw:    []entity.DeploymentRouteGw{},
+			RoutesIntf:  []entity.DeploymentRouteIntf{},
+			BridgeName:  "system-62fc3f",
+			NetworkType: entity.OVSKernelspaceNetworkType,
+		},
+	}
+	//The netmask is filled by the lease and the bridge is filled by the network
+	updatedTag := vlanTag
+	updated := []entity.DeploymentNetwork{
+		{
+			Name:      "my-net",
+			IfName:    "eth1",
+			VlanTag:   &updatedTag,
+			IPAddress: "1.2.3.4",
+		},
+	}
+	suite.True(sameNetworks(old, updated))
+
+	updated[0].IfName = "eth2"
+	suite.False(sameNetworks(old, updated))
+	suite.False(sameNetworks(old, []entity.DeploymentNetwork{}))
+}
+
+func (suite *DeploymentTestSuite) TestGenerateInitContainerWithLease() {
+	network := entity.DeploymentNetwork{
+		Name:       "my-net",
+		IfName:     "eth1",
+		BridgeName: "system-62fc3f",
+		LeaseURL:   "http://localhost:7890/v1/ipam/leases?network=my-net",
+	}
+	command := generateClientCommand(network)
+	suite.Equal("--ip=${IP_CIDR}", command[3])
+
+	containers, err := generateInitContainer([]entity.DeploymentNetwork{network})
+	suite.NoError(err)
+	suite.Equal(1, len(containers))
+	suite.Equal([]string{"/bin/sh", "-c"}, containers[0].Command)
+	suite.Equal([]string{
+		"IP_CIDR=`wget -qO- --post-data='' 'http://localhost:7890/v1/ipam/leases?network=my-net'` && exec /go/bin/client " +
+			`"--server=unix:///tmp/vortex.sock" "--bridge=system-62fc3f" "--nic=eth1" "--ip=${IP_CIDR}"`,
+	}, containers[0].Args)
+}
+
+func (suite *DeploymentTestSuite) TestGenerateLeaseURL() {
+	cf := suite.sp.Config
+	defer func() { suite.sp.Config = cf }()
+	suite.sp.Config.ServerURL = "http://vortex:7890"
+
+	deploy := &entity.Deployment{Name: "web"}
+	network := entity.DeploymentNetwork{Name: "my-net", IfName: "eth1"}
+	leaseURL, err := generateLeaseURL(suite.sp, deploy, network)
+	suite.NoError(err)
+	suite.Len(deploy.LeaseToken, LeaseTokenLength*2)
+	suite.Contains(leaseURL, "&token="+deploy.LeaseToken+"&")
+
+	//The token is kept when the deployment is updated
+	token := deploy.LeaseToken
+	_, err = generateLeaseURL(suite.sp, deploy, network)
+	suite.NoError(err)
+	suite.Equal(token, deploy.LeaseToken)
+}
+
+func (suite *DeploymentTestSuite) TestCheckAutoscaler() {
+	deploy := &entity.Deployment{}
+	suite.NoError(checkAutoscaler(deploy))
+
+	deploy.Autoscaler = &entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50}
+	suite.NoError(checkAutoscaler(deploy))
+	deploy.Autoscaler = &entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, Metric: "http_requests_total", TargetValue: 100}
+	suite.NoError(checkAutoscaler(deploy))
+
+	testCases := []struct {
+		cases      string
+		autoscaler entity.DeploymentAutoscaler
+		networks   []entity.DeploymentNetwork
+	}{
+		{"NoTarget", entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3}, nil},
+		{"BothTargets", entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50, Metric: "up", TargetValue: 1}, nil},
+		{"MetricWithoutValue", entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, Metric: "up"}, nil},
+		{"InvalidMetric", entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, Metric: "up{job='a'}", TargetValue: 1}, nil},
+		{"MinGreaterThanMax", entity.DeploymentAutoscaler{MinReplicas: 3, MaxReplicas: 1, TargetCPUUtilization: 50}, nil},
+		{"StaticIPAddress", entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50}, []entity.DeploymentNetwork{{Name: "my-net", IPAddress: "1.2.3.4"}}},
+		{"StaticMacAddress", entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50}, []entity.DeploymentNetwork{{Name: "my-net", MacAddress: "02:00:00:00:00:01"}}},
+	}
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			deploy := &entity.Deployment{Autoscaler: &tc.autoscaler, Networks: tc.networks}
+			suite.Error(checkAutoscaler(deploy))
+		})
+	}
+}
+
+func (suite *DeploymentTestSuite) TestGenerateDesiredReplicas() {
+	autoscaler := &entity.DeploymentAutoscaler{MinReplicas: 2, MaxReplicas: 10, Metric: "http_requests_total", TargetValue: 100}
+	suite.Equal(int32(4), generateDesiredReplicas(autoscaler, 2, 200))
+	suite.Equal(int32(3), generateDesiredReplicas(autoscaler, 3, 100))
+	suite.Equal(int32(2), generateDesiredReplicas(autoscaler, 4, 10))
+	suite.Equal(int32(10), generateDesiredReplicas(autoscaler, 8, 1000))
+	//The ratio within the tolerance doesn't change the replicas
+	suite.Equal(int32(4), generateDesiredReplicas(autoscaler, 4, 109))
+	suite.Equal(int32(4), generateDesiredReplicas(autoscaler, 4, 91))
+	suite.Equal(int32(5), generateDesiredReplicas(autoscaler, 4, 120))
+}
+
+func (suite *DeploymentTestSuite) TestStabilizeReplicas() {
+	id := bson.NewObjectId()
+	now := time.Now()
+
+	//Scale up right away
+	suite.Equal(int32(6), stabilizeReplicas(id, 4, 6, now))
+	//Keep the highest recommendation in the window when it's scaled down
+	suite.Equal(int32(6), stabilizeReplicas(id, 6, 3, now.Add(time.Minute)))
+	suite.Equal(int32(6), stabilizeReplicas(id, 6, 2, now.Add(2*time.Minute)))
+	//The recommendation of 6 replicas is out of the window
+	suite.Equal(int32(3), stabilizeReplicas(id, 6, 2, now.Add(ScaleDownStabilization+time.Second)))
+}
+
+func (suite *DeploymentTestSuite) TestScaleDeployment() {
+	deploy := &entity.Deployment{
+		ID:          bson.NewObjectId(),
+		Name:        namesgenerator.GetRandomName(0),
+		Namespace:   "default",
+		NetworkType: entity.DeploymentClusterNetwork,
+		Replicas:    1,
+	}
+	_, err := suite.sp.KubeCtl.CreateDeployment(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: deploy.Name},
+		Spec:       appsv1.DeploymentSpec{Replicas: &deploy.Replicas},
+	}, deploy.Namespace)
+	suite.NoError(err)
+	defer suite.sp.KubeCtl.DeleteDeployment(deploy.Name, deploy.Namespace)
+
+	suite.NoError(CheckScale(suite.sp, deploy, 3))
+	suite.NoError(ScaleDeployment(suite.sp, deploy, 3))
+	suite.Equal(int32(3), deploy.Replicas)
+
+	result, err := suite.sp.KubeCtl.GetDeployment(deploy.Name, deploy.Namespace)
+	suite.NoError(err)
+	suite.Equal(int32(3), *result.Spec.Replicas)
+
+	deploy.Networks = []entity.DeploymentNetwork{{Name: "my-net", IPAddress: "1.2.3.4"}}
+	suite.Error(CheckScale(suite.sp, deploy, 2))
+}
+
+func (suite *DeploymentTestSuite) TestSetAutoscaler() {
+	deploy := &entity.Deployment{
+		Name:       namesgenerator.GetRandomName(0),
+		Namespace:  "default",
+		Autoscaler: &entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50},
+	}
+	suite.NoError(SetAutoscaler(suite.sp, deploy))
+	hpa, err := suite.sp.KubeCtl.GetHorizontalPodAutoscaler(deploy.Name, deploy.Namespace)
+	suite.NoError(err)
+	suite.Equal(int32(3), hpa.Spec.MaxReplicas)
+	suite.Equal(int32(50), *hpa.Spec.TargetCPUUtilizationPercentage)
+
+	deploy.Autoscaler.MaxReplicas = 5
+	suite.NoError(SetAutoscaler(suite.sp, deploy))
+	hpa, err = suite.sp.KubeCtl.GetHorizontalPodAutoscaler(deploy.Name, deploy.Namespace)
+	suite.NoError(err)
+	suite.Equal(int32(5), hpa.Spec.MaxReplicas)
+
+	//The autoscaler of the prometheus metric doesn't have the HorizontalPodAutoscaler
+	deploy.Autoscaler = &entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, Metric: "http_requests_total", TargetValue: 100}
+	suite.NoError(SetAutoscaler(suite.sp, deploy))
+	_, err = suite.sp.KubeCtl.GetHorizontalPodAutoscaler(deploy.Name, deploy.Namespace)
+	suite.Error(err)
+	suite.NoError(DeleteAutoscaler(suite.sp, deploy))
+}
+
+func (suite *DeploymentTestSuite) TestRecordRevision() {
+	deploy := &entity.Deployment{
+		ID:        bson.NewObjectId(),
+		Name:      namesgenerator.GetRandomName(0),
+		Namespace: "default",
+		Replicas:  1,
+	}
+	_, err := suite.sp.KubeCtl.CreateDeployment(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: deploy.Name},
+		Spec:       appsv1.DeploymentSpec{Replicas: &deploy.Replicas},
+	}, deploy.Namespace)
+	suite.NoError(err)
+	defer suite.sp.KubeCtl.DeleteDeployment(deploy.Name, deploy.Namespace)
+
+	session := suite.sp.Mongo.NewSession()
+	defer session.Close()
+	defer DeleteRevisions(session, deploy.ID)
+
+	revision, err := RecordRevision(suite.sp, deploy, 0)
+	suite.NoError(err)
+	suite.Equal(int64(1), revision.Revision)
+	suite.Equal(int64(0), revision.ReplicaSetRevision)
+
+	deploy.Replicas = 2
+	revision, err = RecordRevision(suite.sp, deploy, 1)
+	suite.NoError(err)
+	suite.Equal(int64(2), revision.Revision)
+	suite.Equal(int64(1), revision.RollbackTo)
+
+	//The kubernetes deployment controller observes the change
+	current, err := suite.sp.KubeCtl.GetDeployment(deploy.Name, deploy.Namespace)
+	suite.NoError(err)
+	current.Annotations = map[string]string{ReplicaSetRevisionAnnotation: "2"}
+	current.Status.ObservedGeneration = current.Generation
+	_, err = suite.sp.KubeCtl.Clientset.AppsV1().Deployments(deploy.Namespace).Update(current)
+	suite.NoError(err)
+
+	//The revision number is taken by the concurrent change
+	err = session.Insert(entity.DeploymentRevisionCollectionName, &entity.DeploymentRevision{
+		ID:           bson.NewObjectId(),
+		DeploymentID: deploy.ID,
+		Revision:     2,
+	})
+	suite.True(mgo.IsDup(err))
+
+	revisions, err := ListRevisions(suite.sp, deploy)
+	suite.NoError(err)
+	suite.Len(revisions, 2)
+	suite.Equal(int32(1), revisions[0].Deployment.Replicas)
+	suite.Equal(int64(0), revisions[0].ReplicaSetRevision)
+	suite.Equal(int64(2), revisions[1].ReplicaSetRevision)
+
+	revision, err = GetRevision(session, deploy.ID, 2)
+	suite.NoError(err)
+	suite.Equal(int64(2), revision.ReplicaSetRevision)
+	_, err = GetRevision(session, deploy.ID, 3)
+	suite.Error(err)
+}
+
+func (suite *DeploymentTestSuite) TestGenerateRollback() {
+	current := &entity.Deployment{
+		ID:         bson.NewObjectId(),
+		OwnerID:    bson.NewObjectId(),
+		Name:       "my-deploy",
+		Replicas:   3,
+		Autoscaler: &entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50},
+		Containers: []entity.Container{{Name: "busybox", Image: "busybox:1.29"}},
+	}
+	revision := &entity.DeploymentRevision{
+		DeploymentID: current.ID,
+		Revision:     1,
+		Deployment: entity.Deployment{
+			ID:         current.ID,
+			Name:       "my-deploy",
+			Replicas:   1,
+			Containers: []entity.Container{{Name: "busybox", Image: "busybox"}},
+		},
+	}
+	restored, err := GenerateRollback(current, revision)
+	suite.NoError(err)
+	suite.Equal(current.OwnerID, restored.OwnerID)
+	//The replicas aren't a part of the pod template
+	suite.Equal(int32(3), restored.Replicas)
+	suite.Equal("busybox", restored.Containers[0].Image)
+	suite.Equal(current.Autoscaler, restored.Autoscaler)
+
+	revision.DeploymentID = bson.NewObjectId()
+	_, err = GenerateRollback(current, revision)
+	suite.Error(err)
+}
@@ -0,0 +1,157 @@
+package deployment
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/utils/timeutils"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	appsv1 "k8s.io/api/apps/v1"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ReplicaSetRevisionAnnotation is the annotation of the kubernetes deployment for the revision of its current ReplicaSet
+const ReplicaSetRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+func latestRevision(session *mongo.Session, deploymentID bson.ObjectId) (*entity.DeploymentRevision, error) {
+	revision := entity.DeploymentRevision{}
+	err := session.C(entity.DeploymentRevisionCollectionName).Find(bson.M{"deploymentID": deploymentID}).Sort("-revision").One(&revision)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// The ReplicaSet revision is set by the kubernetes deployment controller after it observes the generation of the change.
+// If the pod template isn't changed, the ReplicaSet and its revision are the same as the previous one.
+func observeReplicaSetRevision(revision *entity.DeploymentRevision, current *appsv1.Deployment) bool {
+	if revision.ReplicaSetRevision != 0 || current.Status.ObservedGeneration < revision.Generation {
+		return false
+	}
+	v, err := strconv.ParseInt(current.Annotations[ReplicaSetRevisionAnnotation], 10, 64)
+	if err != nil {
+		return false
+	}
+	revision.ReplicaSetRevision = v
+	return true
+}
+
+// RecordRevisionRetries is the number of the attempts to record the revision if its number is taken by the concurrent change
+const RecordRevisionRetries = 3
+
+// RecordRevision will store the deployment as a new revision after it's created, updated or rolled back
+// The rollbackTo is the revision which the deployment is rolled back to, or 0 if it's not a rollback.
+// The revision numbers are unique in the deployment, the next number is taken again if the concurrent change records it first.
+func RecordRevision(sp *serviceprovider.Container, deploy *entity.Deployment, rollbackTo int64) (*entity.DeploymentRevision, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	session.C(entity.DeploymentRevisionCollectionName).EnsureIndex(mgo.Index{
+		Key:    []string{"deploymentID", "revision"},
+		Unique: true,
+	})
+
+	current, err := sp.KubeCtl.GetDeployment(deploy.Name, deploy.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := entity.DeploymentRevision{
+		DeploymentID: deploy.ID,
+		RollbackTo:   rollbackTo,
+		Deployment:   *deploy,
+		Generation:   current.Generation,
+		CreatedAt:    timeutils.Now(),
+	}
+	//The user is found by the owner ID when it's returned
+	revision.Deployment.CreatedBy = entity.User{}
+	observeReplicaSetRevision(&revision, current)
+
+	for i := 0; ; i++ {
+		revision.ID = bson.NewObjectId()
+		revision.Revision = 1
+		latest, err := latestRevision(session, deploy.ID)
+		if err == nil {
+			revision.Revision = latest.Revision + 1
+		} else if err != mgo.ErrNotFound {
+			return nil, err
+		}
+
+		err = session.Insert(entity.DeploymentRevisionCollectionName, &revision)
+		if err == nil {
+			return &revision, nil
+		}
+		if !mgo.IsDup(err) || i+1 >= RecordRevisionRetries {
+			return nil, err
+		}
+	}
+}
+
+// ListRevisions will return the revisions of the deployment from the oldest one
+// The ReplicaSet revision of the latest one is filled if the kubernetes deployment controller has observed it.
+func ListRevisions(sp *serviceprovider.Container, deploy *entity.Deployment) ([]entity.DeploymentRevision, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	revisions := []entity.DeploymentRevision{}
+	if err := session.C(entity.DeploymentRevisionCollectionName).Find(bson.M{"deploymentID": deploy.ID}).Sort("revision").All(&revisions); err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return revisions, nil
+	}
+
+	latest := &revisions[len(revisions)-1]
+	if latest.ReplicaSetRevision == 0 {
+		current, err := sp.KubeCtl.GetDeployment(deploy.Name, deploy.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if observeReplicaSetRevision(latest, current) {
+			if err := session.C(entity.DeploymentRevisionCollectionName).UpdateId(latest.ID, bson.M{"$set": bson.M{"replicaSetRevision": latest.ReplicaSetRevision}}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return revisions, nil
+}
+
+// GetRevision will return the revision of the deployment by the revision number
+func GetRevision(session *mongo.Session, deploymentID bson.ObjectId, number int64) (*entity.DeploymentRevision, error) {
+	revision := entity.DeploymentRevision{}
+	if err := session.FindOne(entity.DeploymentRevisionCollectionName, bson.M{"deploymentID": deploymentID, "revision": number}, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// DeleteRevisions will delete all revisions of the deployment
+func DeleteRevisions(session *mongo.Session, deploymentID bson.ObjectId) error {
+	_, err := session.C(entity.DeploymentRevisionCollectionName).RemoveAll(bson.M{"deploymentID": deploymentID})
+	return err
+}
+
+// GenerateRollback will generate the deployment restored from the revision
+// Only the pod template, e.g. the containers, networks and volumes, is restored like the kubectl rollout undo,
+// the replicas, strategy and autoscaler are kept since they aren't a part of the pod template.
+func GenerateRollback(current *entity.Deployment, revision *entity.DeploymentRevision) (*entity.Deployment, error) {
+	if revision.DeploymentID != current.ID {
+		return nil, fmt.Errorf("the revision %d doesn't belong to the deployment %s", revision.Revision, current.Name)
+	}
+
+	restored := *current
+	restored.CreatedBy = entity.User{}
+	restored.Labels = revision.Deployment.Labels
+	restored.EnvVars = revision.Deployment.EnvVars
+	restored.Containers = revision.Deployment.Containers
+	restored.Volumes = revision.Deployment.Volumes
+	restored.Networks = revision.Deployment.Networks
+	restored.Capability = revision.Deployment.Capability
+	restored.NetworkType = revision.Deployment.NetworkType
+	restored.NodeAffinity = revision.Deployment.NodeAffinity
+	return &restored, nil
+}
@@ -0,0 +1,346 @@
+package deployment
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/ipam"
+	"github.com/linkernetworks/vortex/src/kubeutils"
+	pc "github.com/linkernetworks/vortex/src/prometheuscontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// AutoscaleInterval is the interval to evaluate the autoscalers of the prometheus metrics
+const AutoscaleInterval = 30 * time.Second
+
+// AutoscaleTolerance is the ratio of the metric to the target within which the replicas aren't changed, the same as the HorizontalPodAutoscaler
+const AutoscaleTolerance = 0.1
+
+// ScaleDownStabilization is the window of the recommended replicas, the deployment is only scaled down to the highest one in it
+const ScaleDownStabilization = 5 * time.Minute
+
+type recommendation struct {
+	replicas  int32
+	timestamp time.Time
+}
+
+// The recommended replicas of the deployments in the stabilization window
+var recommendations = struct {
+	sync.Mutex
+	m map[bson.ObjectId][]recommendation
+}{m: map[bson.ObjectId][]recommendation{}}
+
+// The metric name of the prometheus
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+func checkAutoscaler(deploy *entity.Deployment) error {
+	autoscaler := deploy.Autoscaler
+	if autoscaler == nil {
+		return nil
+	}
+
+	//Only one of the cpu utilization and the prometheus metric is used
+	byCPU := autoscaler.TargetCPUUtilization != 0
+	byMetric := autoscaler.Metric != "" || autoscaler.TargetValue != 0
+	if byCPU == byMetric {
+		return fmt.Errorf("the autoscaler should have either the targetCPUUtilization or the metric and targetValue")
+	}
+	if byMetric {
+		if autoscaler.Metric == "" || autoscaler.TargetValue == 0 {
+			return fmt.Errorf("the metric and targetValue of the autoscaler are both required")
+		}
+		if !metricNameRegexp.MatchString(autoscaler.Metric) {
+			return fmt.Errorf("the metric %s isn't a valid prometheus metric name", autoscaler.Metric)
+		}
+	}
+	if autoscaler.MinReplicas > autoscaler.MaxReplicas {
+		return fmt.Errorf("the minReplicas %d is greater than the maxReplicas %d", autoscaler.MinReplicas, autoscaler.MaxReplicas)
+	}
+	return checkSharedAddresses(deploy.Networks, autoscaler.MaxReplicas)
+}
+
+// The new replicas lease the ip addresses from the subnets and use the free virtual functions of the nodes,
+// so the networks should have enough of them for the added replicas.
+func checkCapacity(sp *serviceprovider.Container, session *mongo.Session, deploy *entity.Deployment, added int32) error {
+	if added <= 0 || deploy.NetworkType != entity.DeploymentCustomNetwork {
+		return nil
+	}
+
+	required := map[string]int{}
+	for _, v := range deploy.Networks {
+		if v.IPAddress == "" && len(v.Addresses) == 0 {
+			required[v.Name]++
+		}
+	}
+	for name, count := range required {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": name}, &network); err != nil {
+			return err
+		}
+		if len(network.Subnets) == 0 {
+			continue
+		}
+		free, err := ipam.CountFreeAddresses(session, &network)
+		if err != nil {
+			return err
+		}
+		if free < count*int(added) {
+			return fmt.Errorf("the free ip addresses of the network %s are only enough for %d more replicas", name, free/count)
+		}
+	}
+
+	scaled := *deploy
+	scaled.Replicas = added
+	_, err := generateSRIOVNodes(sp, session, &scaled, deploy.NodeAffinity)
+	return err
+}
+
+func currentReplicas(sp *serviceprovider.Container, deploy *entity.Deployment) (int32, error) {
+	current, err := sp.KubeCtl.GetDeployment(deploy.Name, deploy.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	//The default replicas of the kubernetes is 1
+	if current.Spec.Replicas == nil {
+		return 1, nil
+	}
+	return *current.Spec.Replicas, nil
+}
+
+// CheckScale will check the deployment can be scaled to the replicas
+func CheckScale(sp *serviceprovider.Container, deploy *entity.Deployment, replicas int32) error {
+	if err := checkSharedAddresses(deploy.Networks, replicas); err != nil {
+		return err
+	}
+
+	current, err := sp.KubeCtl.GetDeployment(deploy.Name, deploy.Namespace)
+	if err != nil {
+		return err
+	}
+	added := replicas - 1
+	if current.Spec.Replicas != nil {
+		added = replicas - *current.Spec.Replicas
+	}
+	//The running pods are counted by the nodes, so only the added ones are checked
+	if added > 0 {
+		if err := kubeutils.CheckNodeAllocatable(sp, &current.Spec.Template.Spec, added); err != nil {
+			return err
+		}
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	return checkCapacity(sp, session, deploy, added)
+}
+
+// ScaleDeployment will change the replicas of the running deployment
+func ScaleDeployment(sp *serviceprovider.Container, deploy *entity.Deployment, replicas int32) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	if _, err := sp.KubeCtl.PatchDeployment(deploy.Name, deploy.Namespace, patch); err != nil {
+		return err
+	}
+	deploy.Replicas = replicas
+	return nil
+}
+
+// CheckAutoscaler will check the autoscaler of the deployment
+// The networks should have enough ip addresses and virtual functions for the max replicas.
+func CheckAutoscaler(sp *serviceprovider.Container, deploy *entity.Deployment) error {
+	if err := checkAutoscaler(deploy); err != nil {
+		return err
+	}
+	if deploy.Autoscaler == nil {
+		return nil
+	}
+
+	current, err := currentReplicas(sp, deploy)
+	if err != nil {
+		return err
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	return checkCapacity(sp, session, deploy, deploy.Autoscaler.MaxReplicas-current)
+}
+
+func generateHorizontalPodAutoscaler(deploy *entity.Deployment) *autoscalingv1.HorizontalPodAutoscaler {
+	minReplicas := deploy.Autoscaler.MinReplicas
+	targetCPUUtilization := deploy.Autoscaler.TargetCPUUtilization
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: deploy.Name,
+			Labels: map[string]string{
+				DefaultLabel: deploy.Name,
+			},
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deploy.Name,
+			},
+			MinReplicas:                    &minReplicas,
+			MaxReplicas:                    deploy.Autoscaler.MaxReplicas,
+			TargetCPUUtilizationPercentage: &targetCPUUtilization,
+		},
+	}
+}
+
+// SetAutoscaler will apply the autoscaler of the deployment
+// The autoscaler of the cpu utilization is the kubernetes HorizontalPodAutoscaler,
+// and the one of the prometheus metric is evaluated by vortex periodically.
+func SetAutoscaler(sp *serviceprovider.Container, deploy *entity.Deployment) error {
+	if deploy.Autoscaler == nil || deploy.Autoscaler.TargetCPUUtilization == 0 {
+		return DeleteAutoscaler(sp, deploy)
+	}
+
+	hpa := generateHorizontalPodAutoscaler(deploy)
+	existed, err := sp.KubeCtl.GetHorizontalPodAutoscaler(deploy.Name, deploy.Namespace)
+	if errors.IsNotFound(err) {
+		_, err = sp.KubeCtl.CreateHorizontalPodAutoscaler(hpa, deploy.Namespace)
+		return err
+	} else if err != nil {
+		return err
+	}
+	existed.Spec = hpa.Spec
+	_, err = sp.KubeCtl.UpdateHorizontalPodAutoscaler(existed, deploy.Namespace)
+	return err
+}
+
+// DeleteAutoscaler will delete the HorizontalPodAutoscaler of the deployment if it exists
+func DeleteAutoscaler(sp *serviceprovider.Container, deploy *entity.Deployment) error {
+	if err := sp.KubeCtl.DeleteHorizontalPodAutoscaler(deploy.Name, deploy.Namespace); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// The desired replicas is the same as the HorizontalPodAutoscaler, the current replicas multiply the ratio of the metric value to the target.
+// The replicas aren't changed if the ratio is within the tolerance.
+func generateDesiredReplicas(autoscaler *entity.DeploymentAutoscaler, current int32, value float64) int32 {
+	desired := current
+	ratio := value / autoscaler.TargetValue
+	if math.Abs(ratio-1) > AutoscaleTolerance {
+		desired = int32(math.Ceil(float64(current) * ratio))
+	}
+	if desired < autoscaler.MinReplicas {
+		return autoscaler.MinReplicas
+	}
+	if desired > autoscaler.MaxReplicas {
+		return autoscaler.MaxReplicas
+	}
+	return desired
+}
+
+// stabilizeReplicas will record the desired replicas and return the highest one in the stabilization window when it's scaled down
+// The deployment is scaled up right away, and it's only scaled down if the load has been low for the whole window.
+func stabilizeReplicas(id bson.ObjectId, current, desired int32, now time.Time) int32 {
+	recommendations.Lock()
+	defer recommendations.Unlock()
+
+	stable := desired
+	kept := []recommendation{{desired, now}}
+	for _, r := range recommendations.m[id] {
+		if now.Sub(r.timestamp) >= ScaleDownStabilization {
+			continue
+		}
+		kept = append(kept, r)
+		if r.replicas > stable {
+			stable = r.replicas
+		}
+	}
+	recommendations.m[id] = kept
+
+	if desired >= current {
+		return desired
+	}
+	if stable > current {
+		return current
+	}
+	return stable
+}
+
+func autoscaleDeployment(sp *serviceprovider.Container, session *mongo.Session, deploy *entity.Deployment) error {
+	current, err := currentReplicas(sp, deploy)
+	if err != nil {
+		return err
+	}
+	//The HorizontalPodAutoscaler scales the deployment by the cpu, only the stored replicas are synced
+	if deploy.Autoscaler.Metric == "" {
+		if current == deploy.Replicas {
+			return nil
+		}
+		return session.C(entity.DeploymentCollectionName).UpdateId(deploy.ID, bson.M{"$set": bson.M{"replicas": current}})
+	}
+
+	value, err := pc.GetDeploymentMetric(sp, deploy.Namespace, deploy.Name, deploy.Autoscaler.Metric)
+	if err != nil {
+		return err
+	}
+
+	desired := generateDesiredReplicas(deploy.Autoscaler, current, value)
+	desired = stabilizeReplicas(deploy.ID, current, desired, time.Now())
+	if desired == current {
+		return nil
+	}
+	//The added replicas need the allocatable resources of the nodes and the ip addresses and virtual functions of the networks
+	if err := CheckScale(sp, deploy, desired); err != nil {
+		return err
+	}
+	if err := ScaleDeployment(sp, deploy, desired); err != nil {
+		return err
+	}
+	logger.Infof("scale the deployment %s from %d to %d replicas by the metric %s", deploy.Name, current, desired, deploy.Autoscaler.Metric)
+	return session.C(entity.DeploymentCollectionName).UpdateId(deploy.ID, bson.M{"$set": bson.M{"replicas": desired}})
+}
+
+// EvaluateAutoscalers will scale the deployments by the prometheus metrics of their autoscalers
+// The replicas of the deployments scaled by the HorizontalPodAutoscalers are stored as well.
+func EvaluateAutoscalers(sp *serviceprovider.Container) error {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	deployments := []entity.Deployment{}
+	if err := session.FindAll(entity.DeploymentCollectionName, bson.M{"autoscaler": bson.M{"$ne": nil}}, &deployments); err != nil {
+		return err
+	}
+	ids := map[bson.ObjectId]bool{}
+	for i := range deployments {
+		ids[deployments[i].ID] = true
+		if err := autoscaleDeployment(sp, session, &deployments[i]); err != nil {
+			logger.Warnf("autoscale the deployment %s fail: %v", deployments[i].Name, err)
+		}
+	}
+
+	//The recommendations of the deleted deployments and autoscalers are dropped
+	recommendations.Lock()
+	defer recommendations.Unlock()
+	for id := range recommendations.m {
+		if !ids[id] {
+			delete(recommendations.m, id)
+		}
+	}
+	return nil
+}
+
+// AutoscaleDeployments will evaluate the autoscalers of the prometheus metrics periodically
+func AutoscaleDeployments(sp *serviceprovider.Container, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := EvaluateAutoscalers(sp); err != nil {
+			logger.Warnf("evaluate the autoscalers fail: %v", err)
+		}
+	}
+}
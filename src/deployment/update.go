@@ -0,0 +1,200 @@
+package deployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/kubeutils"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// The maxSurge and maxUnavailable are the number or the percentage of the replicas
+var intOrPercentRegexp = regexp.MustCompile(`^[0-9]+%?$`)
+
+func checkStrategy(deploy *entity.Deployment) error {
+	strategy := deploy.Strategy
+	if strategy == nil {
+		return nil
+	}
+	if strategy.Type != entity.DeploymentRollingUpdateStrategy {
+		if strategy.MaxSurge != "" || strategy.MaxUnavailable != "" {
+			return fmt.Errorf("the maxSurge and maxUnavailable are only for the %s strategy", entity.DeploymentRollingUpdateStrategy)
+		}
+		return nil
+	}
+
+	for _, v := range []string{strategy.MaxSurge, strategy.MaxUnavailable} {
+		if v != "" && !intOrPercentRegexp.MatchString(v) {
+			return fmt.Errorf("the %s should be the number or the percentage like 25%%", v)
+		}
+	}
+	if isZero(strategy.MaxSurge) && isZero(strategy.MaxUnavailable) {
+		return fmt.Errorf("the maxSurge and maxUnavailable can't be both zero")
+	}
+
+	//The new pod is started before the old one is stopped, the static ip address can't be used by both of them
+	for _, v := range deploy.Networks {
+		if v.IPAddress != "" || len(v.Addresses) != 0 {
+			return fmt.Errorf("the static ip address of network %s can only be used with the %s strategy", v.Name, entity.DeploymentRecreateStrategy)
+		}
+	}
+	return nil
+}
+
+// The empty value is the default of the kubernetes, it's 25%
+func isZero(v string) bool {
+	return v == "0" || v == "0%"
+}
+
+func generateStrategy(strategy *entity.DeploymentStrategy) (appsv1.DeploymentStrategy, error) {
+	if strategy == nil || strategy.Type == entity.DeploymentRecreateStrategy {
+		return appsv1.DeploymentStrategy{
+			Type: appsv1.RecreateDeploymentStrategyType,
+		}, nil
+	}
+	if strategy.Type != entity.DeploymentRollingUpdateStrategy {
+		return appsv1.DeploymentStrategy{}, fmt.Errorf("UnSupported Deployment Strategy %s", strategy.Type)
+	}
+
+	rollingUpdate := &appsv1.RollingUpdateDeployment{}
+	if strategy.MaxSurge != "" {
+		maxSurge := intstr.Parse(strategy.MaxSurge)
+		rollingUpdate.MaxSurge = &maxSurge
+	}
+	if strategy.MaxUnavailable != "" {
+		maxUnavailable := intstr.Parse(strategy.MaxUnavailable)
+		rollingUpdate.MaxUnavailable = &maxUnavailable
+	}
+	return appsv1.DeploymentStrategy{
+		Type:          appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: rollingUpdate,
+	}, nil
+}
+
+// Only the fields set by the user are compared, the others are filled when the deployment is created
+func sameNetworks(old, updated []entity.DeploymentNetwork) bool {
+	if len(old) != len(updated) {
+		return false
+	}
+	for i := range old {
+		a, b := old[i], updated[i]
+		if b.Netmask == "" {
+			a.Netmask = ""
+		}
+		for _, v := range []*entity.DeploymentNetwork{&a, &b} {
+			v.BridgeName = ""
+			v.NetworkType = ""
+			v.Nodes = nil
+			v.LeaseURL = ""
+			if len(v.Addresses) == 0 {
+				v.Addresses = nil
+			}
+			if len(v.RoutesGw) == 0 {
+				v.RoutesGw = nil
+			}
+			if len(v.RoutesIntf) == 0 {
+				v.RoutesIntf = nil
+			}
+		}
+		if !reflect.DeepEqual(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckDeploymentUpdate will check the updated deployment by the stored one
+// The networks can't be changed since the ip addresses are leased for the running pods.
+func CheckDeploymentUpdate(sp *serviceprovider.Container, old, updated *entity.Deployment) error {
+	if updated.Name != old.Name || updated.Namespace != old.Namespace {
+		return fmt.Errorf("the name and namespace of the deployment %s can't be changed", old.Name)
+	}
+	if updated.NetworkType != old.NetworkType || !sameNetworks(old.Networks, updated.Networks) {
+		return fmt.Errorf("the networks of the deployment %s can't be changed, please recreate it", old.Name)
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	if err := checkVolumes(session, updated); err != nil {
+		return err
+	}
+	if err := kubeutils.CheckResources(updated.Containers); err != nil {
+		return err
+	}
+	if err := kubeutils.CheckContainers(updated.Containers); err != nil {
+		return err
+	}
+	if err := checkSharedAddresses(old.Networks, updated.Replicas); err != nil {
+		return err
+	}
+	if err := checkStrategy(updated); err != nil {
+		return err
+	}
+
+	//The running pods of the deployment are replaced, so their resources are available to the new ones
+	deploy := *updated
+	deploy.Networks = old.Networks
+	generated, err := generateDeployment(sp, session, &deploy)
+	if err != nil {
+		return err
+	}
+	return kubeutils.CheckNodeAllocatableReplacing(sp, &generated.Spec.Template.Spec, updated.Replicas, old.Namespace, generated.Spec.Selector.MatchLabels)
+}
+
+// UpdateDeployment will patch the kubernetes deployment by the updated deployment
+// The pods are replaced by the strategy of the deployment, and the ip addresses leased before are kept.
+func UpdateDeployment(sp *serviceprovider.Container, old, updated *entity.Deployment) error {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	updated.Networks = old.Networks
+	if updated.NetworkType == entity.DeploymentCustomNetwork {
+		if err := generateLeaseURLs(sp, session, updated); err != nil {
+			return err
+		}
+	}
+
+	current, err := sp.KubeCtl.GetDeployment(old.Name, old.Namespace)
+	if err != nil {
+		return err
+	}
+	generated, err := generateDeployment(sp, session, updated)
+	if err != nil {
+		return err
+	}
+
+	modified := current.DeepCopy()
+	modified.Labels = generated.Labels
+	//The replicas are changed by the autoscaler
+	if updated.Autoscaler == nil {
+		modified.Spec.Replicas = generated.Spec.Replicas
+	}
+	modified.Spec.Strategy = generated.Spec.Strategy
+	modified.Spec.Template = generated.Spec.Template
+
+	patch, err := generatePatch(current, modified)
+	if err != nil {
+		return err
+	}
+	_, err = sp.KubeCtl.PatchDeployment(old.Name, old.Namespace, patch)
+	return err
+}
+
+func generatePatch(current, modified *appsv1.Deployment) ([]byte, error) {
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	modifiedJSON, err := json.Marshal(modified)
+	if err != nil {
+		return nil, err
+	}
+	return strategicpatch.CreateTwoWayMergePatch(currentJSON, modifiedJSON, appsv1.Deployment{})
+}
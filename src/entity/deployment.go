@@ -20,13 +20,13 @@ const (
 
 // DeploymentRouteGw is the structure for add IP routing table
 type DeploymentRouteGw struct {
-	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidrv4"`
-	Gateway string `bson:"gateway" json:"gateway" validate:"required,ipv4"`
+	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidr"`
+	Gateway string `bson:"gateway" json:"gateway" validate:"required,ip"`
 }
 
 // DeploymentRouteIntf is the structure for add IP routing table via interface
 type DeploymentRouteIntf struct {
-	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidrv4"`
+	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidr"`
 }
 
 // DeploymentNetwork is the structure for deployment network info
@@ -35,13 +35,57 @@ type DeploymentNetwork struct {
 	IfName string `bson:"ifName" json:"ifName" validate:"required"`
 	// can not validate nil
 	VlanTag    *int32                `bson:"vlanTag" json:"vlanTag" validate:"-"`
-	IPAddress  string                `bson:"ipAddress" json:"ipAddress" validate:"required,ipv4"`
-	Netmask    string                `bson:"netmask" json:"netmask" validate:"required,ipv4"`
+	IPAddress  string                `bson:"ipAddress" json:"ipAddress" validate:"omitempty,ip"`
+	Netmask    string                `bson:"netmask" json:"netmask" validate:"omitempty,netmask"`
 	RoutesGw   []DeploymentRouteGw   `bson:"routesGw,omitempty" json:"routesGw" validate:"required,dive,required"`
 	RoutesIntf []DeploymentRouteIntf `bson:"routesIntf,omitempty" json:"routesIntf" validate:"required,dive,required"`
 
+	// The additional addresses of the interface in the cidr format, e.g. 2001:db8::10/64 for the dual-stack
+	Addresses []string `bson:"addresses,omitempty" json:"addresses,omitempty" validate:"omitempty,dive,cidr"`
+
+	// The MAC address of the virtual function, only for the sriov network
+	MacAddress string `bson:"macAddress,omitempty" json:"macAddress,omitempty" validate:"omitempty,mac"`
+
+	// The bandwidth limits of the interface, only for the OVS network
+	QoS *NetworkQoS `bson:"qos,omitempty" json:"qos,omitempty" validate:"omitempty"`
+
 	// It's from the entity.Network entity
-	BridgeName string `bson:"bridgeName" json:"bridgeName" validate:"-"`
+	BridgeName  string      `bson:"bridgeName" json:"bridgeName" validate:"-"`
+	NetworkType NetworkType `bson:"networkType,omitempty" json:"networkType,omitempty" validate:"-"`
+	// The physical functions of each node, only for the sriov network
+	Nodes []Node `bson:"-" json:"-" validate:"-"`
+	// The url to lease the ip address by the init container, only if the ip address isn't set
+	LeaseURL string `bson:"-" json:"-" validate:"-"`
+}
+
+// These are the strategies to replace the old pods by the new ones when the deployment is updated
+const (
+	DeploymentRecreateStrategy      = "Recreate"
+	DeploymentRollingUpdateStrategy = "RollingUpdate"
+)
+
+// DeploymentStrategy is the structure for the deployment strategy, the Recreate strategy is used if it's not set
+// The MaxSurge and MaxUnavailable are the number like 1 or the percentage like 25% of the replicas, only for the RollingUpdate strategy.
+type DeploymentStrategy struct {
+	Type           string `bson:"type" json:"type" validate:"required,eq=Recreate|eq=RollingUpdate"`
+	MaxSurge       string `bson:"maxSurge,omitempty" json:"maxSurge,omitempty" validate:"omitempty"`
+	MaxUnavailable string `bson:"maxUnavailable,omitempty" json:"maxUnavailable,omitempty" validate:"omitempty"`
+}
+
+// DeploymentAutoscaler is the structure for the horizontal autoscaling of the deployment
+// The deployment is scaled by the cpu utilization of the pods with the kubernetes HorizontalPodAutoscaler,
+// or by the average value of the prometheus metric of the pods which is evaluated by vortex.
+type DeploymentAutoscaler struct {
+	MinReplicas          int32   `bson:"minReplicas" json:"minReplicas" validate:"required,min=1"`
+	MaxReplicas          int32   `bson:"maxReplicas" json:"maxReplicas" validate:"required,gtefield=MinReplicas"`
+	TargetCPUUtilization int32   `bson:"targetCPUUtilization,omitempty" json:"targetCPUUtilization,omitempty" validate:"omitempty,min=1"`
+	Metric               string  `bson:"metric,omitempty" json:"metric,omitempty" validate:"omitempty"`
+	TargetValue          float64 `bson:"targetValue,omitempty" json:"targetValue,omitempty" validate:"omitempty,gt=0"`
+}
+
+// DeploymentScale is the structure to scale the deployment
+type DeploymentScale struct {
+	Replicas int32 `json:"replicas" validate:"required,min=1"`
 }
 
 // DeploymentVolume is the structure for deployment volume info
@@ -67,7 +111,13 @@ type Deployment struct {
 	CreatedBy    User                `json:"createdBy" validate:"-"`
 	CreatedAt    *time.Time          `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
 
-	Replicas int32 `bson:"replicas" json:"replicas" validate:"required"`
+	Replicas int32               `bson:"replicas" json:"replicas" validate:"required"`
+	Strategy *DeploymentStrategy `bson:"strategy,omitempty" json:"strategy,omitempty" validate:"omitempty"`
+
+	Autoscaler *DeploymentAutoscaler `bson:"autoscaler,omitempty" json:"autoscaler,omitempty" validate:"omitempty"`
+
+	// The token in the lease url of the init containers, it's generated by vortex
+	LeaseToken string `bson:"leaseToken,omitempty" json:"-" validate:"-"`
 }
 
 // GetCollection - get model mongo collection name.
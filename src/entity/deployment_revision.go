@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DeploymentRevisionCollectionName is a const string
+const DeploymentRevisionCollectionName string = "deployment_revisions"
+
+// DeploymentRevision is the structure for the deployment at each change
+// The Revision is counted by vortex from 1, and the ReplicaSetRevision is the revision of the kubernetes ReplicaSet,
+// it's 0 until the kubernetes deployment controller observes the change.
+type DeploymentRevision struct {
+	ID                 bson.ObjectId `bson:"_id,omitempty" json:"id" validate:"-"`
+	DeploymentID       bson.ObjectId `bson:"deploymentID" json:"deploymentID" validate:"-"`
+	Revision           int64         `bson:"revision" json:"revision" validate:"-"`
+	ReplicaSetRevision int64         `bson:"replicaSetRevision" json:"replicaSetRevision" validate:"-"`
+	// The generation of the kubernetes deployment after the change, to know whether the change is observed
+	Generation int64 `bson:"generation" json:"generation" validate:"-"`
+	// The revision is created by rolling back to the revision
+	RollbackTo int64      `bson:"rollbackTo,omitempty" json:"rollbackTo,omitempty" validate:"-"`
+	Deployment Deployment `bson:"deployment" json:"deployment" validate:"-"`
+	CreatedAt  *time.Time `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
+}
+
+// GetCollection - get model mongo collection name.
+func (m DeploymentRevision) GetCollection() string {
+	return DeploymentRevisionCollectionName
+}
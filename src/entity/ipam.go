@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// IPLeaseCollectionName is a const string
+const IPLeaseCollectionName string = "ipleases"
+
+// IPLease is the structure for the ip address leased to the interface of pod
+// DeploymentName is set if the pod is created by the deployment
+type IPLease struct {
+	ID             bson.ObjectId `bson:"_id,omitempty" json:"id" validate:"-"`
+	NetworkName    string        `bson:"networkName" json:"networkName" validate:"required"`
+	IPAddress      string        `bson:"ipAddress" json:"ipAddress" validate:"-"`
+	Netmask        string        `bson:"netmask" json:"netmask" validate:"-"`
+	Gateway        string        `bson:"gateway,omitempty" json:"gateway,omitempty" validate:"-"`
+	Namespace      string        `bson:"namespace" json:"namespace" validate:"required"`
+	PodName        string        `bson:"podName" json:"podName" validate:"required"`
+	IfName         string        `bson:"ifName" json:"ifName" validate:"required"`
+	DeploymentName string        `bson:"deploymentName,omitempty" json:"deploymentName,omitempty" validate:"-"`
+	CreatedAt      *time.Time    `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
+}
+
+// GetCollection - get model mongo collection name.
+func (m IPLease) GetCollection() string {
+	return IPLeaseCollectionName
+}
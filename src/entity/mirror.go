@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MirrorCollectionName is a const string
+const MirrorCollectionName string = "mirrors"
+
+// MirrorPodInterface is the interface of the pod on the OVS bridge
+type MirrorPodInterface struct {
+	PodName   string `bson:"podName" json:"podName" validate:"required"`
+	Namespace string `bson:"namespace" json:"namespace" validate:"required"`
+	IfName    string `bson:"ifName" json:"ifName" validate:"required"`
+}
+
+// Mirror is the structure for the OVS mirror on the bridge of a node
+// The traffic from and to the Sources and the traffic of the VlanTags are copied to the OutputPort or the interface of the OutputPod.
+// The mirror is removed after the TTL in minutes.
+type Mirror struct {
+	ID         bson.ObjectId        `bson:"_id,omitempty" json:"id" validate:"-"`
+	NetworkID  bson.ObjectId        `bson:"networkID" json:"networkID" validate:"-"`
+	Name       string               `bson:"name" json:"name" validate:"required,k8sname"`
+	NodeName   string               `bson:"nodeName" json:"nodeName" validate:"required"`
+	Sources    []MirrorPodInterface `bson:"sources,omitempty" json:"sources,omitempty" validate:"omitempty,dive,required"`
+	VlanTags   []int32              `bson:"vlanTags,omitempty" json:"vlanTags,omitempty" validate:"omitempty,dive,max=4095,min=0"`
+	OutputPort string               `bson:"outputPort,omitempty" json:"outputPort,omitempty" validate:"-"`
+	OutputPod  *MirrorPodInterface  `bson:"outputPod,omitempty" json:"outputPod,omitempty" validate:"omitempty"`
+	TTL        int                  `bson:"ttl" json:"ttl" validate:"min=0,max=1440"`
+
+	// It's generated by vortex
+	BridgeName string     `bson:"bridgeName" json:"bridgeName" validate:"-"`
+	MirrorName string     `bson:"mirrorName" json:"mirrorName" validate:"-"`
+	ExpiresAt  *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty" validate:"-"`
+	CreatedAt  *time.Time `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
+}
+
+// GetCollection - get model mongo collection name.
+func (m Mirror) GetCollection() string {
+	return MirrorCollectionName
+}
@@ -13,18 +13,29 @@ type NetworkType string
 const (
 	OVSKernelspaceNetworkType NetworkType = "system"
 	OVSUserspaceNetworkType   NetworkType = "netdev"
+	LinuxBridgeNetworkType    NetworkType = "linux"
+	SRIOVNetworkType          NetworkType = "sriov"
 	FakeNetworkType           NetworkType = "fake"
 )
 
+// These are the tunnel types of the overlay network
+const (
+	OverlayVXLAN string = "vxlan"
+	OverlayGRE   string = "gre"
+)
+
 // The const for NetworkCollectionName
 const (
 	NetworkCollectionName string = "networks"
+	VNICollectionName     string = "vnis"
 )
 
 // PhyInterface is the structure for physical interface
 type PhyInterface struct {
 	Name  string `bson:"name" json:"name" validate:"required"`
 	PCIID string `bson:"pciID" json:"pciID" validate:"-"`
+	// NumVFs is the number of virtual functions carved out of the interface, only for the sriov network
+	NumVFs int32 `bson:"numVFs,omitempty" json:"numVFs,omitempty" validate:"min=0"`
 }
 
 // Node is the structure for node info
@@ -33,21 +44,88 @@ type Node struct {
 	PhyInterfaces []PhyInterface `bson:"physicalInterfaces" json:"physicalInterfaces" validate:"required,dive,required"`
 }
 
+// NetworkQoS is the bandwidth limits of the interface on the OVS bridge, the rates are in kbps and the bursts are in kb.
+// The ingress is the traffic received by the bridge from the pod, it's policed and the exceeded packets are dropped.
+// The egress is the traffic sent to the pod by the bridge, it's shaped by the QoS of the port.
+// The burst is ignored if the rate isn't set.
+type NetworkQoS struct {
+	IngressRate  int64 `bson:"ingressRate,omitempty" json:"ingressRate,omitempty" validate:"min=0"`
+	IngressBurst int64 `bson:"ingressBurst,omitempty" json:"ingressBurst,omitempty" validate:"min=0"`
+	EgressRate   int64 `bson:"egressRate,omitempty" json:"egressRate,omitempty" validate:"min=0"`
+	EgressBurst  int64 `bson:"egressBurst,omitempty" json:"egressBurst,omitempty" validate:"min=0"`
+}
+
+// AllocationPool is the range of the ip addresses which can be leased to the pods
+type AllocationPool struct {
+	Start string `bson:"start" json:"start" validate:"required,ipv4"`
+	End   string `bson:"end" json:"end" validate:"required,ipv4"`
+}
+
+// Subnet is the structure for the subnet of network
+// The whole subnet except the gateway is used if there's no allocation pools
+type Subnet struct {
+	CIDR            string           `bson:"cidr" json:"cidr" validate:"required,cidrv4"`
+	Gateway         string           `bson:"gateway,omitempty" json:"gateway,omitempty" validate:"omitempty,ipv4"`
+	AllocationPools []AllocationPool `bson:"allocationPools,omitempty" json:"allocationPools,omitempty" validate:"omitempty,dive,required"`
+}
+
 // Network is the structure for Network info
+// The ip addresses of the pods are leased from the Subnets if the pod network doesn't set the ip address
+// OverlayType is the type of tunnels between the bridges of all nodes and the VNI is allocated by vortex
 type Network struct {
-	ID         bson.ObjectId `bson:"_id,omitempty" json:"id" validate:"-"`
-	OwnerID    bson.ObjectId `bson:"ownerID,omitempty" json:"ownerID" validate:"-"`
-	Type       NetworkType   `bson:"type" json:"type" validate:"required"`
-	IsDPDKPort bool          `bson:"isDPDKPort" json:"isDPDKPort" validate:"-"`
-	Name       string        `bson:"name" json:"name" validate:"required"`
-	VlanTags   []int32       `bson:"vlanTags" json:"vlanTags" validate:"required,dive,max=4095,min=0"`
-	BridgeName string        `bson:"bridgeName" json:"bridgeName" validate:"-"`
-	Nodes      []Node        `bson:"nodes" json:"nodes" validate:"required,dive,required"`
-	CreatedBy  User          `json:"createdBy" validate:"-"`
-	CreatedAt  *time.Time    `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
+	ID          bson.ObjectId `bson:"_id,omitempty" json:"id" validate:"-"`
+	OwnerID     bson.ObjectId `bson:"ownerID,omitempty" json:"ownerID" validate:"-"`
+	Type        NetworkType   `bson:"type" json:"type" validate:"required"`
+	IsDPDKPort  bool          `bson:"isDPDKPort" json:"isDPDKPort" validate:"-"`
+	Name        string        `bson:"name" json:"name" validate:"required"`
+	VlanTags    []int32       `bson:"vlanTags" json:"vlanTags" validate:"required,dive,max=4095,min=0"`
+	BridgeName  string        `bson:"bridgeName" json:"bridgeName" validate:"-"`
+	Nodes       []Node        `bson:"nodes" json:"nodes" validate:"required,dive,required"`
+	OverlayType string        `bson:"overlayType,omitempty" json:"overlayType,omitempty" validate:"omitempty,eq=vxlan|eq=gre"`
+	VNI         int32         `bson:"vni,omitempty" json:"vni,omitempty" validate:"-"`
+	Subnets     []Subnet      `bson:"subnets,omitempty" json:"subnets,omitempty" validate:"omitempty,dive,required"`
+	CreatedBy   User          `json:"createdBy" validate:"-"`
+	CreatedAt   *time.Time    `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
+	Results     []NodeResult  `bson:"-" json:"results,omitempty" validate:"-"`
+}
+
+// NodeResult is the result of the network operation on the node
+// The failed step and message are set if the operation fails, and RolledBack is set if the changes on the node are undone.
+type NodeResult struct {
+	Node       string `json:"node"`
+	Success    bool   `json:"success"`
+	Step       string `json:"step,omitempty"`
+	Message    string `json:"message,omitempty"`
+	RolledBack bool   `json:"rolledBack,omitempty"`
+}
+
+// NodeHealth is the drift between the network and the actual bridge and ports on the node
+// The Error is set if the node can't be checked, and the RepairError is set if the drift can't be repaired.
+type NodeHealth struct {
+	Node          string   `json:"node"`
+	Healthy       bool     `json:"healthy"`
+	Error         string   `json:"error,omitempty"`
+	MissingBridge bool     `json:"missingBridge,omitempty"`
+	MissingPorts  []string `json:"missingPorts,omitempty"`
+	ExtraPorts    []string `json:"extraPorts,omitempty"`
+	WrongTrunks   []string `json:"wrongTrunks,omitempty"`
+	Repaired      bool     `json:"repaired,omitempty"`
+	RepairError   string   `json:"repairError,omitempty"`
+}
+
+// NetworkHealth is the health of the network on all nodes
+type NetworkHealth struct {
+	Healthy bool         `json:"healthy"`
+	Nodes   []NodeHealth `json:"nodes"`
 }
 
 // GetCollection - get model mongo collection name.
 func (m Network) GetCollection() string {
 	return NetworkCollectionName
 }
+
+// VNIAllocation is the structure for the VNI allocated to the overlay network
+type VNIAllocation struct {
+	VNI         int32  `bson:"_id" json:"vni"`
+	NetworkName string `bson:"networkName" json:"networkName"`
+}
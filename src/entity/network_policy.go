@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// NetworkPolicyCollectionName is a const string
+const NetworkPolicyCollectionName string = "network_policies"
+
+// These are the actions, directions and protocols of the policy rule
+const (
+	PolicyActionAllow  string = "allow"
+	PolicyActionDeny   string = "deny"
+	PolicyIngress      string = "ingress"
+	PolicyEgress       string = "egress"
+	PolicyProtocolTCP  string = "tcp"
+	PolicyProtocolUDP  string = "udp"
+	PolicyProtocolICMP string = "icmp"
+)
+
+// NetworkPolicyRule is the structure for the rule of the network policy
+// The peer is selected by the pod labels or the CIDR, all addresses are matched if both are empty.
+// The port is only for the tcp and udp, all protocols of the ip are matched if the protocol is empty.
+type NetworkPolicyRule struct {
+	Action      string            `bson:"action" json:"action" validate:"required,eq=allow|eq=deny"`
+	Direction   string            `bson:"direction" json:"direction" validate:"required,eq=ingress|eq=egress"`
+	PodSelector map[string]string `bson:"podSelector,omitempty" json:"podSelector,omitempty" validate:"omitempty,dive,keys,printascii,endkeys,required,printascii"`
+	CIDR        string            `bson:"cidr,omitempty" json:"cidr,omitempty" validate:"omitempty,cidr"`
+	Protocol    string            `bson:"protocol,omitempty" json:"protocol,omitempty" validate:"omitempty,eq=tcp|eq=udp|eq=icmp"`
+	Port        int               `bson:"port,omitempty" json:"port,omitempty" validate:"omitempty,min=1,max=65535"`
+}
+
+// NetworkPolicy is the structure for the allow/deny rules of the pods on the custom network
+// The rules apply to the pods selected by the PodSelector, all pods of the network are selected if it's empty.
+// The policy with the higher priority is matched first, and the rules of a policy are matched in order.
+// The policies of a network can't have the same priority since their flows would have the same OpenFlow priority.
+type NetworkPolicy struct {
+	ID          bson.ObjectId       `bson:"_id,omitempty" json:"id" validate:"-"`
+	NetworkID   bson.ObjectId       `bson:"networkID" json:"networkID" validate:"-"`
+	Name        string              `bson:"name" json:"name" validate:"required,k8sname"`
+	PodSelector map[string]string   `bson:"podSelector,omitempty" json:"podSelector,omitempty" validate:"omitempty,dive,keys,printascii,endkeys,required,printascii"`
+	Priority    int                 `bson:"priority" json:"priority" validate:"min=0,max=100"`
+	Rules       []NetworkPolicyRule `bson:"rules" json:"rules" validate:"required,min=1,max=100,dive,required"`
+	CreatedAt   *time.Time          `bson:"createdAt,omitempty" json:"createdAt,omitempty" validate:"-"`
+}
+
+// GetCollection - get model mongo collection name.
+func (m NetworkPolicy) GetCollection() string {
+	return NetworkPolicyCollectionName
+}
@@ -0,0 +1,26 @@
+package entity
+
+// NodeInterface is the physical interface of the node which can be used by the networks
+// The NIC information is from the node exporter, and the OVSPort and Trunk are from the network controller of the node.
+// UsedBy is the vortex networks which have already used the interface, separated by commas.
+type NodeInterface struct {
+	Name      string  `json:"name"`
+	PCIID     string  `json:"pciID"`
+	Type      string  `json:"type"`
+	DPDK      bool    `json:"dpdk"`
+	Default   bool    `json:"default"`
+	OVSPort   bool    `json:"ovsPort"`
+	Trunk     []int32 `json:"trunk,omitempty"`
+	UsedBy    string  `json:"usedBy,omitempty"`
+	Available bool    `json:"available"`
+}
+
+// NodeInterfaces is the physical interfaces of the node
+// The ControllerError is set if the network controller of the node can't be reached, so the OVS ports are unknown.
+// The MetricsError is set if the prometheus can't be reached, so the interfaces are unknown.
+type NodeInterfaces struct {
+	Node            string          `json:"node"`
+	Interfaces      []NodeInterface `json:"interfaces"`
+	ControllerError string          `json:"controllerError,omitempty"`
+	MetricsError    string          `json:"metricsError,omitempty"`
+}
@@ -1,5 +1,34 @@
 package entity
 
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// OVSPortStatsCollectionName's const
+const (
+	OVSPortStatsCollectionName string = "ovsportstats"
+	OVSPortGCCollectionName    string = "ovsportgc"
+)
+
+// OVSPortType is what the OVS port is connected to
+type OVSPortType string
+
+// These are the types of the OVS port
+const (
+	// the veth of a pod created by the deployment or the pod api
+	OVSPodPortType OVSPortType = "pod"
+	// the physical interface of the node
+	OVSUplinkPortType OVSPortType = "uplink"
+	// the tunnel to the other nodes of the overlay network
+	OVSTunnelPortType OVSPortType = "tunnel"
+	// the internal port of the bridge
+	OVSInternalPortType OVSPortType = "internal"
+	// the port doesn't belong to anything vortex knows, e.g. the veth of the deleted pod
+	OVSUnknownPortType OVSPortType = "unknown"
+)
+
 // PortStatsTransmit contains information regarding the number of transmitted
 // packets, bytes, etc.
 type OVSPortStats struct {
@@ -13,8 +42,84 @@ type OVSPortInfo struct {
 	PortID        int32        `json:"portID"`
 	Name          string       `json:"name"`
 	PodName       string       `json:"podName"`
+	Namespace     string       `json:"namespace"`
 	InterfaceName string       `json:"interfaceName"`
+	Type          OVSPortType  `json:"type"`
 	MacAddress    string       `json:"macAddress"`
 	Received      OVSPortStats `json:"received"`
 	Transmitted   OVSPortStats `json:"traansmitted"`
+	// the bandwidth limits set on the OVS interface, it's empty if the port isn't limited
+	QoS *NetworkQoS `json:"qos,omitempty"`
+}
+
+// OVSNetworkPort is the OVS port with the network and node where it is
+type OVSNetworkPort struct {
+	NetworkName string `json:"networkName"`
+	NodeName    string `json:"nodeName"`
+	BridgeName  string `json:"bridgeName"`
+	OVSPortInfo
+}
+
+// OVSPortInventory is the OVS ports of all networks, the Errors has the nodes whose ports can't be dumped
+type OVSPortInventory struct {
+	Ports  []OVSNetworkPort `json:"ports"`
+	Errors []string         `json:"errors,omitempty"`
+}
+
+// OVSPortStatsSample is the counters of the OVS port polled at the time
+type OVSPortStatsSample struct {
+	ID            bson.ObjectId `bson:"_id,omitempty" json:"-"`
+	NodeName      string        `bson:"nodeName" json:"nodeName"`
+	BridgeName    string        `bson:"bridgeName" json:"bridgeName"`
+	PortName      string        `bson:"portName" json:"portName"`
+	PodName       string        `bson:"podName" json:"podName"`
+	Namespace     string        `bson:"namespace" json:"namespace"`
+	InterfaceName string        `bson:"interfaceName" json:"interfaceName"`
+	Received      OVSPortStats  `bson:"received" json:"received"`
+	Transmitted   OVSPortStats  `bson:"transmitted" json:"transmitted"`
+	CreatedAt     time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+// OVSPortRates is the rates of the counters per second
+type OVSPortRates struct {
+	BytesPerSec   float64 `json:"bytesPerSec"`
+	PacketsPerSec float64 `json:"packetsPerSec"`
+	DroppedPerSec float64 `json:"droppedPerSec"`
+	ErrorsPerSec  float64 `json:"errorsPerSec"`
+}
+
+// OVSPortRatesPoint is the rates between the previous sample and the sample at the timestamp
+type OVSPortRatesPoint struct {
+	Timestamp   time.Time    `json:"timestamp"`
+	Received    OVSPortRates `json:"received"`
+	Transmitted OVSPortRates `json:"transmitted"`
+}
+
+// OVSPortHistory is the rates of the OVS port over the time window
+type OVSPortHistory struct {
+	NodeName      string              `json:"nodeName"`
+	BridgeName    string              `json:"bridgeName"`
+	PortName      string              `json:"portName"`
+	PodName       string              `json:"podName"`
+	Namespace     string              `json:"namespace"`
+	InterfaceName string              `json:"interfaceName"`
+	Points        []OVSPortRatesPoint `json:"points"`
+}
+
+// OVSPortGCEntry is the orphaned port found by the garbage collector, it's saved as the audit entry when the port is removed
+type OVSPortGCEntry struct {
+	ID          bson.ObjectId `bson:"_id,omitempty" json:"id,omitempty"`
+	NetworkName string        `bson:"networkName" json:"networkName"`
+	NodeName    string        `bson:"nodeName" json:"nodeName"`
+	BridgeName  string        `bson:"bridgeName" json:"bridgeName"`
+	PortName    string        `bson:"portName" json:"portName"`
+	Removed     bool          `bson:"removed" json:"removed"`
+	Error       string        `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   *time.Time    `bson:"createdAt,omitempty" json:"createdAt,omitempty"`
+}
+
+// OVSPortGCResult is the orphaned ports of all OVS networks, the Errors has the nodes whose ports can't be dumped
+type OVSPortGCResult struct {
+	Ports  []OVSPortGCEntry `json:"ports"`
+	Errors []string         `json:"errors,omitempty"`
 }
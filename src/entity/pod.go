@@ -23,17 +23,63 @@ type Container struct {
 	Name    string   `bson:"name" json:"name" validate:"required,k8sname"`
 	Image   string   `bson:"image" json:"image" validate:"required"`
 	Command []string `bson:"command" json:"command" validate:"required,dive,required"`
+
+	Resources *ContainerResources `bson:"resources,omitempty" json:"resources,omitempty" validate:"omitempty"`
+
+	// The EnvVars of the container override the shared ones of the pod or deployment which have the same names
+	Args           []string          `bson:"args,omitempty" json:"args,omitempty" validate:"omitempty"`
+	WorkingDir     string            `bson:"workingDir,omitempty" json:"workingDir,omitempty" validate:"omitempty"`
+	EnvVars        map[string]string `bson:"envVars,omitempty" json:"envVars,omitempty" validate:"omitempty,dive,keys,printascii,endkeys,required,printascii"`
+	Ports          []ContainerPort   `bson:"ports,omitempty" json:"ports,omitempty" validate:"omitempty,dive,required"`
+	LivenessProbe  *ContainerProbe   `bson:"livenessProbe,omitempty" json:"livenessProbe,omitempty" validate:"omitempty"`
+	ReadinessProbe *ContainerProbe   `bson:"readinessProbe,omitempty" json:"readinessProbe,omitempty" validate:"omitempty"`
+}
+
+// ContainerPort is the structure for the port exposed by the container
+type ContainerPort struct {
+	Name          string `bson:"name,omitempty" json:"name,omitempty" validate:"omitempty,max=15"`
+	ContainerPort int32  `bson:"containerPort" json:"containerPort" validate:"required,min=1,max=65535"`
+	Protocol      string `bson:"protocol,omitempty" json:"protocol,omitempty" validate:"omitempty,eq=TCP|eq=UDP"`
+}
+
+// These are the types of the container probes
+const (
+	ContainerProbeExec      = "exec"
+	ContainerProbeHTTPGet   = "httpGet"
+	ContainerProbeTCPSocket = "tcpSocket"
+)
+
+// ContainerProbe is the structure for the liveness or readiness probe of the container
+// The Command is for the exec probe, the Path is for the httpGet probe and the Port is for both httpGet and tcpSocket probes.
+type ContainerProbe struct {
+	Type                string   `bson:"type" json:"type" validate:"required,eq=exec|eq=httpGet|eq=tcpSocket"`
+	Command             []string `bson:"command,omitempty" json:"command,omitempty" validate:"omitempty,dive,required"`
+	Path                string   `bson:"path,omitempty" json:"path,omitempty" validate:"omitempty"`
+	Port                int32    `bson:"port,omitempty" json:"port,omitempty" validate:"omitempty,min=1,max=65535"`
+	InitialDelaySeconds int32    `bson:"initialDelaySeconds,omitempty" json:"initialDelaySeconds,omitempty" validate:"omitempty,min=0"`
+	PeriodSeconds       int32    `bson:"periodSeconds,omitempty" json:"periodSeconds,omitempty" validate:"omitempty,min=0"`
+	TimeoutSeconds      int32    `bson:"timeoutSeconds,omitempty" json:"timeoutSeconds,omitempty" validate:"omitempty,min=0"`
+	SuccessThreshold    int32    `bson:"successThreshold,omitempty" json:"successThreshold,omitempty" validate:"omitempty,min=0"`
+	FailureThreshold    int32    `bson:"failureThreshold,omitempty" json:"failureThreshold,omitempty" validate:"omitempty,min=0"`
+}
+
+// ContainerResources is the structure for the resource requests and limits of the container
+// The keys are cpu, memory, ephemeral-storage and hugepages-<size> like hugepages-2Mi,
+// and the values are the quantities like 500m, 2 and 1Gi.
+type ContainerResources struct {
+	Requests map[string]string `bson:"requests,omitempty" json:"requests,omitempty" validate:"omitempty,dive,keys,required,endkeys,required"`
+	Limits   map[string]string `bson:"limits,omitempty" json:"limits,omitempty" validate:"omitempty,dive,keys,required,endkeys,required"`
 }
 
 // PodRouteGw is the structure for add IP routing table with gateway
 type PodRouteGw struct {
-	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidrv4"`
-	Gateway string `bson:"gateway" json:"gateway" validate:"required,ipv4"`
+	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidr"`
+	Gateway string `bson:"gateway" json:"gateway" validate:"required,ip"`
 }
 
 // PodRouteIntf is the structure for add IP routing table via interface
 type PodRouteIntf struct {
-	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidrv4"`
+	DstCIDR string `bson:"dstCIDR" json:"dstCIDR" validate:"required,cidr"`
 }
 
 // PodNetwork is the structure for pod network info
@@ -42,13 +88,25 @@ type PodNetwork struct {
 	IfName string `bson:"ifName" json:"ifName" validate:"required"`
 	// can not validate nil
 	VlanTag    *int32         `bson:"vlanTag" json:"vlanTag" validate:"-"`
-	IPAddress  string         `bson:"ipAddress" json:"ipAddress" validate:"required,ipv4"`
-	Netmask    string         `bson:"netmask" json:"netmask" validate:"required,ipv4"`
+	IPAddress  string         `bson:"ipAddress" json:"ipAddress" validate:"omitempty,ip"`
+	Netmask    string         `bson:"netmask" json:"netmask" validate:"omitempty,netmask"`
 	RoutesGw   []PodRouteGw   `bson:"routesGw,omitempty" json:"routesGw" validate:"required,dive,required"`
 	RoutesIntf []PodRouteIntf `bson:"routesIntf,omitempty" json:"routesIntf" validate:"required,dive,required"`
 
+	// The additional addresses of the interface in the cidr format, e.g. 2001:db8::10/64 for the dual-stack
+	Addresses []string `bson:"addresses,omitempty" json:"addresses,omitempty" validate:"omitempty,dive,cidr"`
+
+	// The MAC address of the virtual function, only for the sriov network
+	MacAddress string `bson:"macAddress,omitempty" json:"macAddress,omitempty" validate:"omitempty,mac"`
+
+	// The bandwidth limits of the interface, only for the OVS network
+	QoS *NetworkQoS `bson:"qos,omitempty" json:"qos,omitempty" validate:"omitempty"`
+
 	// It's from the entity.Network entity
-	BridgeName string `bson:"bridgeName" json:"bridgeName" validate:"-"`
+	BridgeName  string      `bson:"bridgeName" json:"bridgeName" validate:"-"`
+	NetworkType NetworkType `bson:"networkType,omitempty" json:"networkType,omitempty" validate:"-"`
+	// The physical functions of each node, only for the sriov network
+	Nodes []Node `bson:"-" json:"-" validate:"-"`
 }
 
 // PodVolume is the structure for pod volume info
@@ -0,0 +1,361 @@
+package ipam
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/utils/timeutils"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// LeaseGracePeriod is the period the lease is kept before the pod is created
+const LeaseGracePeriod = time.Minute
+
+// ErrNoFreeAddress is returned if all ip addresses of the network are leased
+var ErrNoFreeAddress = fmt.Errorf("there's no free ip address")
+
+type addressRange struct {
+	start uint64
+	end   uint64
+}
+
+func ipToUint(ip net.IP) uint64 {
+	return uint64(binary.BigEndian.Uint32(ip.To4()))
+}
+
+func uintToIP(n uint64) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, uint32(n))
+	return ip
+}
+
+// Get the ranges of the ip addresses in the subnet which can be leased
+// The network and broadcast addresses are excluded if there's no allocation pools
+func generateRanges(subnet entity.Subnet) (*net.IPNet, []addressRange, error) {
+	_, ipnet, err := net.ParseCIDR(subnet.CIDR)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ipnet.IP.To4() == nil {
+		return nil, nil, fmt.Errorf("the subnet %s isn't the ipv4 subnet", subnet.CIDR)
+	}
+
+	if len(subnet.AllocationPools) == 0 {
+		ones, bits := ipnet.Mask.Size()
+		start := ipToUint(ipnet.IP)
+		end := start + 1<<uint(bits-ones) - 1
+		if bits-ones > 1 {
+			start, end = start+1, end-1
+		}
+		return ipnet, []addressRange{{start, end}}, nil
+	}
+
+	ranges := []addressRange{}
+	for _, pool := range subnet.AllocationPools {
+		start, end := net.ParseIP(pool.Start), net.ParseIP(pool.End)
+		if start == nil || end == nil || start.To4() == nil || end.To4() == nil {
+			return nil, nil, fmt.Errorf("the allocation pool %s-%s is invalid", pool.Start, pool.End)
+		}
+		if !ipnet.Contains(start) || !ipnet.Contains(end) {
+			return nil, nil, fmt.Errorf("the allocation pool %s-%s isn't in the subnet %s", pool.Start, pool.End, subnet.CIDR)
+		}
+		if ipToUint(start) > ipToUint(end) {
+			return nil, nil, fmt.Errorf("the start of allocation pool %s-%s is greater than the end", pool.Start, pool.End)
+		}
+		ranges = append(ranges, addressRange{ipToUint(start), ipToUint(end)})
+	}
+	return ipnet, ranges, nil
+}
+
+// ValidateSubnets will check the allocation pools and gateway are in the subnets
+func ValidateSubnets(subnets []entity.Subnet) error {
+	for _, subnet := range subnets {
+		ipnet, _, err := generateRanges(subnet)
+		if err != nil {
+			return err
+		}
+		if subnet.Gateway != "" && !ipnet.Contains(net.ParseIP(subnet.Gateway)) {
+			return fmt.Errorf("the gateway %s isn't in the subnet %s", subnet.Gateway, subnet.CIDR)
+		}
+	}
+	return nil
+}
+
+func ensureIndex(session *mongo.Session) {
+	session.C(entity.IPLeaseCollectionName).EnsureIndex(mgo.Index{
+		Key:    []string{"networkName", "ipAddress"},
+		Unique: true,
+	})
+	session.C(entity.IPLeaseCollectionName).EnsureIndex(mgo.Index{
+		Key:    []string{"networkName", "namespace", "podName", "ifName"},
+		Unique: true,
+	})
+}
+
+func findLease(session *mongo.Session, lease *entity.IPLease) (bool, error) {
+	existed := entity.IPLease{}
+	err := session.FindOne(entity.IPLeaseCollectionName, bson.M{
+		"networkName": lease.NetworkName,
+		"namespace":   lease.Namespace,
+		"podName":     lease.PodName,
+		"ifName":      lease.IfName,
+	}, &existed)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	*lease = existed
+	return true, nil
+}
+
+func allocate(session *mongo.Session, network *entity.Network, lease *entity.IPLease) error {
+	leases := []entity.IPLease{}
+	if err := session.FindAll(entity.IPLeaseCollectionName, bson.M{"networkName": network.Name}, &leases); err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, v := range leases {
+		used[v.IPAddress] = true
+	}
+
+	for _, subnet := range network.Subnets {
+		ipnet, ranges, err := generateRanges(subnet)
+		if err != nil {
+			return err
+		}
+		for _, r := range ranges {
+			for n := r.start; n <= r.end; n++ {
+				address := uintToIP(n).String()
+				if used[address] || address == subnet.Gateway {
+					continue
+				}
+
+				lease.ID = bson.NewObjectId()
+				lease.IPAddress = address
+				lease.Netmask = net.IP(ipnet.Mask).String()
+				lease.Gateway = subnet.Gateway
+				lease.CreatedAt = timeutils.Now()
+				err := session.Insert(entity.IPLeaseCollectionName, lease)
+				if err == nil {
+					return nil
+				} else if !mgo.IsDup(err) {
+					return err
+				}
+				//The address or the interface is leased at the same time
+				if found, err := findLease(session, lease); err != nil || found {
+					return err
+				}
+			}
+		}
+	}
+	return ErrNoFreeAddress
+}
+
+// CountFreeAddresses will return the number of the ip addresses of the network which aren't leased
+// The stale leases aren't released, so the real number may be greater.
+func CountFreeAddresses(session *mongo.Session, network *entity.Network) (int, error) {
+	leases := []entity.IPLease{}
+	if err := session.FindAll(entity.IPLeaseCollectionName, bson.M{"networkName": network.Name}, &leases); err != nil {
+		return 0, err
+	}
+
+	free := 0
+	for _, subnet := range network.Subnets {
+		_, ranges, err := generateRanges(subnet)
+		if err != nil {
+			return 0, err
+		}
+		used := []net.IP{}
+		for _, v := range leases {
+			used = append(used, net.ParseIP(v.IPAddress))
+		}
+		if subnet.Gateway != "" {
+			used = append(used, net.ParseIP(subnet.Gateway))
+		}
+		for _, r := range ranges {
+			free += int(r.end - r.start + 1)
+			for _, ip := range used {
+				if ip == nil || ip.To4() == nil {
+					continue
+				}
+				if n := ipToUint(ip); n >= r.start && n <= r.end {
+					free--
+				}
+			}
+		}
+	}
+	return free, nil
+}
+
+// Allocate will lease a free ip address of the network to the interface of pod
+// The existing lease is returned if the interface has leased the ip address before,
+// and the stale leases are released if all ip addresses of the network are leased.
+func Allocate(sp *serviceprovider.Container, network *entity.Network, lease *entity.IPLease) error {
+	if len(network.Subnets) == 0 {
+		return fmt.Errorf("the network %s doesn't have any subnet", network.Name)
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	ensureIndex(session)
+
+	lease.NetworkName = network.Name
+	if found, err := findLease(session, lease); err != nil || found {
+		return err
+	}
+
+	err := allocate(session, network, lease)
+	if err != ErrNoFreeAddress {
+		return err
+	}
+	if _, err := releaseStaleLeases(sp, session, bson.M{"networkName": network.Name}); err != nil {
+		return err
+	}
+	if err := allocate(session, network, lease); err != nil {
+		return fmt.Errorf("allocate the ip address of network %s fail: %v", network.Name, err)
+	}
+	return nil
+}
+
+// Reserve will lease the static ip address of the pod, the address should be in one of the subnets
+func Reserve(sp *serviceprovider.Container, network *entity.Network, lease *entity.IPLease) error {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	ensureIndex(session)
+
+	ip := net.ParseIP(lease.IPAddress)
+	for _, subnet := range network.Subnets {
+		_, ipnet, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil {
+			return err
+		}
+		if !ipnet.Contains(ip) {
+			continue
+		}
+
+		lease.ID = bson.NewObjectId()
+		lease.NetworkName = network.Name
+		lease.Netmask = net.IP(ipnet.Mask).String()
+		lease.Gateway = subnet.Gateway
+		lease.CreatedAt = timeutils.Now()
+		if err := session.Insert(entity.IPLeaseCollectionName, lease); err != nil {
+			if mgo.IsDup(err) {
+				return fmt.Errorf("the ip address %s of network %s has been leased", lease.IPAddress, network.Name)
+			}
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("the ip address %s isn't in the subnets of network %s", lease.IPAddress, network.Name)
+}
+
+// OutsideLeases will return the leases of the network which aren't in its subnets or are the gateways,
+// it's used to check the subnets of the updated network since the leased ip addresses are used by the pods.
+func OutsideLeases(session *mongo.Session, network *entity.Network) ([]entity.IPLease, error) {
+	leases := []entity.IPLease{}
+	if err := session.FindAll(entity.IPLeaseCollectionName, bson.M{"networkName": network.Name}, &leases); err != nil {
+		return nil, err
+	}
+
+	outside := []entity.IPLease{}
+	for _, lease := range leases {
+		ip := net.ParseIP(lease.IPAddress)
+		found := false
+		for _, subnet := range network.Subnets {
+			_, ipnet, err := net.ParseCIDR(subnet.CIDR)
+			if err != nil {
+				return nil, err
+			}
+			if ipnet.Contains(ip) && lease.IPAddress != subnet.Gateway {
+				found = true
+				break
+			}
+		}
+		if !found {
+			outside = append(outside, lease)
+		}
+	}
+	return outside, nil
+}
+
+// ReleasePod will release all leases of the pod
+func ReleasePod(session *mongo.Session, namespace, podName string) error {
+	_, err := session.C(entity.IPLeaseCollectionName).RemoveAll(bson.M{"namespace": namespace, "podName": podName})
+	return err
+}
+
+// ReleaseDeployment will release all leases of the pods created by the deployment
+func ReleaseDeployment(session *mongo.Session, namespace, deploymentName string) error {
+	_, err := session.C(entity.IPLeaseCollectionName).RemoveAll(bson.M{"namespace": namespace, "deploymentName": deploymentName})
+	return err
+}
+
+// ReleaseNetwork will release all leases of the network
+func ReleaseNetwork(session *mongo.Session, networkName string) error {
+	_, err := session.C(entity.IPLeaseCollectionName).RemoveAll(bson.M{"networkName": networkName})
+	return err
+}
+
+// The lease is stale if its pod or deployment doesn't exist in the kubernetes
+func releaseStaleLeases(sp *serviceprovider.Container, session *mongo.Session, selector bson.M) (int, error) {
+	leases := []entity.IPLease{}
+	if err := session.FindAll(entity.IPLeaseCollectionName, selector, &leases); err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, lease := range leases {
+		//The pod is created after the lease is allocated
+		if lease.CreatedAt != nil && time.Since(*lease.CreatedAt) < LeaseGracePeriod {
+			continue
+		}
+		var err error
+		if lease.PodName == lease.DeploymentName {
+			//The static ip address is leased by the deployment itself
+			_, err = sp.KubeCtl.GetDeployment(lease.DeploymentName, lease.Namespace)
+		} else {
+			_, err = sp.KubeCtl.GetPod(lease.PodName, lease.Namespace)
+		}
+		if err == nil {
+			continue
+		} else if !errors.IsNotFound(err) {
+			return released, err
+		}
+		if err := session.Remove(entity.IPLeaseCollectionName, "_id", lease.ID); err != nil && err != mgo.ErrNotFound {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
+
+// ReleaseStaleLeases will release the leases whose pods have gone away
+func ReleaseStaleLeases(sp *serviceprovider.Container) (int, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	return releaseStaleLeases(sp, session, bson.M{})
+}
+
+// CollectStaleLeases will release the stale leases periodically
+func CollectStaleLeases(sp *serviceprovider.Container, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		released, err := ReleaseStaleLeases(sp)
+		if err != nil {
+			logger.Warnf("release the stale ip leases fail: %v", err)
+		} else if released > 0 {
+			logger.Infof("release %d stale ip leases", released)
+		}
+	}
+}
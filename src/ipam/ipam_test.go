@@ -0,0 +1,191 @@
+package ipam
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/moby/moby/pkg/namesgenerator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSubnets(t *testing.T) {
+	testCases := []struct {
+		cases   string
+		subnets []entity.Subnet
+		valid   bool
+	}{
+		{"noSubnet", []entity.Subnet{}, true},
+		{"subnet", []entity.Subnet{{CIDR: "10.1.0.0/24", Gateway: "10.1.0.1"}}, true},
+		{"pool", []entity.Subnet{{CIDR: "10.1.0.0/24", AllocationPools: []entity.AllocationPool{{Start: "10.1.0.10", End: "10.1.0.20"}}}}, true},
+		{"invalidCIDR", []entity.Subnet{{CIDR: "10.1.0.0/33"}}, false},
+		{"gatewayOutOfSubnet", []entity.Subnet{{CIDR: "10.1.0.0/24", Gateway: "10.2.0.1"}}, false},
+		{"poolOutOfSubnet", []entity.Subnet{{CIDR: "10.1.0.0/24", AllocationPools: []entity.AllocationPool{{Start: "10.1.0.10", End: "10.1.1.20"}}}}, false},
+		{"reversedPool", []entity.Subnet{{CIDR: "10.1.0.0/24", AllocationPools: []entity.AllocationPool{{Start: "10.1.0.20", End: "10.1.0.10"}}}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			err := ValidateSubnets(tc.subnets)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateRanges(t *testing.T) {
+	_, ranges, err := generateRanges(entity.Subnet{CIDR: "10.1.0.0/24"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ranges))
+	assert.Equal(t, "10.1.0.1", uintToIP(ranges[0].start).String())
+	assert.Equal(t, "10.1.0.254", uintToIP(ranges[0].end).String())
+
+	_, ranges, err = generateRanges(entity.Subnet{CIDR: "10.1.0.0/31"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.0", uintToIP(ranges[0].start).String())
+	assert.Equal(t, "10.1.0.1", uintToIP(ranges[0].end).String())
+}
+
+func TestAllocate(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := &entity.Network{
+		Name: namesgenerator.GetRandomName(0),
+		Subnets: []entity.Subnet{
+			{
+				CIDR:            "10.1.0.0/24",
+				Gateway:         "10.1.0.10",
+				AllocationPools: []entity.AllocationPool{{Start: "10.1.0.10", End: "10.1.0.12"}},
+			},
+		},
+	}
+	defer ReleaseNetwork(session, network.Name)
+
+	//The gateway is excluded
+	lease := entity.IPLease{Namespace: "default", PodName: "pod-1", IfName: "eth1"}
+	err := Allocate(sp, network, &lease)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.11", lease.IPAddress)
+	assert.Equal(t, "255.255.255.0", lease.Netmask)
+
+	//The same interface gets the same lease
+	again := entity.IPLease{Namespace: "default", PodName: "pod-1", IfName: "eth1"}
+	err = Allocate(sp, network, &again)
+	assert.NoError(t, err)
+	assert.Equal(t, lease.IPAddress, again.IPAddress)
+
+	another := entity.IPLease{Namespace: "default", PodName: "pod-2", IfName: "eth1"}
+	err = Allocate(sp, network, &another)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.12", another.IPAddress)
+
+	//The pool is exhausted and the leases are in the grace period
+	exhausted := entity.IPLease{Namespace: "default", PodName: "pod-3", IfName: "eth1"}
+	err = Allocate(sp, network, &exhausted)
+	assert.Error(t, err)
+
+	err = ReleasePod(session, "default", "pod-1")
+	assert.NoError(t, err)
+	err = Allocate(sp, network, &exhausted)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.11", exhausted.IPAddress)
+}
+
+func TestCountFreeAddresses(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := &entity.Network{
+		Name: namesgenerator.GetRandomName(0),
+		Subnets: []entity.Subnet{
+			{
+				CIDR:            "10.1.0.0/24",
+				Gateway:         "10.1.0.10",
+				AllocationPools: []entity.AllocationPool{{Start: "10.1.0.10", End: "10.1.0.14"}},
+			},
+		},
+	}
+	defer ReleaseNetwork(session, network.Name)
+
+	//The gateway is excluded
+	free, err := CountFreeAddresses(session, network)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, free)
+
+	lease := entity.IPLease{Namespace: "default", PodName: "pod-1", IfName: "eth1"}
+	err = Allocate(sp, network, &lease)
+	assert.NoError(t, err)
+
+	free, err = CountFreeAddresses(session, network)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, free)
+}
+
+func TestReserve(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := &entity.Network{
+		Name:    namesgenerator.GetRandomName(0),
+		Subnets: []entity.Subnet{{CIDR: "10.1.0.0/24"}},
+	}
+	defer ReleaseNetwork(session, network.Name)
+
+	lease := entity.IPLease{Namespace: "default", PodName: "pod-1", IfName: "eth1", IPAddress: "10.1.0.100"}
+	err := Reserve(sp, network, &lease)
+	assert.NoError(t, err)
+
+	//The address can not be reserved twice
+	conflict := entity.IPLease{Namespace: "default", PodName: "pod-2", IfName: "eth1", IPAddress: "10.1.0.100"}
+	err = Reserve(sp, network, &conflict)
+	assert.Error(t, err)
+
+	outside := entity.IPLease{Namespace: "default", PodName: "pod-3", IfName: "eth1", IPAddress: "10.2.0.100"}
+	err = Reserve(sp, network, &outside)
+	assert.Error(t, err)
+}
+
+func TestOutsideLeases(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := &entity.Network{
+		Name:    namesgenerator.GetRandomName(0),
+		Subnets: []entity.Subnet{{CIDR: "10.1.0.0/24"}},
+	}
+	defer ReleaseNetwork(session, network.Name)
+
+	lease := entity.IPLease{Namespace: "default", PodName: "pod-1", IfName: "eth1", IPAddress: "10.1.0.100"}
+	assert.NoError(t, Reserve(sp, network, &lease))
+
+	outside, err := OutsideLeases(session, network)
+	assert.NoError(t, err)
+	assert.Len(t, outside, 0)
+
+	//The subnet is changed or the leased address becomes the gateway
+	updated := &entity.Network{Name: network.Name, Subnets: []entity.Subnet{{CIDR: "10.2.0.0/24"}}}
+	outside, err = OutsideLeases(session, updated)
+	assert.NoError(t, err)
+	assert.Len(t, outside, 1)
+	updated.Subnets = []entity.Subnet{{CIDR: "10.1.0.0/24", Gateway: "10.1.0.100"}}
+	outside, err = OutsideLeases(session, updated)
+	assert.NoError(t, err)
+	assert.Len(t, outside, 1)
+}
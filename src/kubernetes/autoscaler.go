@@ -0,0 +1,26 @@
+package kubernetes
+
+import (
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetHorizontalPodAutoscaler will get the horizontal pod autoscaler by the name
+func (kc *KubeCtl) GetHorizontalPodAutoscaler(name string, namespace string) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	return kc.Clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(name, metav1.GetOptions{})
+}
+
+// CreateHorizontalPodAutoscaler will create the horizontal pod autoscaler by the autoscaler object
+func (kc *KubeCtl) CreateHorizontalPodAutoscaler(hpa *autoscalingv1.HorizontalPodAutoscaler, namespace string) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	return kc.Clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).Create(hpa)
+}
+
+// UpdateHorizontalPodAutoscaler will update the horizontal pod autoscaler by the autoscaler object
+func (kc *KubeCtl) UpdateHorizontalPodAutoscaler(hpa *autoscalingv1.HorizontalPodAutoscaler, namespace string) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	return kc.Clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).Update(hpa)
+}
+
+// DeleteHorizontalPodAutoscaler will delete the horizontal pod autoscaler by the name
+func (kc *KubeCtl) DeleteHorizontalPodAutoscaler(name string, namespace string) error {
+	return kc.Clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).Delete(name, &metav1.DeleteOptions{})
+}
@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/moby/moby/pkg/namesgenerator"
+	"github.com/stretchr/testify/suite"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+type KubeCtlAutoscalerTestSuite struct {
+	suite.Suite
+	kubectl    *KubeCtl
+	fakeclient *fakeclientset.Clientset
+}
+
+func (suite *KubeCtlAutoscalerTestSuite) SetupSuite() {
+	suite.fakeclient = fakeclientset.NewSimpleClientset()
+	suite.kubectl = New(suite.fakeclient)
+}
+
+func (suite *KubeCtlAutoscalerTestSuite) TearDownSuite() {}
+func (suite *KubeCtlAutoscalerTestSuite) TestHorizontalPodAutoscaler() {
+	namespace := "default"
+	name := namesgenerator.GetRandomName(0)
+	minReplicas := int32(1)
+	target := int32(50)
+	hpa := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       name,
+			},
+			MinReplicas:                    &minReplicas,
+			MaxReplicas:                    3,
+			TargetCPUUtilizationPercentage: &target,
+		},
+	}
+	ret, err := suite.kubectl.CreateHorizontalPodAutoscaler(&hpa, namespace)
+	suite.NoError(err)
+	suite.NotNil(ret)
+
+	ret, err = suite.kubectl.GetHorizontalPodAutoscaler(name, namespace)
+	suite.NoError(err)
+	suite.Equal(int32(3), ret.Spec.MaxReplicas)
+
+	ret.Spec.MaxReplicas = 5
+	_, err = suite.kubectl.UpdateHorizontalPodAutoscaler(ret, namespace)
+	suite.NoError(err)
+	ret, err = suite.kubectl.GetHorizontalPodAutoscaler(name, namespace)
+	suite.NoError(err)
+	suite.Equal(int32(5), ret.Spec.MaxReplicas)
+
+	err = suite.kubectl.DeleteHorizontalPodAutoscaler(name, namespace)
+	suite.NoError(err)
+	_, err = suite.kubectl.GetHorizontalPodAutoscaler(name, namespace)
+	suite.Error(err)
+}
+
+func TestAutoscalerTestSuite(t *testing.T) {
+	suite.Run(t, new(KubeCtlAutoscalerTestSuite))
+}
@@ -3,6 +3,7 @@ package kubernetes
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // CreateDeployment will get the external IP address of node
@@ -22,12 +23,17 @@ func (kc *KubeCtl) GetDeployments(namespace string) ([]*appsv1.Deployment, error
 	if err != nil {
 		return deployments, err
 	}
-	for _, d := range deploymentsList.Items {
-		deployments = append(deployments, &d)
+	for i := 0; i < len(deploymentsList.Items); i++ {
+		deployments = append(deployments, &deploymentsList.Items[i])
 	}
 	return deployments, nil
 }
 
+// PatchDeployment will patch the deployment by the strategic merge patch
+func (kc *KubeCtl) PatchDeployment(name string, namespace string, patch []byte) (*appsv1.Deployment, error) {
+	return kc.Clientset.AppsV1().Deployments(namespace).Patch(name, types.StrategicMergePatchType, patch)
+}
+
 // DeleteDeployment will delete deploy
 func (kc *KubeCtl) DeleteDeployment(name string, namespace string) error {
 	propagation := metav1.DeletePropagationForeground
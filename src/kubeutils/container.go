@@ -0,0 +1,158 @@
+package kubeutils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// The port name is the IANA_SVC_NAME, lowercase letters, digits and hyphens with at least one letter
+var portNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+var portNameLetterRegexp = regexp.MustCompile(`[a-z]`)
+
+func checkProbe(name string, probe *entity.ContainerProbe) error {
+	if probe == nil {
+		return nil
+	}
+	switch probe.Type {
+	case entity.ContainerProbeExec:
+		if len(probe.Command) == 0 {
+			return fmt.Errorf("the command of the %s probe of the container %s is required", probe.Type, name)
+		}
+	case entity.ContainerProbeHTTPGet, entity.ContainerProbeTCPSocket:
+		if probe.Port == 0 {
+			return fmt.Errorf("the port of the %s probe of the container %s is required", probe.Type, name)
+		}
+	default:
+		return fmt.Errorf("the probe type %s of the container %s isn't supported", probe.Type, name)
+	}
+	return nil
+}
+
+// CheckContainers will check the ports and probes of the containers of a pod
+// The ports and their names can't be the same in a pod since the containers share the network.
+func CheckContainers(containers []entity.Container) error {
+	ports := map[string]bool{}
+	names := map[string]bool{}
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			key := fmt.Sprintf("%d/%s", port.ContainerPort, generateProtocol(port.Protocol))
+			if ports[key] {
+				return fmt.Errorf("the port %s of the container %s is duplicated", key, container.Name)
+			}
+			ports[key] = true
+
+			if port.Name == "" {
+				continue
+			}
+			if !portNameRegexp.MatchString(port.Name) || !portNameLetterRegexp.MatchString(port.Name) {
+				return fmt.Errorf("the port name %s of the container %s should be the lowercase letters, digits and hyphens", port.Name, container.Name)
+			}
+			if names[port.Name] {
+				return fmt.Errorf("the port name %s of the container %s is duplicated", port.Name, container.Name)
+			}
+			names[port.Name] = true
+		}
+
+		if err := checkProbe(container.Name, container.LivenessProbe); err != nil {
+			return err
+		}
+		if err := checkProbe(container.Name, container.ReadinessProbe); err != nil {
+			return err
+		}
+		//The kubernetes only allows the liveness probe succeeds once
+		if container.LivenessProbe != nil && container.LivenessProbe.SuccessThreshold > 1 {
+			return fmt.Errorf("the successThreshold of the liveness probe of the container %s should be 1", container.Name)
+		}
+	}
+	return nil
+}
+
+func generateProtocol(protocol string) corev1.Protocol {
+	if protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return corev1.Protocol(protocol)
+}
+
+// GenerateEnvVars will generate the environment variables of the container
+// The shared ones of the pod or deployment are overridden by the ones of the container which have the same names.
+func GenerateEnvVars(shared []corev1.EnvVar, container entity.Container) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{}
+	for _, v := range shared {
+		if _, ok := container.EnvVars[v.Name]; !ok {
+			envVars = append(envVars, v)
+		}
+	}
+
+	//The order of the map is random, sort them to avoid changing the pod template
+	keys := []string{}
+	for k := range container.EnvVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  k,
+			Value: container.EnvVars[k],
+		})
+	}
+	return envVars
+}
+
+// GeneratePorts will generate the ports of the container
+func GeneratePorts(container entity.Container) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{}
+	for _, port := range container.Ports {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.ContainerPort,
+			Protocol:      generateProtocol(port.Protocol),
+		})
+	}
+	return ports
+}
+
+// GenerateProbe will generate the probe of the container, or nil if it's not set
+// The probe without a handler is rejected by the kubernetes, so the unknown probe type is an error.
+func GenerateProbe(probe *entity.ContainerProbe) (*corev1.Probe, error) {
+	if probe == nil {
+		return nil, nil
+	}
+
+	handler := corev1.Handler{}
+	switch probe.Type {
+	case entity.ContainerProbeExec:
+		handler.Exec = &corev1.ExecAction{
+			Command: probe.Command,
+		}
+	case entity.ContainerProbeHTTPGet:
+		path := probe.Path
+		if path == "" {
+			path = "/"
+		}
+		handler.HTTPGet = &corev1.HTTPGetAction{
+			Path: path,
+			Port: intstr.FromInt(int(probe.Port)),
+		}
+	case entity.ContainerProbeTCPSocket:
+		handler.TCPSocket = &corev1.TCPSocketAction{
+			Port: intstr.FromInt(int(probe.Port)),
+		}
+	default:
+		return nil, fmt.Errorf("the probe type %s isn't supported", probe.Type)
+	}
+
+	return &corev1.Probe{
+		Handler:             handler,
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		SuccessThreshold:    probe.SuccessThreshold,
+		FailureThreshold:    probe.FailureThreshold,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package kubeutils
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestCheckContainers(t *testing.T) {
+	containers := []entity.Container{
+		{
+			Name: "web",
+			Ports: []entity.ContainerPort{
+				{Name: "http", ContainerPort: 80},
+				{Name: "dns", ContainerPort: 53, Protocol: "UDP"},
+			},
+			LivenessProbe:  &entity.ContainerProbe{Type: entity.ContainerProbeHTTPGet, Port: 80},
+			ReadinessProbe: &entity.ContainerProbe{Type: entity.ContainerProbeExec, Command: []string{"true"}, SuccessThreshold: 3},
+		},
+		{
+			Name:  "sidecar",
+			Ports: []entity.ContainerPort{{ContainerPort: 53}},
+		},
+	}
+	assert.NoError(t, CheckContainers(containers))
+
+	testCases := []struct {
+		cases     string
+		container entity.Container
+	}{
+		{"DuplicatedPort", entity.Container{Name: "dup", Ports: []entity.ContainerPort{{ContainerPort: 80, Protocol: "TCP"}}}},
+		{"DuplicatedPortName", entity.Container{Name: "dup", Ports: []entity.ContainerPort{{Name: "http", ContainerPort: 8080}}}},
+		{"InvalidPortName", entity.Container{Name: "dup", Ports: []entity.ContainerPort{{Name: "HTTP_ALT", ContainerPort: 8080}}}},
+		{"NumericPortName", entity.Container{Name: "dup", Ports: []entity.ContainerPort{{Name: "8080", ContainerPort: 8080}}}},
+		{"ExecWithoutCommand", entity.Container{Name: "probe", LivenessProbe: &entity.ContainerProbe{Type: entity.ContainerProbeExec}}},
+		{"TCPSocketWithoutPort", entity.Container{Name: "probe", ReadinessProbe: &entity.ContainerProbe{Type: entity.ContainerProbeTCPSocket}}},
+		{"UnknownProbe", entity.Container{Name: "probe", ReadinessProbe: &entity.ContainerProbe{Type: "grpc", Port: 80}}},
+		{"LivenessSuccessThreshold", entity.Container{Name: "probe", LivenessProbe: &entity.ContainerProbe{Type: entity.ContainerProbeTCPSocket, Port: 80, SuccessThreshold: 2}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			assert.Error(t, CheckContainers(append([]entity.Container{containers[0]}, tc.container)))
+		})
+	}
+}
+
+func TestGenerateEnvVars(t *testing.T) {
+	shared := []corev1.EnvVar{
+		{Name: "DEBUG", Value: "0"},
+		{Name: "MY_IP", Value: "1.2.3.4"},
+	}
+	assert.Equal(t, shared, GenerateEnvVars(shared, entity.Container{Name: "busybox"}))
+
+	container := entity.Container{
+		Name:    "busybox",
+		EnvVars: map[string]string{"MODE": "fast", "DEBUG": "1", "LEVEL": "3"},
+	}
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "MY_IP", Value: "1.2.3.4"},
+		{Name: "DEBUG", Value: "1"},
+		{Name: "LEVEL", Value: "3"},
+		{Name: "MODE", Value: "fast"},
+	}, GenerateEnvVars(shared, container))
+}
+
+func TestGeneratePorts(t *testing.T) {
+	container := entity.Container{
+		Name: "web",
+		Ports: []entity.ContainerPort{
+			{Name: "http", ContainerPort: 80},
+			{ContainerPort: 53, Protocol: "UDP"},
+		},
+	}
+	assert.Equal(t, []corev1.ContainerPort{
+		{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+		{ContainerPort: 53, Protocol: corev1.ProtocolUDP},
+	}, GeneratePorts(container))
+}
+
+func TestGenerateProbe(t *testing.T) {
+	probe, err := GenerateProbe(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, probe)
+
+	probe, err = GenerateProbe(&entity.ContainerProbe{Type: entity.ContainerProbeHTTPGet, Port: 8080, InitialDelaySeconds: 5, PeriodSeconds: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "/", probe.HTTPGet.Path)
+	assert.Equal(t, intstr.FromInt(8080), probe.HTTPGet.Port)
+	assert.Equal(t, int32(5), probe.InitialDelaySeconds)
+	assert.Equal(t, int32(10), probe.PeriodSeconds)
+
+	probe, err = GenerateProbe(&entity.ContainerProbe{Type: entity.ContainerProbeTCPSocket, Port: 22})
+	assert.NoError(t, err)
+	assert.Equal(t, intstr.FromInt(22), probe.TCPSocket.Port)
+	assert.Nil(t, probe.HTTPGet)
+
+	probe, err = GenerateProbe(&entity.ContainerProbe{Type: entity.ContainerProbeExec, Command: []string{"cat", "/tmp/healthy"}, FailureThreshold: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cat", "/tmp/healthy"}, probe.Exec.Command)
+	assert.Equal(t, int32(3), probe.FailureThreshold)
+
+	_, err = GenerateProbe(&entity.ContainerProbe{Type: "grpc", Port: 50051})
+	assert.Error(t, err)
+}
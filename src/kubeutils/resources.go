@@ -0,0 +1,382 @@
+package kubeutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// HugepageVolumeName is the name of the volume for the hugepages of the containers
+const HugepageVolumeName = "hugepage"
+
+// HugepageMountPath is the path to mount the hugepages in the containers
+const HugepageMountPath = "/dev/hugepages"
+
+// HostnameLabel is the label of the node for its hostname
+const HostnameLabel = "kubernetes.io/hostname"
+
+// ResourceError is the error that the nodes don't have enough allocatable resources for the replicas of the pod
+type ResourceError struct {
+	Available int64
+	Replicas  int64
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("the allocatable resources of the nodes are only enough for %d of %d replicas", e.Available, e.Replicas)
+}
+
+func isHugepages(name string) bool {
+	return strings.HasPrefix(name, corev1.ResourceHugePagesPrefix)
+}
+
+func checkResourceName(name string) error {
+	switch corev1.ResourceName(name) {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return nil
+	}
+	if isHugepages(name) {
+		if _, err := resource.ParseQuantity(strings.TrimPrefix(name, corev1.ResourceHugePagesPrefix)); err != nil {
+			return fmt.Errorf("the page size of the resource %s is invalid", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("the resource %s isn't supported, it should be cpu, memory, ephemeral-storage or hugepages-<size>", name)
+}
+
+func generateResourceList(values map[string]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		if err := checkResourceName(name); err != nil {
+			return nil, err
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("the quantity %s of the resource %s is invalid", value, name)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+// GenerateResources will generate the resource requests and limits of the container
+// The hugepages can't be overcommitted, so their requests are the same as the limits.
+// The containers get the dedicated cpus from the static cpu manager of kubelet if their cpu and memory requests are the same as the limits and the cpu is an integer.
+func GenerateResources(container entity.Container) (corev1.ResourceRequirements, error) {
+	requirements := corev1.ResourceRequirements{}
+	if container.Resources == nil {
+		return requirements, nil
+	}
+
+	requests, err := generateResourceList(container.Resources.Requests)
+	if err != nil {
+		return requirements, err
+	}
+	limits, err := generateResourceList(container.Resources.Limits)
+	if err != nil {
+		return requirements, err
+	}
+
+	hugepages := false
+	for name, request := range requests {
+		limit, ok := limits[name]
+		if isHugepages(string(name)) {
+			if !ok || request.Cmp(limit) != 0 {
+				return requirements, fmt.Errorf("the requests and limits of the %s of the container %s should be the same", name, container.Name)
+			}
+			continue
+		}
+		if ok && request.Cmp(limit) > 0 {
+			return requirements, fmt.Errorf("the requests of the %s of the container %s are greater than the limits", name, container.Name)
+		}
+	}
+	//The requests are the same as the limits if they're not set, like the kubernetes does
+	for name, limit := range limits {
+		if isHugepages(string(name)) {
+			hugepages = true
+		}
+		if _, ok := requests[name]; !ok {
+			requests[name] = limit
+		}
+	}
+	if hugepages {
+		_, cpu := requests[corev1.ResourceCPU]
+		_, memory := requests[corev1.ResourceMemory]
+		if !cpu && !memory {
+			return requirements, fmt.Errorf("the container %s with the hugepages should have the cpu or memory resources", container.Name)
+		}
+	}
+
+	if len(requests) != 0 {
+		requirements.Requests = requests
+	}
+	if len(limits) != 0 {
+		requirements.Limits = limits
+	}
+	return requirements, nil
+}
+
+// CheckResources will check the resources of the containers of a pod
+// The hugepages are mounted by the volume of one page size, so all containers should use the same page size.
+func CheckResources(containers []entity.Container) error {
+	sizes := map[corev1.ResourceName]bool{}
+	for _, container := range containers {
+		requirements, err := GenerateResources(container)
+		if err != nil {
+			return err
+		}
+		for name := range requirements.Limits {
+			if isHugepages(string(name)) {
+				sizes[name] = true
+			}
+		}
+	}
+	if len(sizes) > 1 {
+		return fmt.Errorf("the containers of a pod can only use the hugepages of one page size")
+	}
+	return nil
+}
+
+// HasHugepages will return true if the container requests the hugepages
+func HasHugepages(container entity.Container) bool {
+	if container.Resources == nil {
+		return false
+	}
+	for name := range container.Resources.Limits {
+		if isHugepages(name) {
+			return true
+		}
+	}
+	for name := range container.Resources.Requests {
+		if isHugepages(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateHugepageVolume will return the volume of the hugepages, or nil if none of the containers requests them
+func GenerateHugepageVolume(containers []entity.Container) *corev1.Volume {
+	for _, container := range containers {
+		if HasHugepages(container) {
+			return &corev1.Volume{
+				Name: HugepageVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{
+						Medium: corev1.StorageMediumHugePages,
+					},
+				},
+			}
+		}
+	}
+	return nil
+}
+
+// The effective requests of the pod are the sum of the containers or the max of the init containers
+func podRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			if v, ok := requests[name]; ok {
+				v.Add(quantity)
+				requests[name] = v
+			} else {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	for _, container := range spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if v, ok := requests[name]; !ok || quantity.Cmp(v) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// The node matches the requirement of the node selector term by its labels
+func matchNodeSelectorRequirement(requirement corev1.NodeSelectorRequirement, nodeLabels map[string]string) bool {
+	value, ok := nodeLabels[requirement.Key]
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+		found := false
+		for _, v := range requirement.Values {
+			if ok && v == value {
+				found = true
+			}
+		}
+		return found == (requirement.Operator == corev1.NodeSelectorOpIn)
+	case corev1.NodeSelectorOpExists:
+		return ok
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !ok
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !ok || len(requirement.Values) != 1 {
+			return false
+		}
+		a, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		b, err := strconv.ParseInt(requirement.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		return (requirement.Operator == corev1.NodeSelectorOpGt && a > b) || (requirement.Operator == corev1.NodeSelectorOpLt && a < b)
+	}
+	return false
+}
+
+// The pods can only be scheduled to the nodes matching the node selector and any term of the required node affinity
+// The hostname label is the node name if it's not set, since the node affinity of vortex is generated by the node names.
+func matchNodeAffinity(spec *corev1.PodSpec, node *corev1.Node) bool {
+	nodeLabels := map[string]string{HostnameLabel: node.Name}
+	for k, v := range node.Labels {
+		nodeLabels[k] = v
+	}
+
+	for k, v := range spec.NodeSelector {
+		if value, ok := nodeLabels[k]; !ok || value != v {
+			return false
+		}
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil || spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		matched := true
+		for _, requirement := range term.MatchExpressions {
+			if !matchNodeSelectorRequirement(requirement, nodeLabels) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// The pods can't be scheduled to the cordoned nodes or the nodes with the NoSchedule taints which they don't tolerate
+func schedulable(spec *corev1.PodSpec, node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return matchNodeAffinity(spec, node)
+}
+
+// The free resources of the node are the allocatable ones minus the requests of the pods running on it
+func freeResources(node *corev1.Node, pods []corev1.Pod) corev1.ResourceList {
+	free := corev1.ResourceList{}
+	for name, quantity := range node.Status.Allocatable {
+		free[name] = quantity.DeepCopy()
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != node.Name || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for name, quantity := range podRequests(&pod.Spec) {
+			if v, ok := free[name]; ok {
+				v.Sub(quantity)
+				free[name] = v
+			}
+		}
+	}
+	return free
+}
+
+// The number of the pods with the requests which can be scheduled to the node
+func nodeCapacity(free corev1.ResourceList, requests corev1.ResourceList) int64 {
+	capacity := int64(-1)
+	for name, quantity := range requests {
+		if quantity.IsZero() {
+			continue
+		}
+		v, ok := free[name]
+		if !ok || v.Sign() <= 0 {
+			return 0
+		}
+		count := v.MilliValue() / quantity.MilliValue()
+		if capacity == -1 || count < capacity {
+			capacity = count
+		}
+	}
+	return capacity
+}
+
+// CheckNodeAllocatable will check the nodes have enough allocatable resources for the replicas of the pod
+// Only the nodes which the pod can be scheduled to are checked, and the pods without the requests are always fine.
+func CheckNodeAllocatable(sp *serviceprovider.Container, spec *corev1.PodSpec, replicas int32) error {
+	return checkNodeAllocatable(sp, spec, replicas, "", nil)
+}
+
+// CheckNodeAllocatableReplacing will check the nodes like CheckNodeAllocatable, but the pods selected by the labels
+// in the namespace aren't counted since they're replaced by the new ones, e.g. the pods of the updated deployment.
+func CheckNodeAllocatableReplacing(sp *serviceprovider.Container, spec *corev1.PodSpec, replicas int32, namespace string, replaced map[string]string) error {
+	return checkNodeAllocatable(sp, spec, replicas, namespace, replaced)
+}
+
+func checkNodeAllocatable(sp *serviceprovider.Container, spec *corev1.PodSpec, replicas int32, namespace string, replaced map[string]string) error {
+	requests := podRequests(spec)
+	if len(requests) == 0 {
+		return nil
+	}
+
+	nodeList, err := sp.KubeCtl.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	podList, err := sp.KubeCtl.Clientset.CoreV1().Pods("").List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if len(replaced) != 0 && pod.Namespace == namespace && labels.SelectorFromSet(replaced).Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+
+	total := int64(0)
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if !schedulable(spec, node) {
+			continue
+		}
+		capacity := nodeCapacity(freeResources(node, pods), requests)
+		if capacity == -1 {
+			return nil
+		}
+		total += capacity
+	}
+	if total < int64(replicas) {
+		return &ResourceError{total, int64(replicas)}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package kubeutils
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/moby/moby/pkg/namesgenerator"
+
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ResourcesTestSuite struct {
+	suite.Suite
+	sp *serviceprovider.Container
+}
+
+func (suite *ResourcesTestSuite) SetupSuite() {
+	cf := config.MustRead("../../config/testing.json")
+	suite.sp = serviceprovider.NewForTesting(cf)
+}
+
+func (suite *ResourcesTestSuite) TearDownSuite() {
+}
+
+func TestResourcesSuite(t *testing.T) {
+	suite.Run(t, new(ResourcesTestSuite))
+}
+
+func (suite *ResourcesTestSuite) TestGenerateResources() {
+	requirements, err := GenerateResources(entity.Container{Name: "busybox"})
+	suite.NoError(err)
+	suite.Nil(requirements.Requests)
+	suite.Nil(requirements.Limits)
+
+	container := entity.Container{
+		Name: "dpdk",
+		Resources: &entity.ContainerResources{
+			Requests: map[string]string{"cpu": "500m"},
+			Limits:   map[string]string{"cpu": "2", "memory": "1Gi", "hugepages-2Mi": "512Mi"},
+		},
+	}
+	requirements, err = GenerateResources(container)
+	suite.NoError(err)
+	suite.Equal(resource.MustParse("500m"), requirements.Requests[corev1.ResourceCPU])
+	suite.Equal(resource.MustParse("2"), requirements.Limits[corev1.ResourceCPU])
+	//The requests are the same as the limits if they're not set
+	suite.Equal(resource.MustParse("1Gi"), requirements.Requests[corev1.ResourceMemory])
+	suite.Equal(resource.MustParse("512Mi"), requirements.Requests["hugepages-2Mi"])
+	suite.True(HasHugepages(container))
+	suite.False(HasHugepages(entity.Container{Name: "busybox"}))
+
+	testCases := []struct {
+		cases     string
+		resources entity.ContainerResources
+	}{
+		{"UnknownResource", entity.ContainerResources{Limits: map[string]string{"gpu": "1"}}},
+		{"InvalidPageSize", entity.ContainerResources{Limits: map[string]string{"cpu": "1", "hugepages-huge": "1Gi"}}},
+		{"InvalidQuantity", entity.ContainerResources{Requests: map[string]string{"memory": "one"}}},
+		{"RequestsGreaterThanLimits", entity.ContainerResources{Requests: map[string]string{"cpu": "2"}, Limits: map[string]string{"cpu": "1"}}},
+		{"HugepagesWithoutLimits", entity.ContainerResources{Requests: map[string]string{"cpu": "1", "hugepages-2Mi": "512Mi"}}},
+		{"DifferentHugepages", entity.ContainerResources{Requests: map[string]string{"hugepages-2Mi": "256Mi"}, Limits: map[string]string{"cpu": "1", "hugepages-2Mi": "512Mi"}}},
+		{"HugepagesOnly", entity.ContainerResources{Limits: map[string]string{"hugepages-1Gi": "2Gi"}}},
+	}
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			_, err := GenerateResources(entity.Container{Name: "dpdk", Resources: &tc.resources})
+			suite.Error(err)
+		})
+	}
+}
+
+func (suite *ResourcesTestSuite) TestCheckResources() {
+	containers := []entity.Container{
+		{Name: "busybox"},
+		{Name: "dpdk-1", Resources: &entity.ContainerResources{Limits: map[string]string{"memory": "1Gi", "hugepages-2Mi": "512Mi"}}},
+		{Name: "dpdk-2", Resources: &entity.ContainerResources{Limits: map[string]string{"memory": "1Gi", "hugepages-2Mi": "256Mi"}}},
+	}
+	suite.NoError(CheckResources(containers))
+
+	volume := GenerateHugepageVolume(containers)
+	suite.NotNil(volume)
+	suite.Equal(HugepageVolumeName, volume.Name)
+	suite.Equal(corev1.StorageMediumHugePages, volume.EmptyDir.Medium)
+	suite.Nil(GenerateHugepageVolume(containers[:1]))
+
+	containers[2].Resources.Limits = map[string]string{"memory": "1Gi", "hugepages-1Gi": "1Gi"}
+	suite.Error(CheckResources(containers))
+}
+
+func (suite *ResourcesTestSuite) TestCheckNodeAllocatable() {
+	nodeName := namesgenerator.GetRandomName(0)
+	_, err := suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Create(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				"hugepages-1Gi":       resource.MustParse("4Gi"),
+			},
+		},
+	})
+	suite.NoError(err)
+	defer suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Delete(nodeName, &metav1.DeleteOptions{})
+
+	//The running pod uses 2 cpus of the node
+	podName := namesgenerator.GetRandomName(0)
+	_, err = suite.sp.KubeCtl.CreatePod(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: podName, Labels: map[string]string{"app": podName}},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{
+				{
+					Name: "busybox",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}, "default")
+	suite.NoError(err)
+	defer suite.sp.KubeCtl.DeletePod(podName, "default")
+
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name: "dpdk",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+						"hugepages-1Gi":       resource.MustParse("1Gi"),
+					},
+				},
+			},
+		},
+		Affinity: &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{
+									Key:      "kubernetes.io/hostname",
+									Values:   []string{nodeName},
+									Operator: corev1.NodeSelectorOpIn,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	suite.NoError(CheckNodeAllocatable(suite.sp, spec, 2))
+
+	err = CheckNodeAllocatable(suite.sp, spec, 3)
+	suite.Error(err)
+	resourceErr, ok := err.(*ResourceError)
+	suite.True(ok)
+	suite.Equal(int64(2), resourceErr.Available)
+
+	//The pod without the requests is always fine
+	suite.NoError(CheckNodeAllocatable(suite.sp, &corev1.PodSpec{Containers: []corev1.Container{{Name: "busybox"}}}, 3))
+
+	//The running pod is replaced by the new ones
+	suite.Error(CheckNodeAllocatable(suite.sp, spec, 4))
+	suite.NoError(CheckNodeAllocatableReplacing(suite.sp, spec, 4, "default", map[string]string{"app": podName}))
+	suite.Error(CheckNodeAllocatableReplacing(suite.sp, spec, 4, "other", map[string]string{"app": podName}))
+
+	//The node selector doesn't match the labels of the node
+	spec.NodeSelector = map[string]string{"dpdk": "enabled"}
+	suite.Error(CheckNodeAllocatable(suite.sp, spec, 1))
+	spec.NodeSelector = nil
+
+	//The node is cordoned or tainted
+	node, err := suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	suite.NoError(err)
+	node.Spec.Unschedulable = true
+	node, err = suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Update(node)
+	suite.NoError(err)
+	suite.Error(CheckNodeAllocatable(suite.sp, spec, 1))
+
+	node.Spec.Unschedulable = false
+	node.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "dpdk", Effect: corev1.TaintEffectNoSchedule}}
+	_, err = suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Update(node)
+	suite.NoError(err)
+	suite.Error(CheckNodeAllocatable(suite.sp, spec, 1))
+	spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "dpdk", Effect: corev1.TaintEffectNoSchedule}}
+	suite.NoError(CheckNodeAllocatable(suite.sp, spec, 1))
+
+	spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values = []string{namesgenerator.GetRandomName(0)}
+	suite.Error(CheckNodeAllocatable(suite.sp, spec, 1))
+}
+
+func (suite *ResourcesTestSuite) TestMatchNodeAffinity() {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"zone": "a", "cores": "16"}},
+	}
+	term := func(requirements ...corev1.NodeSelectorRequirement) *corev1.PodSpec {
+		return &corev1.PodSpec{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: requirements}},
+					},
+				},
+			},
+		}
+	}
+
+	suite.True(matchNodeAffinity(&corev1.PodSpec{}, node))
+	suite.True(matchNodeAffinity(&corev1.PodSpec{NodeSelector: map[string]string{"zone": "a"}}, node))
+	suite.False(matchNodeAffinity(&corev1.PodSpec{NodeSelector: map[string]string{"zone": "b"}}, node))
+	//The hostname is the node name if it's not labeled
+	suite.True(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: HostnameLabel, Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}}), node))
+	suite.False(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"a"}}), node))
+	suite.True(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpExists}), node))
+	suite.False(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpExists}), node))
+	suite.True(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpDoesNotExist}), node))
+	suite.True(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: "cores", Operator: corev1.NodeSelectorOpGt, Values: []string{"8"}}), node))
+	suite.False(matchNodeAffinity(term(corev1.NodeSelectorRequirement{Key: "cores", Operator: corev1.NodeSelectorOpLt, Values: []string{"8"}}), node))
+	//All the requirements of a term should be matched
+	suite.False(matchNodeAffinity(term(
+		corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+		corev1.NodeSelectorRequirement{Key: "gpu", Operator: corev1.NodeSelectorOpExists},
+	), node))
+}
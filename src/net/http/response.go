@@ -13,6 +13,8 @@ type ErrorPayload struct {
 	Error           bool     `json:"error" xml:"error"`
 	Message         string   `json:"message" xml:"message"`
 	PreviousMessage string   `json:"previousMessage,omitempty" xml:"previousMessage,omitempty"`
+	// Details is the structured information of the error
+	Details interface{} `json:"details,omitempty" xml:"-"`
 }
 
 // ActionResponse is the structure for Response action
@@ -56,7 +58,11 @@ func EncodeErrorPayload(req *http.Request, payload ErrorPayload) (out []byte, co
 
 // WriteStatusAndError will write the error status and error code to the http request and return the written byte count of the http request
 func WriteStatusAndError(req *http.Request, resp http.ResponseWriter, status int, errs ...error) (int, error) {
-	payload := NewErrorPayload(errs...)
+	return WritePayload(req, resp, status, NewErrorPayload(errs...))
+}
+
+// WritePayload will write the error status and the payload to the http request and return the written byte count of the http request
+func WritePayload(req *http.Request, resp http.ResponseWriter, status int, payload ErrorPayload) (int, error) {
 	out, contentType, encErr := EncodeErrorPayload(req, payload)
 	if encErr != nil {
 		resp.Write([]byte("failed to encode payload"))
@@ -103,6 +109,11 @@ func Conflict(req *http.Request, resp http.ResponseWriter, errs ...error) (int,
 	return WriteStatusAndError(req, resp, http.StatusConflict, errs...)
 }
 
+// TooManyRequests will set the status code http.StatusTooManyRequests to the HTTP response message
+func TooManyRequests(req *http.Request, resp http.ResponseWriter, errs ...error) (int, error) {
+	return WriteStatusAndError(req, resp, http.StatusTooManyRequests, errs...)
+}
+
 // MethodNotAllow will set the status code http.StatusMethodNotAllowed, to the HTTP response message
 func MethodNotAllow(req *http.Request, resp http.ResponseWriter, errs ...error) (int, error) {
 	return WriteStatusAndError(req, resp, http.StatusMethodNotAllowed, errs...)
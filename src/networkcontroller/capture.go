@@ -0,0 +1,60 @@
+package networkcontroller
+
+import (
+	"io"
+	"time"
+
+	pb "github.com/linkernetworks/network-controller/messages"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CapturePackets will capture the packets of the interface on the node and return the pcap stream
+// The Network Controller stops the capture after the duration or the max bytes, and the stream is
+// ended if it's not finished in the timeout after the duration. Close the stream to stop the capture earlier.
+func (nc *NetworkController) CapturePackets(ifaceName string, filter string, duration time.Duration, maxBytes int64) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), duration+nc.Timeout)
+
+	stream, err := nc.ClientCtl.CapturePackets(
+		ctx,
+		&pb.CapturePacketsRequest{
+			IfaceName: ifaceName,
+			Filter:    filter,
+			Duration:  int64(duration / time.Second),
+			MaxBytes:  maxBytes,
+		})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &captureReader{stream: stream, cancel: cancel}, nil
+}
+
+// captureReader reads the pcap data of the messages in the stream
+type captureReader struct {
+	stream pb.NetworkControl_CapturePacketsClient
+	cancel context.CancelFunc
+	buf    []byte
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		reply, err := r.stream.Recv()
+		//The capture is finished if the Network Controller doesn't end the stream in time
+		if status.Code(err) == codes.DeadlineExceeded {
+			return 0, io.EOF
+		} else if err != nil {
+			return 0, err
+		}
+		r.buf = reply.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *captureReader) Close() error {
+	r.cancel()
+	return nil
+}
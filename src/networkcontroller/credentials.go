@@ -0,0 +1,99 @@
+package networkcontroller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/linkernetworks/logger"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Config is the structure for the connections to the Network Controllers
+// The connections use the mutual TLS, the Insecure is only for the development.
+type Config struct {
+	// the CA certificate to verify the Network Controllers
+	CAFile string `json:"caFile"`
+	// the client certificate and key presented to the Network Controllers
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// the nodes are dialed by the IP address, so the certificate of the Network Controllers is verified by the server name
+	ServerName string `json:"serverName"`
+	// the shared token sent with each call, it's optional
+	Token string `json:"token"`
+	// disable the TLS, anyone on the node network can call the Network Controllers
+	Insecure bool `json:"insecure"`
+}
+
+// tokenCredentials sends the shared token in the metadata of each call
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + c.token,
+	}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+// DialOptions will return the dial options of the connections to the Network Controllers by the config
+// The connections are insecure as before if the config doesn't have the networkController block.
+func DialOptions(cf *Config) ([]grpc.DialOption, error) {
+	if cf == nil {
+		logger.Warnf("The networkController config is missing, set it to connect to the network controllers with the mutual TLS")
+		cf = &Config{Insecure: true}
+	}
+
+	opts := []grpc.DialOption{}
+	if cf.Insecure {
+		logger.Warnf("The connections to the network controllers are insecure, it's only for the development")
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		tlsConfig, err := loadTLSConfig(cf)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if cf.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{
+			token:  cf.Token,
+			secure: !cf.Insecure,
+		}))
+	}
+	return opts, nil
+}
+
+func loadTLSConfig(cf *Config) (*tls.Config, error) {
+	if cf.CAFile == "" || cf.CertFile == "" || cf.KeyFile == "" {
+		return nil, fmt.Errorf("the caFile, certFile and keyFile of the networkController config are required unless it's insecure")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cf.CertFile, cf.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load the client certificate fail: %v", err)
+	}
+	ca, err := ioutil.ReadFile(cf.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("load the CA certificate fail: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("the CA certificate %s is invalid", cf.CAFile)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   cf.ServerName,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package networkcontroller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestDialOptions(t *testing.T) {
+	//The config without the networkController block is insecure
+	opts, err := DialOptions(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(opts))
+
+	//The certificates are required unless it's insecure
+	_, err = DialOptions(&Config{})
+	assert.Error(t, err)
+	_, err = DialOptions(&Config{
+		CAFile:   "/nonexistent/ca.crt",
+		CertFile: "/nonexistent/tls.crt",
+		KeyFile:  "/nonexistent/tls.key",
+	})
+	assert.Error(t, err)
+
+	opts, err = DialOptions(&Config{Insecure: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(opts))
+
+	opts, err = DialOptions(&Config{Insecure: true, Token: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(opts))
+}
+
+func TestTokenCredentials(t *testing.T) {
+	creds := tokenCredentials{token: "secret", secure: true}
+	md, err := creds.GetRequestMetadata(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Bearer secret"}, md)
+	assert.True(t, creds.RequireTransportSecurity())
+}
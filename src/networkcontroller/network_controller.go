@@ -1,6 +1,7 @@
 package networkcontroller
 
 import (
+	"fmt"
 	"time"
 
 	pb "github.com/linkernetworks/network-controller/messages"
@@ -12,13 +13,22 @@ import (
 // DEFAULT_CONTROLLER_PORT set the default port as 50051
 const DEFAULT_CONTROLLER_PORT = "50051"
 
+// ClientImage is the image of the network client in the init containers of the pods
+// The client flags like the multiple --ip, the ipv6 routes and the qos need this version, it should be the same as the vendored messages.
+const ClientImage = "sdnvortex/network-controller:v0.5.0"
+
+// DefaultTimeout is the timeout of each call to the Network Controller
+const DefaultTimeout = 10 * time.Second
+
 // NetworkController is the structure for Network Controller
 type NetworkController struct {
 	ClientCtl pb.NetworkControlClient
-	Context   context.Context
+	Timeout   time.Duration
+	conn      *grpc.ClientConn
 }
 
-// New will Set up a connection to the Network Controller server
+// New will Set up an insecure connection to the Network Controller server, it's for the development and testing
+// The connection should be closed by the caller, use the Pool to share the secure connections instead.
 func New(serverAddress string) (*NetworkController, error) {
 	// Set up a connection to the server.
 	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
@@ -26,28 +36,67 @@ func New(serverAddress string) (*NetworkController, error) {
 		return nil, err
 	}
 
-	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
-
 	return &NetworkController{
 		ClientCtl: pb.NewNetworkControlClient(conn),
-		Context:   ctx,
+		Timeout:   DefaultTimeout,
+		conn:      conn,
 	}, nil
 }
 
+// Close will close the connection created by New
+func (nc *NetworkController) Close() error {
+	if nc.conn == nil {
+		return nil
+	}
+	return nc.conn.Close()
+}
+
+// Each call has its own timeout, so the long-lived connection can be shared by the requests
+func (nc *NetworkController) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), nc.Timeout)
+}
+
 // CreateOVSNetwork will Create OVS Network by Network Controller
 func (nc *NetworkController) CreateOVSNetwork(datapathType string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
-	if _, err := nc.ClientCtl.CreateBridge(
-		nc.Context,
+	if err := nc.CreateOVSBridge(datapathType, bridgeName); err != nil {
+		return err
+	}
+	return nc.AddOVSPorts(bridgeName, phyIfaces, vlanTags)
+}
+
+// CreateOVSDPDKNetwork will Create OVS+DPDK Network by Network Controller
+func (nc *NetworkController) CreateOVSDPDKNetwork(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	if err := nc.CreateOVSBridge("netdev", bridgeName); err != nil {
+		return err
+	}
+	return nc.AddOVSDPDKPorts(bridgeName, phyIfaces, vlanTags)
+}
+
+// CreateOVSBridge will create the OVS bridge without any port
+func (nc *NetworkController) CreateOVSBridge(datapathType string, bridgeName string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.CreateBridge(
+		ctx,
 		&pb.CreateBridgeRequest{
 			BridgeName:   bridgeName,
 			DatapathType: datapathType,
-		}); err != nil {
+		})
+	if err != nil {
 		return err
 	}
+	return nil
+}
+
+// AddOVSPorts will add the physical interfaces to the OVS bridge and trunk the vlan tags
+func (nc *NetworkController) AddOVSPorts(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
 
 	for _, phyIface := range phyIfaces {
 		_, err := nc.ClientCtl.AddPort(
-			nc.Context,
+			ctx,
 			&pb.AddPortRequest{
 				BridgeName: bridgeName,
 				IfaceName:  phyIface.Name,
@@ -55,39 +104,21 @@ func (nc *NetworkController) CreateOVSNetwork(datapathType string, bridgeName st
 		if err != nil {
 			return err
 		}
-
-		if len(vlanTags) > 0 {
-			_, err := nc.ClientCtl.SetPort(
-				nc.Context,
-				&pb.SetPortRequest{
-					IfaceName: phyIface.Name,
-					Options: &pb.PortOptions{
-						VLANMode: "trunk",
-						Trunk:    vlanTags,
-					},
-				})
-			if err != nil {
-				return err
-			}
-		}
+	}
+	if len(vlanTags) > 0 {
+		return nc.SetOVSPortsTrunk(phyIfaces, vlanTags)
 	}
 	return nil
 }
 
-// CreateOVSDPDKNetwork will Create OVS+DPDK Network by Network Controller
-func (nc *NetworkController) CreateOVSDPDKNetwork(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
-	if _, err := nc.ClientCtl.CreateBridge(
-		nc.Context,
-		&pb.CreateBridgeRequest{
-			BridgeName:   bridgeName,
-			DatapathType: "netdev",
-		}); err != nil {
-		return err
-	}
+// AddOVSDPDKPorts will add the DPDK physical interfaces to the OVS bridge and trunk the vlan tags
+func (nc *NetworkController) AddOVSDPDKPorts(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
 
 	for _, phyIface := range phyIfaces {
 		_, err := nc.ClientCtl.AddDPDKPort(
-			nc.Context,
+			ctx,
 			&pb.AddPortRequest{
 				BridgeName:  bridgeName,
 				IfaceName:   phyIface.Name,
@@ -96,29 +127,78 @@ func (nc *NetworkController) CreateOVSDPDKNetwork(bridgeName string, phyIfaces [
 		if err != nil {
 			return err
 		}
+	}
+	if len(vlanTags) > 0 {
+		return nc.SetOVSPortsTrunk(phyIfaces, vlanTags)
+	}
+	return nil
+}
 
-		if len(vlanTags) > 0 {
-			_, err := nc.ClientCtl.SetPort(
-				nc.Context,
-				&pb.SetPortRequest{
-					IfaceName: phyIface.Name,
-					Options: &pb.PortOptions{
-						VLANMode: "trunk",
-						Trunk:    vlanTags,
-					},
-				})
-			if err != nil {
-				return err
-			}
+// SetOVSPortsTrunk will set the vlan tags trunked by the ports, all vlans are trunked if there's no vlan tag
+func (nc *NetworkController) SetOVSPortsTrunk(phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	for _, phyIface := range phyIfaces {
+		_, err := nc.ClientCtl.SetPort(
+			ctx,
+			&pb.SetPortRequest{
+				IfaceName: phyIface.Name,
+				Options: &pb.PortOptions{
+					VLANMode: "trunk",
+					Trunk:    vlanTags,
+				},
+			})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// GetOVSPortTrunk will get the vlan tags trunked by the port, it's empty if all vlans are trunked
+func (nc *NetworkController) GetOVSPortTrunk(ifaceName string) ([]int32, error) {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	data, err := nc.ClientCtl.GetPort(
+		ctx,
+		&pb.GetPortRequest{
+			IfaceName: ifaceName,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if data.Options == nil {
+		return []int32{}, nil
+	}
+	return data.Options.Trunk, nil
+}
+
+// DeleteOVSPort will delete the port from the OVS bridge
+func (nc *NetworkController) DeleteOVSPort(bridgeName string, ifaceName string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.DeletePort(
+		ctx,
+		&pb.DeletePortRequest{
+			BridgeName: bridgeName,
+			IfaceName:  ifaceName,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // DeleteOVSNetwork will delete OVS network controller
 func (nc *NetworkController) DeleteOVSNetwork(bridgeName string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
 	_, err := nc.ClientCtl.DeleteBridge(
-		nc.Context,
+		ctx,
 		&pb.DeleteBridgeRequest{
 			BridgeName: bridgeName,
 		})
@@ -130,8 +210,11 @@ func (nc *NetworkController) DeleteOVSNetwork(bridgeName string) error {
 
 // DumpOVSPorts will dump ports information of the target ovs
 func (nc *NetworkController) DumpOVSPorts(bridgeName string) ([]*pb.PortInfo, error) {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
 	data, err := nc.ClientCtl.DumpPorts(
-		nc.Context,
+		ctx,
 		&pb.DumpPortsRequest{
 			BridgeName: bridgeName,
 		})
@@ -141,3 +224,286 @@ func (nc *NetworkController) DumpOVSPorts(bridgeName string) ([]*pb.PortInfo, er
 
 	return data.Ports, nil
 }
+
+// CreateLinuxBridgeNetwork will Create Linux Bridge Network by Network Controller
+func (nc *NetworkController) CreateLinuxBridgeNetwork(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	if err := nc.CreateLinuxBridge(bridgeName); err != nil {
+		return err
+	}
+	return nc.AddLinuxBridgePorts(bridgeName, phyIfaces, vlanTags)
+}
+
+// CreateLinuxBridge will create the Linux Bridge without any port
+func (nc *NetworkController) CreateLinuxBridge(bridgeName string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.CreateLinuxBridge(
+		ctx,
+		&pb.CreateLinuxBridgeRequest{
+			BridgeName: bridgeName,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddLinuxBridgePorts will attach the physical interfaces to the Linux Bridge
+// The physical interface is attached to the bridge directly if there's no vlan tag,
+// otherwise the network controller creates the vlan sub-interface and attaches it instead.
+func (nc *NetworkController) AddLinuxBridgePorts(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	for _, phyIface := range phyIfaces {
+		if len(vlanTags) == 0 {
+			_, err := nc.ClientCtl.AddLinuxBridgePort(
+				ctx,
+				&pb.AddPortRequest{
+					BridgeName: bridgeName,
+					IfaceName:  phyIface.Name,
+				})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, vlanTag := range vlanTags {
+			_, err := nc.ClientCtl.AddVLANInterface(
+				ctx,
+				&pb.AddVLANInterfaceRequest{
+					BridgeName: bridgeName,
+					IfaceName:  phyIface.Name,
+					VlanTag:    vlanTag,
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteLinuxBridgePorts will detach the physical interfaces or delete their vlan sub-interfaces
+func (nc *NetworkController) DeleteLinuxBridgePorts(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	for _, phyIface := range phyIfaces {
+		if len(vlanTags) == 0 {
+			_, err := nc.ClientCtl.DeleteLinuxBridgePort(
+				ctx,
+				&pb.DeletePortRequest{
+					BridgeName: bridgeName,
+					IfaceName:  phyIface.Name,
+				})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, vlanTag := range vlanTags {
+			_, err := nc.ClientCtl.DeleteVLANInterface(
+				ctx,
+				&pb.DeleteVLANInterfaceRequest{
+					IfaceName: phyIface.Name,
+					VlanTag:   vlanTag,
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteLinuxBridgeNetwork will delete the Linux Bridge and its vlan sub-interfaces
+// The physical interfaces attached directly are released with the bridge.
+// The bridge is still deleted if the vlan sub-interfaces fail, and both errors are returned.
+func (nc *NetworkController) DeleteLinuxBridgeNetwork(bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	var portErr error
+	if len(vlanTags) > 0 {
+		portErr = nc.DeleteLinuxBridgePorts(bridgeName, phyIfaces, vlanTags)
+	}
+
+	//The ports may use up the timeout, so the context of the bridge is created after them
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.DeleteLinuxBridge(
+		ctx,
+		&pb.DeleteBridgeRequest{
+			BridgeName: bridgeName,
+		})
+	if portErr != nil && err != nil {
+		return fmt.Errorf("failed to delete the vlan interfaces: %v, failed to delete the bridge: %v", portErr, err)
+	}
+	if portErr != nil {
+		return portErr
+	}
+	return err
+}
+
+// CreateSRIOVNetwork will carve the virtual functions out of the physical interfaces by Network Controller
+func (nc *NetworkController) CreateSRIOVNetwork(phyIfaces []entity.PhyInterface) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	for _, phyIface := range phyIfaces {
+		_, err := nc.ClientCtl.CreateVFs(
+			ctx,
+			&pb.CreateVFsRequest{
+				IfaceName: phyIface.Name,
+				PciID:     phyIface.PCIID,
+				NumVFs:    phyIface.NumVFs,
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteSRIOVNetwork will release all virtual functions of the physical interfaces
+func (nc *NetworkController) DeleteSRIOVNetwork(phyIfaces []entity.PhyInterface) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	for _, phyIface := range phyIfaces {
+		_, err := nc.ClientCtl.DeleteVFs(
+			ctx,
+			&pb.DeleteVFsRequest{
+				IfaceName: phyIface.Name,
+				PciID:     phyIface.PCIID,
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddOVSTunnelPort will add the vxlan/gre tunnel port to the remote node on the OVS bridge
+func (nc *NetworkController) AddOVSTunnelPort(bridgeName string, ifaceName string, tunnelType string, remoteIP string, key int32) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.AddTunnelPort(
+		ctx,
+		&pb.AddTunnelPortRequest{
+			BridgeName: bridgeName,
+			IfaceName:  ifaceName,
+			TunnelType: tunnelType,
+			RemoteIP:   remoteIP,
+			Key:        key,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// EnableOVSRSTP will enable the RSTP of the OVS bridge, the full mesh tunnels between bridges have loops without it
+func (nc *NetworkController) EnableOVSRSTP(bridgeName string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.SetBridge(
+		ctx,
+		&pb.SetBridgeRequest{
+			BridgeName: bridgeName,
+			Options: &pb.BridgeOptions{
+				RSTPEnable: true,
+			},
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddOVSFlows will add the OpenFlow flows to the OVS bridge, the flow with the same match and priority is replaced
+func (nc *NetworkController) AddOVSFlows(bridgeName string, flows []string) error {
+	for _, flow := range flows {
+		if err := nc.addOVSFlow(bridgeName, flow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nc *NetworkController) addOVSFlow(bridgeName string, flow string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.AddFlow(
+		ctx,
+		&pb.AddFlowRequest{
+			BridgeName: bridgeName,
+			FlowString: flow,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteOVSFlows will delete the OpenFlow flows matched by the match string, e.g. cookie=0x1/-1
+func (nc *NetworkController) DeleteOVSFlows(bridgeName string, match string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.DeleteFlow(
+		ctx,
+		&pb.DeleteFlowRequest{
+			BridgeName: bridgeName,
+			FlowString: match,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateOVSMirror will create the mirror on the OVS bridge, the traffic from and to the ports and the traffic of the vlans
+// are copied to the output port. All traffic of the vlans on the bridge is selected if there's no ports.
+func (nc *NetworkController) CreateOVSMirror(bridgeName string, mirrorName string, ports []string, vlanTags []int32, outputPort string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.CreateMirror(
+		ctx,
+		&pb.CreateMirrorRequest{
+			BridgeName:    bridgeName,
+			MirrorName:    mirrorName,
+			SelectSrcPort: ports,
+			SelectDstPort: ports,
+			SelectVlan:    vlanTags,
+			SelectAll:     len(ports) == 0,
+			OutputPort:    outputPort,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteOVSMirror will delete the mirror from the OVS bridge
+func (nc *NetworkController) DeleteOVSMirror(bridgeName string, mirrorName string) error {
+	ctx, cancel := nc.newContext()
+	defer cancel()
+
+	_, err := nc.ClientCtl.DeleteMirror(
+		ctx,
+		&pb.DeleteMirrorRequest{
+			BridgeName: bridgeName,
+			MirrorName: mirrorName,
+		})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package networkcontroller
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/linkernetworks/network-controller/messages"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// MaxBackoffDelay is the max delay between the reconnections to the Network Controller
+const MaxBackoffDelay = 30 * time.Second
+
+// Pool keeps one long-lived connection to the Network Controller of each node, keyed by the node address.
+// The state of the connection is checked before it's used, it reconnects with backoff if the node is unreachable
+// and it's re-created if it has been shut down.
+type Pool struct {
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	timeout time.Duration
+	opts    []grpc.DialOption
+	closed  bool
+}
+
+// NewPool will create the connection pool, the timeout is used by each call to the Network Controller
+// The opts should have the transport credentials, see DialOptions.
+func NewPool(timeout time.Duration, opts ...grpc.DialOption) *Pool {
+	return &Pool{
+		conns:   map[string]*grpc.ClientConn{},
+		timeout: timeout,
+		opts:    opts,
+	}
+}
+
+// Get will return the Network Controller of the address with the shared connection
+func (p *Pool) Get(serverAddress string) (*NetworkController, error) {
+	conn, err := p.getConn(serverAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &NetworkController{
+		ClientCtl: pb.NewNetworkControlClient(conn),
+		Timeout:   p.timeout,
+	}, nil
+}
+
+func (p *Pool) getConn(serverAddress string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, grpc.ErrClientConnClosing
+	}
+	if conn, ok := p.conns[serverAddress]; ok {
+		//The calls fail fast in the transient failure until grpc reconnects, so only the shut down connection is re-created
+		if conn.GetState() != connectivity.Shutdown {
+			return conn, nil
+		}
+		delete(p.conns, serverAddress)
+	}
+
+	//The dial doesn't block, grpc connects in the background and reconnects with backoff if it fails
+	opts := append([]grpc.DialOption{grpc.WithBackoffMaxDelay(MaxBackoffDelay)}, p.opts...)
+	conn, err := grpc.Dial(serverAddress, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[serverAddress] = conn
+	return conn, nil
+}
+
+// Close will close all connections, the pool can't be used after closing
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var lastErr error
+	for addr, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			lastErr = err
+		}
+		delete(p.conns, addr)
+	}
+	p.closed = true
+	return lastErr
+}
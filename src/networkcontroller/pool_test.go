@@ -0,0 +1,41 @@
+package networkcontroller
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestPool(t *testing.T) {
+	pool := NewPool(time.Second, grpc.WithInsecure())
+	addr := net.JoinHostPort("127.0.0.1", DEFAULT_CONTROLLER_PORT)
+
+	nc, err := pool.Get(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, nc.Timeout)
+	conn := pool.conns[addr]
+
+	//The connection is shared by the same address
+	_, err = pool.Get(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pool.conns))
+	assert.True(t, conn == pool.conns[addr])
+
+	_, err = pool.Get(net.JoinHostPort("127.0.0.2", DEFAULT_CONTROLLER_PORT))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(pool.conns))
+
+	//The shut down connection is re-created
+	conn.Close()
+	_, err = pool.Get(addr)
+	assert.NoError(t, err)
+	assert.False(t, conn == pool.conns[addr])
+
+	assert.NoError(t, pool.Close())
+	assert.Equal(t, 0, len(pool.conns))
+	_, err = pool.Get(addr)
+	assert.Error(t, err)
+}
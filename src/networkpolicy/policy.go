@@ -0,0 +1,157 @@
+package networkpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/utils"
+)
+
+// The flows of the policies are above the NORMAL flow of the bridge, the ARP and the traffic not matched are switched as usual.
+// The flow of the policy with the higher priority is above, and the flows of a rule are above the rules after it.
+const (
+	BasePriority   = 1000
+	MaxPolicyRules = 100
+)
+
+// Endpoint is the address of the pod on the network and the labels of the pod
+type Endpoint struct {
+	IPAddress string
+	Labels    map[string]string
+}
+
+// Validate will check the fields of the policy rules which depend on each other
+func Validate(policy *entity.NetworkPolicy) error {
+	for i, rule := range policy.Rules {
+		if len(rule.PodSelector) != 0 && rule.CIDR != "" {
+			return fmt.Errorf("the rule %d can't have both the podSelector and the cidr", i)
+		}
+		if rule.Port != 0 && rule.Protocol != entity.PolicyProtocolTCP && rule.Protocol != entity.PolicyProtocolUDP {
+			return fmt.Errorf("the port of the rule %d is only for the tcp and udp protocols", i)
+		}
+	}
+	return nil
+}
+
+// Cookie is the OpenFlow cookie of the flows of the network, the flows are deleted by the cookie when they're re-computed
+// The lowest bit is reserved for the generation of the flows.
+func Cookie(networkName string) uint64 {
+	cookie, _ := strconv.ParseUint(utils.SHA256String("policy" + networkName)[0:16], 16, 64)
+	return cookie &^ 1
+}
+
+func priority(policy entity.NetworkPolicy, ruleIndex int) int {
+	return BasePriority + policy.Priority*MaxPolicyRules + (MaxPolicyRules - ruleIndex)
+}
+
+func selectAddresses(selector map[string]string, endpoints []Endpoint) []string {
+	addresses := []string{}
+	for _, endpoint := range endpoints {
+		if matchLabels(selector, endpoint.Labels) {
+			addresses = append(addresses, endpoint.IPAddress)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func matchLabels(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateFlows will translate the policies of the network to the OpenFlow flows of ovs-ofctl
+// The flows are stateless, the reply traffic should be allowed by the rules as well.
+func GenerateFlows(policies []entity.NetworkPolicy, endpoints []Endpoint, cookie uint64) []string {
+	flows := []string{}
+	for _, policy := range policies {
+		//The policy doesn't apply to any pod if its selector doesn't match
+		targets := []string{""}
+		if len(policy.PodSelector) != 0 {
+			targets = selectAddresses(policy.PodSelector, endpoints)
+		}
+
+		for i, rule := range policy.Rules {
+			peers := []string{""}
+			if rule.CIDR != "" {
+				peers = []string{rule.CIDR}
+			} else if len(rule.PodSelector) != 0 {
+				peers = selectAddresses(rule.PodSelector, endpoints)
+			}
+
+			for _, target := range targets {
+				for _, peer := range peers {
+					src, dst := peer, target
+					if rule.Direction == entity.PolicyEgress {
+						src, dst = target, peer
+					}
+					for _, ipv6 := range families(src, dst) {
+						flows = append(flows, generateFlow(cookie, priority(policy, i), rule, src, dst, ipv6))
+					}
+				}
+			}
+		}
+	}
+	return flows
+}
+
+// families will return the ip families of the flows between the addresses, the IPv4 is false and the IPv6 is true
+// The flows of both families are generated if neither address is set, and none if they're in the different families.
+func families(src, dst string) []bool {
+	switch {
+	case src == "" && dst == "":
+		return []bool{false, true}
+	case src == "":
+		return []bool{isIPv6(dst)}
+	case dst == "" || isIPv6(src) == isIPv6(dst):
+		return []bool{isIPv6(src)}
+	}
+	return nil
+}
+
+// The address is the ip address of the lease or the cidr of the rule
+func isIPv6(address string) bool {
+	return strings.Contains(address, ":")
+}
+
+func generateFlow(cookie uint64, priority int, rule entity.NetworkPolicyRule, src, dst string, ipv6 bool) string {
+	fields := []string{
+		fmt.Sprintf("cookie=0x%x", cookie),
+		fmt.Sprintf("priority=%d", priority),
+	}
+	protocol, srcField, dstField := rule.Protocol, "nw_src=", "nw_dst="
+	if protocol == "" {
+		protocol = "ip"
+	}
+	if ipv6 {
+		srcField, dstField = "ipv6_src=", "ipv6_dst="
+		if protocol == "ip" {
+			protocol = "ipv6"
+		} else {
+			protocol += "6"
+		}
+	}
+	fields = append(fields, protocol)
+	if src != "" {
+		fields = append(fields, srcField+src)
+	}
+	if dst != "" {
+		fields = append(fields, dstField+dst)
+	}
+	if rule.Port != 0 {
+		fields = append(fields, fmt.Sprintf("tp_dst=%d", rule.Port))
+	}
+
+	action := "actions=NORMAL"
+	if rule.Action == entity.PolicyActionDeny {
+		action = "actions=drop"
+	}
+	return strings.Join(append(fields, action), ",")
+}
@@ -0,0 +1,152 @@
+package networkpolicy
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&entity.NetworkPolicy{
+		Rules: []entity.NetworkPolicyRule{{Protocol: entity.PolicyProtocolTCP, Port: 80, CIDR: "10.0.0.0/24"}},
+	}))
+	assert.Error(t, Validate(&entity.NetworkPolicy{
+		Rules: []entity.NetworkPolicyRule{{PodSelector: map[string]string{"app": "web"}, CIDR: "10.0.0.0/24"}},
+	}))
+	assert.Error(t, Validate(&entity.NetworkPolicy{
+		Rules: []entity.NetworkPolicyRule{{Port: 80}},
+	}))
+}
+
+func TestFamilies(t *testing.T) {
+	assert.Equal(t, []bool{false, true}, families("", ""))
+	assert.Equal(t, []bool{false}, families("10.0.0.2", ""))
+	assert.Equal(t, []bool{true}, families("", "fd00::/64"))
+	assert.Equal(t, []bool{true}, families("fd00::2", "fd00::/64"))
+	assert.Nil(t, families("10.0.0.2", "fd00::/64"))
+}
+
+func TestCookie(t *testing.T) {
+	assert.Equal(t, Cookie("my-net"), Cookie("my-net"))
+	assert.NotEqual(t, Cookie("my-net"), Cookie("other-net"))
+	assert.Equal(t, uint64(0), Cookie("my-net")&1)
+}
+
+func TestGenerateFlows(t *testing.T) {
+	endpoints := []Endpoint{
+		{IPAddress: "10.0.0.2", Labels: map[string]string{"app": "db"}},
+		{IPAddress: "10.0.0.3", Labels: map[string]string{"app": "web"}},
+		{IPAddress: "10.0.0.4", Labels: map[string]string{"app": "web", "tier": "front"}},
+	}
+	policies := []entity.NetworkPolicy{
+		{
+			Name:        "db",
+			PodSelector: map[string]string{"app": "db"},
+			Priority:    1,
+			Rules: []entity.NetworkPolicyRule{
+				{Action: entity.PolicyActionAllow, Direction: entity.PolicyIngress, PodSelector: map[string]string{"app": "web"}, Protocol: entity.PolicyProtocolTCP, Port: 5432},
+				{Action: entity.PolicyActionDeny, Direction: entity.PolicyIngress},
+			},
+		},
+		{
+			Name: "egress",
+			Rules: []entity.NetworkPolicyRule{
+				{Action: entity.PolicyActionDeny, Direction: entity.PolicyEgress, CIDR: "192.168.0.0/16", Protocol: entity.PolicyProtocolICMP},
+			},
+		},
+		{
+			//The selector doesn't match any pod
+			Name:        "none",
+			PodSelector: map[string]string{"app": "none"},
+			Rules: []entity.NetworkPolicyRule{
+				{Action: entity.PolicyActionDeny, Direction: entity.PolicyIngress},
+			},
+		},
+	}
+
+	flows := GenerateFlows(policies, endpoints, 0x10)
+	assert.Equal(t, []string{
+		"cookie=0x10,priority=1200,tcp,nw_src=10.0.0.3,nw_dst=10.0.0.2,tp_dst=5432,actions=NORMAL",
+		"cookie=0x10,priority=1200,tcp,nw_src=10.0.0.4,nw_dst=10.0.0.2,tp_dst=5432,actions=NORMAL",
+		"cookie=0x10,priority=1199,ip,nw_dst=10.0.0.2,actions=drop",
+		"cookie=0x10,priority=1100,icmp,nw_dst=192.168.0.0/16,actions=drop",
+	}, flows)
+}
+
+func TestGenerateIPv6Flows(t *testing.T) {
+	endpoints := []Endpoint{
+		{IPAddress: "10.0.0.2", Labels: map[string]string{"app": "db"}},
+		{IPAddress: "fd00::2", Labels: map[string]string{"app": "db"}},
+	}
+	policies := []entity.NetworkPolicy{
+		{
+			Name:        "db",
+			PodSelector: map[string]string{"app": "db"},
+			Priority:    1,
+			Rules: []entity.NetworkPolicyRule{
+				//The cidr only matches the address of the same family
+				{Action: entity.PolicyActionAllow, Direction: entity.PolicyIngress, CIDR: "fd00::/64", Protocol: entity.PolicyProtocolTCP, Port: 5432},
+			},
+		},
+		{
+			Name: "deny-all",
+			Rules: []entity.NetworkPolicyRule{
+				{Action: entity.PolicyActionDeny, Direction: entity.PolicyIngress, Protocol: entity.PolicyProtocolICMP},
+			},
+		},
+	}
+
+	flows := GenerateFlows(policies, endpoints, 0x10)
+	assert.Equal(t, []string{
+		"cookie=0x10,priority=1200,tcp6,ipv6_src=fd00::/64,ipv6_dst=fd00::2,tp_dst=5432,actions=NORMAL",
+		"cookie=0x10,priority=1100,icmp,actions=drop",
+		"cookie=0x10,priority=1100,icmp6,actions=drop",
+	}, flows)
+}
+
+func TestGenerationFlows(t *testing.T) {
+	flows := []string{"cookie=0x10,priority=1100,ip,actions=drop"}
+	assert.Equal(t, flows, generationFlows(flows, 0x10, 0))
+	assert.Equal(t, []string{"cookie=0x11,priority=1100,ip,actions=drop"}, generationFlows(flows, 0x10, 1))
+}
+
+func TestEndpoints(t *testing.T) {
+	podLabels := map[string]map[string]string{
+		"default/db":                  {"app": "db"},
+		"default/web-5b6f5bd8c-2xqlm": {"vortex": "web", "replica": "1"},
+		"default/cache":               {"app": "cache"},
+	}
+	deployLabels := map[string]map[string]string{
+		"default/web":   {"vortex": "web"},
+		"default/proxy": {"vortex": "proxy"},
+	}
+	leases := []entity.IPLease{
+		{IPAddress: "10.0.0.2", Namespace: "default", PodName: "db"},
+		//The replica is found by its pod name
+		{IPAddress: "10.0.0.3", Namespace: "default", PodName: "web-5b6f5bd8c-2xqlm", DeploymentName: "web"},
+		//The static ip address leased by the deployment
+		{IPAddress: "10.0.0.4", Namespace: "default", PodName: "web", DeploymentName: "web"},
+		//The pod has gone away
+		{IPAddress: "10.0.0.5", Namespace: "default", PodName: "gone"},
+	}
+	podRecords := []entity.Pod{
+		//The leased address isn't duplicated
+		{Name: "db", Namespace: "default", Networks: []entity.PodNetwork{{Name: "net1", IPAddress: "10.0.0.2", Addresses: []string{"fd00::2/64"}}}},
+		//The network without the subnets
+		{Name: "cache", Namespace: "default", Networks: []entity.PodNetwork{{Name: "net2", IPAddress: "10.1.0.2"}, {Name: "net1", IPAddress: "10.0.0.6"}}},
+		{Name: "gone", Namespace: "default", Networks: []entity.PodNetwork{{Name: "net1", IPAddress: "10.0.0.7"}}},
+	}
+	deployRecords := []entity.Deployment{
+		{Name: "proxy", Namespace: "default", Networks: []entity.DeploymentNetwork{{Name: "net1", Addresses: []string{"fd00::8/64"}}}},
+	}
+
+	assert.Equal(t, []Endpoint{
+		{IPAddress: "10.0.0.2", Labels: map[string]string{"app": "db"}},
+		{IPAddress: "10.0.0.3", Labels: map[string]string{"vortex": "web", "replica": "1"}},
+		{IPAddress: "10.0.0.4", Labels: map[string]string{"vortex": "web"}},
+		{IPAddress: "fd00::2", Labels: map[string]string{"app": "db"}},
+		{IPAddress: "10.0.0.6", Labels: map[string]string{"app": "cache"}},
+		{IPAddress: "fd00::8", Labels: map[string]string{"vortex": "proxy"}},
+	}, endpoints("net1", leases, podRecords, deployRecords, podLabels, deployLabels))
+}
@@ -0,0 +1,318 @@
+package networkpolicy
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/linkernetworks/vortex/src/utils"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// PolicySyncInterval is the interval to re-compute the flows of the policies when the pods come and go
+const PolicySyncInterval = 30 * time.Second
+
+// nodeState is the flows pushed to the bridge of a node
+// The flows are pushed with the cookie of the next generation before the flows of the current generation
+// are deleted, so the policies are always applied while the flows are replaced.
+type nodeState struct {
+	generation uint64
+	hash       string
+}
+
+var (
+	mu     sync.Mutex
+	states = map[string]nodeState{}
+)
+
+// The networks triggered to sync in the background, the triggers are merged until the sync loop picks them up
+var (
+	pendingMu sync.Mutex
+	pending   = map[string]bool{}
+	wakeup    = make(chan struct{}, 1)
+)
+
+func stateKey(networkName, nodeName string) string {
+	return networkName + "/" + nodeName
+}
+
+// The pods are found by the ip leases of the network and the addresses of the pod and deployment records,
+// so the pods on the network without the subnets and the ipv6 addresses are found too.
+// The pod of the deployment has the labels of its pod template if the pod isn't found by its name.
+func listEndpoints(sp *serviceprovider.Container, session *mongo.Session, network *entity.Network) ([]Endpoint, error) {
+	leases := []entity.IPLease{}
+	if err := session.FindAll(entity.IPLeaseCollectionName, bson.M{"networkName": network.Name}, &leases); err != nil {
+		return nil, err
+	}
+	podRecords := []entity.Pod{}
+	if err := session.FindAll(entity.PodCollectionName, bson.M{"networks.name": network.Name}, &podRecords); err != nil {
+		return nil, err
+	}
+	deployRecords := []entity.Deployment{}
+	if err := session.FindAll(entity.DeploymentCollectionName, bson.M{"networks.name": network.Name}, &deployRecords); err != nil {
+		return nil, err
+	}
+	pods, err := sp.KubeCtl.GetPods("")
+	if err != nil {
+		return nil, err
+	}
+	deployments, err := sp.KubeCtl.GetDeployments("")
+	if err != nil {
+		return nil, err
+	}
+
+	podLabels := map[string]map[string]string{}
+	for _, pod := range pods {
+		podLabels[pod.Namespace+"/"+pod.Name] = pod.Labels
+	}
+	deployLabels := map[string]map[string]string{}
+	for _, d := range deployments {
+		deployLabels[d.Namespace+"/"+d.Name] = d.Spec.Template.Labels
+	}
+	return endpoints(network.Name, leases, podRecords, deployRecords, podLabels, deployLabels), nil
+}
+
+// The labels of the pods and the pod templates of the deployments are keyed by namespace/name
+func endpoints(networkName string, leases []entity.IPLease, podRecords []entity.Pod, deployRecords []entity.Deployment, podLabels, deployLabels map[string]map[string]string) []Endpoint {
+	ret := []Endpoint{}
+	//The leased address is also the address of the pod record
+	seen := map[string]bool{}
+	add := func(address string, labels map[string]string) {
+		if address == "" || seen[address] {
+			return
+		}
+		seen[address] = true
+		ret = append(ret, Endpoint{IPAddress: address, Labels: labels})
+	}
+
+	for _, lease := range leases {
+		labels, ok := podLabels[lease.Namespace+"/"+lease.PodName]
+		if !ok && lease.DeploymentName != "" {
+			labels, ok = deployLabels[lease.Namespace+"/"+lease.DeploymentName]
+		}
+		//The pod has gone away
+		if !ok {
+			continue
+		}
+		add(lease.IPAddress, labels)
+	}
+	for _, pod := range podRecords {
+		labels, ok := podLabels[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue
+		}
+		for _, network := range pod.Networks {
+			if network.Name != networkName {
+				continue
+			}
+			add(network.IPAddress, labels)
+			for _, address := range network.Addresses {
+				add(addressIP(address), labels)
+			}
+		}
+	}
+	for _, deploy := range deployRecords {
+		labels, ok := deployLabels[deploy.Namespace+"/"+deploy.Name]
+		if !ok {
+			continue
+		}
+		for _, network := range deploy.Networks {
+			if network.Name != networkName {
+				continue
+			}
+			add(network.IPAddress, labels)
+			for _, address := range network.Addresses {
+				add(addressIP(address), labels)
+			}
+		}
+	}
+	return ret
+}
+
+// The additional addresses are in the cidr format
+func addressIP(address string) string {
+	ip, _, err := net.ParseCIDR(address)
+	if err != nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// Sync will re-compute the flows of the network policies and push them to the bridge of each node
+// The flows are only pushed to the node if they're changed since the last sync.
+// Only the OVS networks have the policies, the other networks are skipped.
+func Sync(sp *serviceprovider.Container, network *entity.Network) error {
+	if network.Type != entity.OVSKernelspaceNetworkType && network.Type != entity.OVSUserspaceNetworkType {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	policies := []entity.NetworkPolicy{}
+	if err := session.FindAll(entity.NetworkPolicyCollectionName, bson.M{"networkID": network.ID}, &policies); err != nil {
+		return err
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	endpoints, err := listEndpoints(sp, session, network)
+	if err != nil {
+		return err
+	}
+
+	cookie := Cookie(network.Name)
+	flows := GenerateFlows(policies, endpoints, cookie)
+	hash := utils.SHA256String(strings.Join(flows, "\n"))
+
+	errs := []string{}
+	for _, node := range network.Nodes {
+		key := stateKey(network.Name, node.Name)
+		state, ok := states[key]
+		if ok && state.hash == hash {
+			continue
+		}
+		generation, err := pushFlows(sp, node.Name, network.BridgeName, cookie, flows, state, ok)
+		if err != nil {
+			delete(states, key)
+			errs = append(errs, fmt.Sprintf("node %s: %v", node.Name, err))
+			continue
+		}
+		states[key] = nodeState{generation, hash}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("push the flows of network %s fail: %s", network.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// The lowest bit of the cookie is the generation, the flows of the unknown generation are all deleted before
+// the new flows are pushed, e.g. after vortex restarts.
+func pushFlows(sp *serviceprovider.Container, nodeName, bridgeName string, cookie uint64, flows []string, state nodeState, known bool) (uint64, error) {
+	nodeIP, err := sp.KubeCtl.GetNodeInternalIP(nodeName)
+	if err != nil {
+		return 0, err
+	}
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return 0, err
+	}
+
+	if !known {
+		if err := nc.DeleteOVSFlows(bridgeName, fmt.Sprintf("cookie=0x%x/0x%x", cookie, ^uint64(1))); err != nil {
+			return 0, err
+		}
+		return 0, nc.AddOVSFlows(bridgeName, generationFlows(flows, cookie, 0))
+	}
+
+	generation := state.generation ^ 1
+	if err := nc.AddOVSFlows(bridgeName, generationFlows(flows, cookie, generation)); err != nil {
+		return 0, err
+	}
+	return generation, nc.DeleteOVSFlows(bridgeName, fmt.Sprintf("cookie=0x%x/-1", cookie|state.generation))
+}
+
+func generationFlows(flows []string, cookie, generation uint64) []string {
+	if generation == 0 {
+		return flows
+	}
+	ret := make([]string, len(flows))
+	from := fmt.Sprintf("cookie=0x%x,", cookie)
+	to := fmt.Sprintf("cookie=0x%x,", cookie|generation)
+	for i, flow := range flows {
+		ret[i] = strings.Replace(flow, from, to, 1)
+	}
+	return ret
+}
+
+// Forget will drop the flows pushed to the nodes of the network, it's called when the network is deleted
+func Forget(network *entity.Network) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, node := range network.Nodes {
+		delete(states, stateKey(network.Name, node.Name))
+	}
+}
+
+// Trigger will sync the policies of the network in the background by the SyncPolicies loop
+// It's called when the pods lease the ip addresses, so the pods don't wait for the nodes of the network.
+func Trigger(network *entity.Network) {
+	pendingMu.Lock()
+	pending[network.Name] = true
+	pendingMu.Unlock()
+
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// SyncAll will sync the policies of all OVS networks
+func SyncAll(sp *serviceprovider.Container) error {
+	return syncNetworks(sp, bson.M{})
+}
+
+func syncPending(sp *serviceprovider.Container) error {
+	pendingMu.Lock()
+	names := []string{}
+	for name := range pending {
+		names = append(names, name)
+	}
+	pending = map[string]bool{}
+	pendingMu.Unlock()
+
+	return syncNetworks(sp, bson.M{"name": bson.M{"$in": names}})
+}
+
+func syncNetworks(sp *serviceprovider.Container, q bson.M) error {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	networks := []entity.Network{}
+	q["type"] = bson.M{"$in": []entity.NetworkType{entity.OVSKernelspaceNetworkType, entity.OVSUserspaceNetworkType}}
+	if err := session.FindAll(entity.NetworkCollectionName, q, &networks); err != nil {
+		return err
+	}
+	for i := range networks {
+		//The node may be unreachable, the other networks are still synced
+		if err := Sync(sp, &networks[i]); err != nil {
+			logger.Warnf("sync the network policies fail: %v", err)
+		}
+	}
+	return nil
+}
+
+// SyncPolicies will re-compute the flows of the policies periodically and when the networks are triggered
+// The flows are pushed at the start since the flows pushed before vortex restarts are unknown.
+func SyncPolicies(sp *serviceprovider.Container, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	if err := SyncAll(sp); err != nil {
+		logger.Warnf("sync the network policies fail: %v", err)
+	}
+	for {
+		var err error
+		select {
+		case <-ticker.C:
+			err = SyncAll(sp)
+		case <-wakeup:
+			err = syncPending(sp)
+		}
+		if err != nil {
+			logger.Warnf("sync the network policies fail: %v", err)
+		}
+	}
+}
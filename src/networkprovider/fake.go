@@ -11,16 +11,29 @@ type fakeNetworkProvider struct {
 	entity.Network
 }
 
-func (fnp fakeNetworkProvider) CreateNetwork(sp *serviceprovider.Container) error {
+func (fnp fakeNetworkProvider) CreateNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
 	if !fnp.IsDPDKPort {
-		return fmt.Errorf("fail to validate but don't worry, I'm fake network")
+		return nil, fmt.Errorf("fail to validate but don't worry, I'm fake network")
 	}
-	return nil
+	return fnp.results(), nil
 }
 
-func (fnp fakeNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) error {
+func (fnp fakeNetworkProvider) UpdateNetwork(sp *serviceprovider.Container, network *entity.Network) error {
 	if !fnp.IsDPDKPort {
-		return fmt.Errorf("fail to delete network but don't worry, I'm fake network")
+		return fmt.Errorf("fail to update network but don't worry, I'm fake network")
 	}
 	return nil
 }
+
+func (fnp fakeNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	if !fnp.IsDPDKPort {
+		return nil, fmt.Errorf("fail to delete network but don't worry, I'm fake network")
+	}
+	return fnp.results(), nil
+}
+
+func (fnp fakeNetworkProvider) results() []entity.NodeResult {
+	return runOnNodes(fnp.Nodes, func(i int, node entity.Node) error {
+		return nil
+	})
+}
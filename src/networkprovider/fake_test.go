@@ -14,7 +14,7 @@ func TestFakeNetworkCreating(t *testing.T) {
 		Type:       entity.FakeNetworkType,
 	})
 	assert.NoError(t, err)
-	err = fake.CreateNetwork(nil)
+	_, err = fake.CreateNetwork(nil)
 	assert.NoError(t, err)
 }
 
@@ -23,7 +23,7 @@ func TestFakeNetworkCreatingFail(t *testing.T) {
 		Type: entity.FakeNetworkType,
 	})
 	assert.NoError(t, err)
-	err = fake.CreateNetwork(nil)
+	_, err = fake.CreateNetwork(nil)
 	assert.Error(t, err)
 }
 
@@ -33,7 +33,7 @@ func TestFakeNetworkDelete(t *testing.T) {
 		Type:       entity.FakeNetworkType,
 	})
 	assert.NoError(t, err)
-	err = fake.DeleteNetwork(nil)
+	_, err = fake.DeleteNetwork(nil)
 	assert.NoError(t, err)
 }
 
@@ -42,6 +42,6 @@ func TestFakeNetworkDeleteFail(t *testing.T) {
 		Type: entity.FakeNetworkType,
 	})
 	assert.NoError(t, err)
-	err = fake.DeleteNetwork(nil)
+	_, err = fake.DeleteNetwork(nil)
 	assert.Error(t, err)
 }
@@ -0,0 +1,257 @@
+package networkprovider
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// UnsupportedError is returned if the operation is unsupported for the network type
+type UnsupportedError struct {
+	Operation   string
+	NetworkType entity.NetworkType
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("the %s is unsupported for the network type %s", e.Operation, e.NetworkType)
+}
+
+// CheckNetworkHealth will compare the actual bridge and ports on each node with the network
+// Only the OVS networks can be checked since the network controller dumps the ports of OVS bridges.
+// The drift is repaired on the nodes which can be reached if the repair is set.
+func CheckNetworkHealth(sp *serviceprovider.Container, network *entity.Network, repair bool) (*entity.NetworkHealth, error) {
+	health := &entity.NetworkHealth{
+		Healthy: true,
+		Nodes:   make([]entity.NodeHealth, len(network.Nodes)),
+	}
+
+	switch network.Type {
+	case entity.OVSKernelspaceNetworkType, entity.OVSUserspaceNetworkType:
+	case entity.FakeNetworkType:
+		for i, node := range network.Nodes {
+			health.Nodes[i] = entity.NodeHealth{Node: node.Name, Healthy: true}
+		}
+		return health, nil
+	default:
+		return nil, &UnsupportedError{"health check", network.Type}
+	}
+
+	nodeIPs, err := getNodeIPs(sp, network.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	mirrorPorts, err := mirrorOutputPorts(sp, network)
+	if err != nil {
+		return nil, err
+	}
+
+	runOnNodes(network.Nodes, func(i int, node entity.Node) error {
+		nodeHealth := checkOVSNode(sp, network, node, nodeIPs[i], nodeIPs, mirrorPorts[node.Name])
+		if repair && !nodeHealth.Healthy && nodeHealth.Error == "" {
+			if err := repairOVSNode(sp, network, node, nodeIPs[i], nodeIPs, &nodeHealth); err != nil {
+				nodeHealth.RepairError = err.Error()
+			} else {
+				nodeHealth.Repaired = true
+			}
+		}
+		health.Nodes[i] = nodeHealth
+		return nil
+	})
+
+	for _, v := range health.Nodes {
+		if !v.Healthy {
+			health.Healthy = false
+		}
+	}
+	return health, nil
+}
+
+// The output ports of the mirrors are added to the bridges by the admin, they aren't the drift of the network
+func mirrorOutputPorts(sp *serviceprovider.Container, network *entity.Network) (map[string][]string, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	mirrors := []entity.Mirror{}
+	if err := session.FindAll(entity.MirrorCollectionName, bson.M{"networkID": network.ID}, &mirrors); err != nil {
+		return nil, err
+	}
+	ports := map[string][]string{}
+	for _, mirror := range mirrors {
+		if mirror.OutputPort != "" {
+			ports[mirror.NodeName] = append(ports[mirror.NodeName], mirror.OutputPort)
+		}
+	}
+	return ports, nil
+}
+
+func checkOVSNode(sp *serviceprovider.Container, network *entity.Network, node entity.Node, nodeIP string, nodeIPs []string, mirrorPorts []string) entity.NodeHealth {
+	health := entity.NodeHealth{Node: node.Name}
+
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	//The network controller can't dump the ports if the bridge doesn't exist
+	ports, err := nc.DumpOVSPorts(network.BridgeName)
+	if err != nil {
+		if isUnreachable(err) {
+			health.Error = err.Error()
+		} else {
+			health.MissingBridge = true
+		}
+		return health
+	}
+
+	actual := map[string]bool{}
+	for _, port := range ports {
+		actual[port.Name] = true
+	}
+	expected := map[string]bool{}
+
+	for _, phyIface := range node.PhyInterfaces {
+		expected[phyIface.Name] = true
+		if !actual[phyIface.Name] {
+			health.MissingPorts = append(health.MissingPorts, phyIface.Name)
+			continue
+		}
+		trunk, err := nc.GetOVSPortTrunk(phyIface.Name)
+		if err != nil {
+			health.Error = err.Error()
+			return health
+		}
+		if !sameVlanTags(trunk, network.VlanTags) {
+			health.WrongTrunks = append(health.WrongTrunks, phyIface.Name)
+		}
+	}
+
+	if network.OverlayType != "" {
+		for _, remoteIP := range nodeIPs {
+			if remoteIP == nodeIP {
+				continue
+			}
+			tunnelName := GenerateTunnelName(network.OverlayType, network.BridgeName, remoteIP)
+			expected[tunnelName] = true
+			if !actual[tunnelName] {
+				health.MissingPorts = append(health.MissingPorts, tunnelName)
+			}
+		}
+	}
+
+	for _, name := range mirrorPorts {
+		expected[name] = true
+	}
+	for _, port := range ports {
+		if !expected[port.Name] && !isManagedPort(network, port.Name) {
+			health.ExtraPorts = append(health.ExtraPorts, port.Name)
+		}
+	}
+
+	health.Healthy = len(health.MissingPorts) == 0 && len(health.ExtraPorts) == 0 && len(health.WrongTrunks) == 0
+	return health
+}
+
+func repairOVSNode(sp *serviceprovider.Container, network *entity.Network, node entity.Node, nodeIP string, nodeIPs []string, health *entity.NodeHealth) error {
+	//The ports and tunnels are created with the bridge
+	if health.MissingBridge {
+		return createOVSNode(sp, &transaction{}, *network, node, nodeIP, nodeIPs)
+	}
+
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range health.ExtraPorts {
+		if err := nc.DeleteOVSPort(network.BridgeName, name); err != nil {
+			return err
+		}
+	}
+
+	missing := map[string]bool{}
+	for _, name := range health.MissingPorts {
+		missing[name] = true
+	}
+	wrongTrunks := map[string]bool{}
+	for _, name := range health.WrongTrunks {
+		wrongTrunks[name] = true
+	}
+	addedIfaces := []entity.PhyInterface{}
+	trunkIfaces := []entity.PhyInterface{}
+	for _, phyIface := range node.PhyInterfaces {
+		if missing[phyIface.Name] {
+			addedIfaces = append(addedIfaces, phyIface)
+		} else if wrongTrunks[phyIface.Name] {
+			trunkIfaces = append(trunkIfaces, phyIface)
+		}
+	}
+
+	if len(addedIfaces) > 0 {
+		if network.IsDPDKPort {
+			err = nc.AddOVSDPDKPorts(network.BridgeName, addedIfaces, network.VlanTags)
+		} else {
+			err = nc.AddOVSPorts(network.BridgeName, addedIfaces, network.VlanTags)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(trunkIfaces) > 0 {
+		if err := nc.SetOVSPortsTrunk(trunkIfaces, network.VlanTags); err != nil {
+			return err
+		}
+	}
+
+	if network.OverlayType == "" {
+		return nil
+	}
+	for _, remoteIP := range nodeIPs {
+		tunnelName := GenerateTunnelName(network.OverlayType, network.BridgeName, remoteIP)
+		if remoteIP == nodeIP || !missing[tunnelName] {
+			continue
+		}
+		if err := nc.AddOVSTunnelPort(network.BridgeName, tunnelName, network.OverlayType, remoteIP, network.VNI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The internal port of the bridge and the veth of pods are managed by OVS and the network controller,
+// they aren't the drift of the network.
+func isManagedPort(network *entity.Network, portName string) bool {
+	return portName == network.BridgeName || portName == "LOCAL" || strings.HasPrefix(portName, "veth")
+}
+
+// The node can't be checked if the network controller is unreachable
+func isUnreachable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func sameVlanTags(a, b []int32) bool {
+	setA := map[int32]bool{}
+	for _, tag := range a {
+		setA[tag] = true
+	}
+	setB := map[int32]bool{}
+	for _, tag := range b {
+		setB[tag] = true
+	}
+	return reflect.DeepEqual(setA, setB)
+}
@@ -0,0 +1,38 @@
+package networkprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkernetworks/vortex/src/entity"
+)
+
+func TestSameVlanTags(t *testing.T) {
+	assert.True(t, sameVlanTags([]int32{}, nil))
+	assert.True(t, sameVlanTags([]int32{100, 200}, []int32{200, 100}))
+	assert.False(t, sameVlanTags([]int32{100}, []int32{100, 200}))
+	assert.False(t, sameVlanTags([]int32{}, []int32{100}))
+}
+
+func TestIsManagedPort(t *testing.T) {
+	network := &entity.Network{BridgeName: "system-62fc3f"}
+	assert.True(t, isManagedPort(network, "system-62fc3f"))
+	assert.True(t, isManagedPort(network, "LOCAL"))
+	assert.True(t, isManagedPort(network, "veth7e3a8c21"))
+	assert.False(t, isManagedPort(network, "eth1"))
+	assert.False(t, isManagedPort(network, "vxlan-1a2b3c4d"))
+}
+
+func TestCheckNetworkHealthUnsupported(t *testing.T) {
+	_, err := CheckNetworkHealth(nil, &entity.Network{Type: entity.SRIOVNetworkType}, false)
+	assert.IsType(t, &UnsupportedError{}, err)
+
+	health, err := CheckNetworkHealth(nil, &entity.Network{
+		Type:  entity.FakeNetworkType,
+		Nodes: []entity.Node{{Name: "node1"}},
+	}, true)
+	assert.NoError(t, err)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, []entity.NodeHealth{{Node: "node1", Healthy: true}}, health.Nodes)
+}
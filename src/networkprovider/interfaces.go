@@ -0,0 +1,213 @@
+package networkprovider
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	pc "github.com/linkernetworks/vortex/src/prometheuscontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// InterfaceError is the physical interface which can't be used by the network
+type InterfaceError struct {
+	Node      string
+	Interface string
+	Reason    string
+}
+
+func (e *InterfaceError) Error() string {
+	return fmt.Sprintf("the interface %s on node %s %s", e.Interface, e.Node, e.Reason)
+}
+
+// ListNodeInterfaces will list the physical interfaces of the node and mark the interfaces used by the vortex networks
+// The interfaces which are the ports of the OVS bridges are also marked by the network controller,
+// the network controller is optional and the ControllerError is set if it can't be reached.
+// The interfaces are from the node exporter, the MetricsError is set and the list is empty if the prometheus can't be reached.
+func ListNodeInterfaces(sp *serviceprovider.Container, nodeName string) (*entity.NodeInterfaces, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	networks := []entity.Network{}
+	if err := session.FindAll(entity.NetworkCollectionName, bson.M{"nodes.name": nodeName}, &networks); err != nil {
+		return nil, err
+	}
+
+	result := &entity.NodeInterfaces{
+		Node:       nodeName,
+		Interfaces: []entity.NodeInterface{},
+	}
+	nics, err := pc.ListNodeNICs(sp, nodeName)
+	if err != nil {
+		result.MetricsError = err.Error()
+		return result, nil
+	}
+	result.Interfaces = mergeInterfaces(nodeName, nics.NICs, networks, "")
+
+	nodeIP, err := sp.KubeCtl.GetNodeInternalIP(nodeName)
+	if err != nil {
+		result.ControllerError = err.Error()
+		return result, nil
+	}
+	nc, err := sp.NetworkControllers.Get(net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT))
+	if err != nil {
+		result.ControllerError = err.Error()
+		return result, nil
+	}
+	for i, iface := range result.Interfaces {
+		trunk, err := nc.GetOVSPortTrunk(iface.Name)
+		if err != nil {
+			//The interface isn't the port of any OVS bridge
+			if !isUnreachable(err) {
+				continue
+			}
+			result.ControllerError = err.Error()
+			break
+		}
+		result.Interfaces[i].OVSPort = true
+		result.Interfaces[i].Trunk = trunk
+	}
+	return result, nil
+}
+
+// The networks which use the interfaces of the node except the excluded one, e.g. the network which is being updated
+func interfaceUsers(nodeName string, networks []entity.Network, exclude string) map[string][]entity.Network {
+	users := map[string][]entity.Network{}
+	for _, network := range networks {
+		if network.Name == exclude {
+			continue
+		}
+		for _, node := range network.Nodes {
+			if node.Name != nodeName {
+				continue
+			}
+			for _, phyIface := range node.PhyInterfaces {
+				users[phyIface.Name] = append(users[phyIface.Name], network)
+			}
+		}
+	}
+	return users
+}
+
+func mergeInterfaces(nodeName string, nics []entity.NICOverviewMetrics, networks []entity.Network, exclude string) []entity.NodeInterface {
+	users := interfaceUsers(nodeName, networks, exclude)
+
+	ifaces := []entity.NodeInterface{}
+	for _, nic := range nics {
+		names := []string{}
+		for _, network := range users[nic.Name] {
+			names = append(names, network.Name)
+		}
+		ifaces = append(ifaces, entity.NodeInterface{
+			Name:    nic.Name,
+			PCIID:   nic.PCIID,
+			Type:    nic.Type,
+			DPDK:    nic.DPDK,
+			Default: nic.Default,
+			UsedBy:  strings.Join(names, ","),
+			//The default interface carries the traffic of the node itself
+			Available: len(names) == 0 && !nic.Default,
+		})
+	}
+	return ifaces
+}
+
+// isExclusive will return true if the network takes the physical interface itself
+// The OVS bridges take the interfaces as their ports, and so does the linux bridge without the vlan tag.
+// The vlan sub-interfaces of the linux bridges and the virtual functions of a SR-IOV PF can share the interface.
+func isExclusive(network *entity.Network) bool {
+	switch network.Type {
+	case entity.OVSKernelspaceNetworkType, entity.OVSUserspaceNetworkType:
+		return true
+	case entity.LinuxBridgeNetworkType:
+		return len(network.VlanTags) == 0
+	}
+	return false
+}
+
+// conflicts will return true if the networks can't share the physical interface
+// The linux bridges create the same vlan sub-interface if they have the same vlan tag.
+// The SR-IOV networks own the virtual functions of the PF, since they're created and deleted together by the numVFs.
+func conflicts(a, b *entity.Network) bool {
+	if isExclusive(a) || isExclusive(b) {
+		return true
+	}
+	if a.Type == entity.SRIOVNetworkType && b.Type == entity.SRIOVNetworkType {
+		return true
+	}
+	if a.Type == entity.LinuxBridgeNetworkType && b.Type == entity.LinuxBridgeNetworkType {
+		return a.VlanTags[0] == b.VlanTags[0]
+	}
+	return false
+}
+
+// CheckPhyInterfaces will check the physical interfaces of the network exist on the nodes and can be shared with the other networks
+// The empty PCIID of the interface is filled by the one on the node. The fake network isn't checked.
+// The DPDK interfaces aren't listed by the node exporter, and the existence isn't checked if the prometheus can't be reached.
+func CheckPhyInterfaces(sp *serviceprovider.Container, network *entity.Network) error {
+	if network.Type == entity.FakeNetworkType {
+		return nil
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	for i, node := range network.Nodes {
+		if len(node.PhyInterfaces) == 0 {
+			continue
+		}
+		networks := []entity.Network{}
+		if err := session.FindAll(entity.NetworkCollectionName, bson.M{"nodes.name": node.Name}, &networks); err != nil {
+			return err
+		}
+		if err := checkSharedInterfaces(network, node, interfaceUsers(node.Name, networks, network.Name)); err != nil {
+			return err
+		}
+
+		if network.IsDPDKPort {
+			continue
+		}
+		nics, err := pc.ListNodeNICs(sp, node.Name)
+		if err != nil {
+			logger.Warnf("list the interfaces of node %s fail, their existence isn't checked: %v", node.Name, err)
+			continue
+		}
+		if err := checkNodeInterfaces(&network.Nodes[i], nics.NICs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSharedInterfaces(network *entity.Network, node entity.Node, users map[string][]entity.Network) error {
+	for _, phyIface := range node.PhyInterfaces {
+		for _, user := range users[phyIface.Name] {
+			if conflicts(network, &user) {
+				return &InterfaceError{node.Name, phyIface.Name, "is used by the network " + user.Name}
+			}
+		}
+	}
+	return nil
+}
+
+func checkNodeInterfaces(node *entity.Node, nics []entity.NICOverviewMetrics) error {
+	found := map[string]entity.NICOverviewMetrics{}
+	for _, nic := range nics {
+		found[nic.Name] = nic
+	}
+	for j, phyIface := range node.PhyInterfaces {
+		nic, ok := found[phyIface.Name]
+		if !ok {
+			return &InterfaceError{node.Name, phyIface.Name, "doesn't exist"}
+		}
+		if phyIface.PCIID == "" {
+			node.PhyInterfaces[j].PCIID = nic.PCIID
+		}
+	}
+	return nil
+}
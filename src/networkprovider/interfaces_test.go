@@ -0,0 +1,99 @@
+package networkprovider
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeInterfaces(t *testing.T) {
+	nics := []entity.NICOverviewMetrics{
+		{Name: "eth0", Default: true, Type: "physical", PCIID: "0000:00:03.0"},
+		{Name: "eth1", Type: "physical", PCIID: "0000:00:04.0"},
+		{Name: "eth2", DPDK: true, Type: "physical", PCIID: "0000:00:05.0"},
+	}
+	networks := []entity.Network{
+		{
+			Name: "net1",
+			Nodes: []entity.Node{
+				{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}},
+				{Name: "node2", PhyInterfaces: []entity.PhyInterface{{Name: "eth2"}}},
+			},
+		},
+	}
+
+	ifaces := mergeInterfaces("node1", nics, networks, "")
+	assert.Equal(t, 3, len(ifaces))
+	assert.Equal(t, "", ifaces[0].UsedBy)
+	assert.False(t, ifaces[0].Available)
+	assert.Equal(t, "net1", ifaces[1].UsedBy)
+	assert.False(t, ifaces[1].Available)
+	assert.Equal(t, "", ifaces[2].UsedBy)
+	assert.True(t, ifaces[2].Available)
+	assert.True(t, ifaces[2].DPDK)
+	assert.Equal(t, "0000:00:05.0", ifaces[2].PCIID)
+
+	//The excluded network doesn't use the interfaces
+	ifaces = mergeInterfaces("node1", nics, networks, "net1")
+	assert.Equal(t, "", ifaces[1].UsedBy)
+	assert.True(t, ifaces[1].Available)
+}
+
+func TestCheckNodeInterfaces(t *testing.T) {
+	nics := []entity.NICOverviewMetrics{
+		{Name: "eth1", PCIID: "0000:00:04.0"},
+	}
+
+	node := entity.Node{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}}
+	assert.NoError(t, checkNodeInterfaces(&node, nics))
+	assert.Equal(t, "0000:00:04.0", node.PhyInterfaces[0].PCIID)
+
+	node = entity.Node{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:00:08.0"}}}
+	assert.NoError(t, checkNodeInterfaces(&node, nics))
+	assert.Equal(t, "0000:00:08.0", node.PhyInterfaces[0].PCIID)
+
+	node = entity.Node{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth9"}}}
+	err := checkNodeInterfaces(&node, nics)
+	assert.Error(t, err)
+	assert.IsType(t, &InterfaceError{}, err)
+}
+
+func TestCheckSharedInterfaces(t *testing.T) {
+	node := entity.Node{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}}
+	ovs := entity.Network{Name: "ovs", Type: entity.OVSKernelspaceNetworkType, Nodes: []entity.Node{node}}
+	bridge := entity.Network{Name: "bridge", Type: entity.LinuxBridgeNetworkType, Nodes: []entity.Node{node}}
+	vlan100 := entity.Network{Name: "vlan100", Type: entity.LinuxBridgeNetworkType, VlanTags: []int32{100}, Nodes: []entity.Node{node}}
+	vlan100b := entity.Network{Name: "vlan100b", Type: entity.LinuxBridgeNetworkType, VlanTags: []int32{100}, Nodes: []entity.Node{node}}
+	vlan200 := entity.Network{Name: "vlan200", Type: entity.LinuxBridgeNetworkType, VlanTags: []int32{200}, Nodes: []entity.Node{node}}
+	sriov := entity.Network{Name: "sriov", Type: entity.SRIOVNetworkType, VlanTags: []int32{100}, Nodes: []entity.Node{node}}
+	sriov2 := entity.Network{Name: "sriov2", Type: entity.SRIOVNetworkType, VlanTags: []int32{200}, Nodes: []entity.Node{node}}
+
+	testCases := []struct {
+		cases    string
+		network  entity.Network
+		users    []entity.Network
+		conflict bool
+	}{
+		{"Unused", ovs, nil, false},
+		{"OVSTakesTheInterface", ovs, []entity.Network{vlan100}, true},
+		{"UsedByOVS", sriov, []entity.Network{ovs}, true},
+		{"BridgeWithoutVlan", bridge, []entity.Network{sriov}, true},
+		{"VlanSubInterfaces", vlan200, []entity.Network{vlan100, sriov}, false},
+		{"SameVlanSubInterface", vlan100b, []entity.Network{vlan100}, true},
+		//The virtual functions of the PF are reset by each sriov network
+		{"SharedPF", sriov2, []entity.Network{sriov}, true},
+		{"PFWithVlanSubInterface", sriov2, []entity.Network{vlan100}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			users := interfaceUsers("node1", tc.users, tc.network.Name)
+			err := checkSharedInterfaces(&tc.network, node, users)
+			if tc.conflict {
+				assert.IsType(t, &InterfaceError{}, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
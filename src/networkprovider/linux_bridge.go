@@ -0,0 +1,133 @@
+package networkprovider
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+)
+
+type linuxBridgeNetworkProvider struct {
+	entity.Network
+}
+
+// All vlan sub-interfaces are attached to the same kernel bridge and the tag is stripped there,
+// so more than one vlan tag will merge different vlans into one L2 domain.
+func (lnp linuxBridgeNetworkProvider) validateVlanTags() error {
+	if len(lnp.VlanTags) > 1 {
+		return fmt.Errorf("the linux bridge network supports at most one vlan tag, but got %d", len(lnp.VlanTags))
+	}
+	return nil
+}
+
+func (lnp linuxBridgeNetworkProvider) CreateNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	if err := lnp.validateVlanTags(); err != nil {
+		return nil, err
+	}
+
+	nodeIPs, err := getNodeIPs(sp, lnp.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	//The bridges created on other nodes are deleted if any node fails
+	tx := &transaction{}
+	return tx.run(lnp.Nodes, func(i int, node entity.Node) error {
+		nodeIP := nodeIPs[i]
+		nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+		nc, err := sp.NetworkControllers.Get(nodeAddr)
+		if err != nil {
+			return nodeError(node.Name, StepConnect, err)
+		}
+		if err := nc.CreateLinuxBridge(lnp.BridgeName); err != nil {
+			return nodeError(node.Name, StepCreateBridge, err)
+		}
+		tx.add(node.Name, func() error {
+			return deleteLinuxBridgeNetwork(sp, nodeIP, lnp.BridgeName, node.PhyInterfaces, lnp.VlanTags)
+		})
+		if err := nc.AddLinuxBridgePorts(lnp.BridgeName, node.PhyInterfaces, lnp.VlanTags); err != nil {
+			return nodeError(node.Name, StepAddPorts, err)
+		}
+		return nil
+	})
+}
+
+// The vlan sub-interfaces of the kept physical interfaces are re-created if the vlan tag is changed
+func (lnp linuxBridgeNetworkProvider) UpdateNetwork(sp *serviceprovider.Container, network *entity.Network) error {
+	if err := (linuxBridgeNetworkProvider{*network}).validateVlanTags(); err != nil {
+		return err
+	}
+	diff := diffNetwork(&lnp.Network, network)
+
+	for _, node := range diff.removedNodes {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return err
+		}
+		if err := deleteLinuxBridgeNetwork(sp, nodeIP, lnp.BridgeName, node.PhyInterfaces, lnp.VlanTags); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range diff.keptNodes {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return err
+		}
+		nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+		nc, err := sp.NetworkControllers.Get(nodeAddr)
+		if err != nil {
+			return err
+		}
+
+		removed := diff.removedIfaces[node.Name]
+		added := diff.addedIfaces[node.Name]
+		if diff.vlanTagsChanged {
+			removed = append(removed, diff.keptIfaces[node.Name]...)
+			added = append(added, diff.keptIfaces[node.Name]...)
+		}
+		if err := nc.DeleteLinuxBridgePorts(lnp.BridgeName, removed, lnp.VlanTags); err != nil {
+			return err
+		}
+		if err := nc.AddLinuxBridgePorts(network.BridgeName, added, network.VlanTags); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range diff.addedNodes {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return err
+		}
+		if err := createLinuxBridgeNetwork(sp, nodeIP, network.BridgeName, node.PhyInterfaces, network.VlanTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lnp linuxBridgeNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	return deleteOnNodes(sp, lnp.Nodes, func(nodeIP string, node entity.Node) error {
+		return deleteLinuxBridgeNetwork(sp, nodeIP, lnp.BridgeName, node.PhyInterfaces, lnp.VlanTags)
+	})
+}
+
+func createLinuxBridgeNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return err
+	}
+	return nc.CreateLinuxBridgeNetwork(bridgeName, phyIfaces, vlanTags)
+}
+
+func deleteLinuxBridgeNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return err
+	}
+	return nc.DeleteLinuxBridgeNetwork(bridgeName, phyIfaces, vlanTags)
+}
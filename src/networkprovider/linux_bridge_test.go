@@ -0,0 +1,41 @@
+package networkprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkernetworks/vortex/src/entity"
+)
+
+func TestLinuxBridgeNetworkWithMultipleVlanTags(t *testing.T) {
+	provider, err := GetNetworkProvider(&entity.Network{
+		Type:     entity.LinuxBridgeNetworkType,
+		VlanTags: []int32{100, 200},
+	})
+	assert.NoError(t, err)
+	_, err = provider.CreateNetwork(nil)
+	assert.Error(t, err)
+}
+
+func TestLinuxBridgeNetworkValidateVlanTags(t *testing.T) {
+	testCases := []struct {
+		cases    string
+		vlanTags []int32
+	}{
+		{"noVlan", []int32{}},
+		{"singleVlan", []int32{100}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			provider := linuxBridgeNetworkProvider{
+				entity.Network{
+					Type:     entity.LinuxBridgeNetworkType,
+					VlanTags: tc.vlanTags,
+				},
+			}
+			assert.NoError(t, provider.validateVlanTags())
+		})
+	}
+}
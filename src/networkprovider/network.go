@@ -6,12 +6,17 @@ import (
 	"github.com/linkernetworks/vortex/src/entity"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 	"github.com/linkernetworks/vortex/src/utils"
+
+	"gopkg.in/mgo.v2/bson"
 )
 
 // NetworkProvider is the structure for Network Provider
+// CreateNetwork and DeleteNetwork operate the nodes concurrently and return the result of each node.
+// UpdateNetwork only applies the difference between the provider's network and the updated one
 type NetworkProvider interface {
-	CreateNetwork(sp *serviceprovider.Container) error
-	DeleteNetwork(sp *serviceprovider.Container) error
+	CreateNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error)
+	UpdateNetwork(sp *serviceprovider.Container, network *entity.Network) error
+	DeleteNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error)
 }
 
 // GetNetworkProvider will get network provider if you gave *entity.Network
@@ -25,6 +30,14 @@ func GetNetworkProvider(network *entity.Network) (NetworkProvider, error) {
 		return userspaceNetworkProvider{
 			*network,
 		}, nil
+	case entity.LinuxBridgeNetworkType:
+		return linuxBridgeNetworkProvider{
+			*network,
+		}, nil
+	case entity.SRIOVNetworkType:
+		return sriovNetworkProvider{
+			*network,
+		}, nil
 	case entity.FakeNetworkType:
 		return fakeNetworkProvider{
 			*network,
@@ -40,3 +53,56 @@ func GenerateBridgeName(datapathType, networkName string) string {
 	str := utils.SHA256String(tmp)
 	return fmt.Sprintf("%s-%s", datapathType, str[0:6])
 }
+
+// GetNetworkUsage will return the number of the interfaces attached to the network on each node
+// The interfaces are used by the running pods and the pods of deployments.
+func GetNetworkUsage(sp *serviceprovider.Container, network *entity.Network) (map[string]int, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	pods := []entity.Pod{}
+	if err := session.FindAll(entity.PodCollectionName, bson.M{"networks.name": network.Name}, &pods); err != nil {
+		return nil, fmt.Errorf("load the pods of network %s fail:%v", network.Name, err)
+	}
+	deployments := []entity.Deployment{}
+	if err := session.FindAll(entity.DeploymentCollectionName, bson.M{"networks.name": network.Name}, &deployments); err != nil {
+		return nil, fmt.Errorf("load the deployments of network %s fail:%v", network.Name, err)
+	}
+
+	//The key of podIfaces is namespace/name of the pod and the key of deployIfaces is the name of the deployment
+	podIfaces := map[string]int{}
+	for _, pod := range pods {
+		for _, v := range pod.Networks {
+			if v.Name == network.Name {
+				podIfaces[pod.Namespace+"/"+pod.Name]++
+			}
+		}
+	}
+	deployIfaces := map[string]int{}
+	for _, deploy := range deployments {
+		for _, v := range deploy.Networks {
+			if v.Name == network.Name {
+				deployIfaces[deploy.Name]++
+			}
+		}
+	}
+
+	currentPods, err := sp.KubeCtl.GetPods("")
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]int{}
+	for _, pod := range currentPods {
+		if pod.Spec.NodeName == "" || sp.KubeCtl.IsPodCompleted(pod) {
+			continue
+		}
+		used := podIfaces[pod.Namespace+"/"+pod.Name]
+		if name, ok := pod.Labels["vortex"]; ok {
+			used += deployIfaces[name]
+		}
+		if used > 0 {
+			usage[pod.Spec.NodeName] += used
+		}
+	}
+	return usage, nil
+}
@@ -17,6 +17,8 @@ func TestGetNetworkProvider(t *testing.T) {
 	}{
 		{"system", entity.OVSKernelspaceNetworkType, reflect.TypeOf(kernelspaceNetworkProvider{})},
 		{"netdev", entity.OVSUserspaceNetworkType, reflect.TypeOf(userspaceNetworkProvider{})},
+		{"linux", entity.LinuxBridgeNetworkType, reflect.TypeOf(linuxBridgeNetworkProvider{})},
+		{"sriov", entity.SRIOVNetworkType, reflect.TypeOf(sriovNetworkProvider{})},
 		{"fake", entity.FakeNetworkType, reflect.TypeOf(fakeNetworkProvider{})},
 	}
 
@@ -0,0 +1,110 @@
+package networkprovider
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/linkernetworks/vortex/src/utils"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MaxVNI is the max value of the 24 bits VXLAN network identifier
+const MaxVNI = 1<<24 - 1
+
+// ValidateOverlay will check the network type supports the overlay tunnels
+func ValidateOverlay(network *entity.Network) error {
+	if network.OverlayType == "" {
+		return nil
+	}
+	switch network.Type {
+	case entity.OVSKernelspaceNetworkType, entity.OVSUserspaceNetworkType, entity.FakeNetworkType:
+		return nil
+	default:
+		return fmt.Errorf("the overlay %s is unsupported for the network type %s", network.OverlayType, network.Type)
+	}
+}
+
+// AllocateVNI will allocate the lowest VNI which isn't used by other overlay networks
+func AllocateVNI(session *mongo.Session, networkName string) (int32, error) {
+	allocations := []entity.VNIAllocation{}
+	if err := session.FindAll(entity.VNICollectionName, bson.M{}, &allocations); err != nil {
+		return 0, err
+	}
+	used := map[int32]bool{}
+	for _, v := range allocations {
+		used[v.VNI] = true
+	}
+
+	for vni := int32(1); vni <= MaxVNI; vni++ {
+		if used[vni] {
+			continue
+		}
+		//The VNI is the _id of the allocation, another network may take it at the same time
+		err := session.Insert(entity.VNICollectionName, &entity.VNIAllocation{
+			VNI:         vni,
+			NetworkName: networkName,
+		})
+		if err == nil {
+			return vni, nil
+		} else if !mgo.IsDup(err) {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("there's no available VNI for network %s", networkName)
+}
+
+// ReleaseVNI will release the VNI of the overlay network
+func ReleaseVNI(session *mongo.Session, vni int32) error {
+	return session.Remove(entity.VNICollectionName, "_id", vni)
+}
+
+// GenerateTunnelName will generate the tunnel port name to the remote node
+// The port name is unique in the whole OVS, so we use the bridge name and remote IP to generate it.
+func GenerateTunnelName(tunnelType, bridgeName, remoteIP string) string {
+	tmp := fmt.Sprintf("%s%s", bridgeName, remoteIP)
+	str := utils.SHA256String(tmp)
+	return fmt.Sprintf("%s-%s", tunnelType, str[0:8])
+}
+
+func getNodeIPs(sp *serviceprovider.Container, nodes []entity.Node) ([]string, error) {
+	nodeIPs := []string{}
+	for _, node := range nodes {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return nil, &NetworkError{NodeError: NodeError{node.Name, StepGetNodeIP, err.Error()}}
+		}
+		nodeIPs = append(nodeIPs, nodeIP)
+	}
+	return nodeIPs, nil
+}
+
+// Create the tunnel ports from the node to all other nodes of the network
+func createOVSTunnels(sp *serviceprovider.Container, nodeIP string, network entity.Network, nodeIPs []string) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return err
+	}
+
+	for _, remoteIP := range nodeIPs {
+		if remoteIP == nodeIP {
+			continue
+		}
+		if err := nc.AddOVSTunnelPort(
+			network.BridgeName,
+			GenerateTunnelName(network.OverlayType, network.BridgeName, remoteIP),
+			network.OverlayType,
+			remoteIP,
+			network.VNI,
+		); err != nil {
+			return err
+		}
+	}
+	return nc.EnableOVSRSTP(network.BridgeName)
+}
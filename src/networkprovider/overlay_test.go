@@ -0,0 +1,88 @@
+package networkprovider
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/moby/moby/pkg/namesgenerator"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestValidateOverlay(t *testing.T) {
+	testCases := []struct {
+		cases       string
+		netType     entity.NetworkType
+		overlayType string
+		valid       bool
+	}{
+		{"systemWithoutOverlay", entity.OVSKernelspaceNetworkType, "", true},
+		{"systemVXLAN", entity.OVSKernelspaceNetworkType, entity.OverlayVXLAN, true},
+		{"netdevGRE", entity.OVSUserspaceNetworkType, entity.OverlayGRE, true},
+		{"linuxWithoutOverlay", entity.LinuxBridgeNetworkType, "", true},
+		{"linuxVXLAN", entity.LinuxBridgeNetworkType, entity.OverlayVXLAN, false},
+		{"sriovGRE", entity.SRIOVNetworkType, entity.OverlayGRE, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			err := ValidateOverlay(&entity.Network{
+				Type:        tc.netType,
+				OverlayType: tc.overlayType,
+			})
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateTunnelName(t *testing.T) {
+	name := GenerateTunnelName(entity.OverlayVXLAN, "system-62fc3f", "10.0.0.2")
+	assert.Equal(t, 14, len(name))
+	assert.Equal(t, name, GenerateTunnelName(entity.OverlayVXLAN, "system-62fc3f", "10.0.0.2"))
+	assert.NotEqual(t, name, GenerateTunnelName(entity.OverlayVXLAN, "system-62fc3f", "10.0.0.3"))
+}
+
+func TestAllocateVNI(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	//The VNIs are shared by all networks and other tests may allocate them at the same time,
+	//so the allocations are checked by the network names of this test instead of the VNI numbers
+	allocated := func(networkName string) []entity.VNIAllocation {
+		allocations := []entity.VNIAllocation{}
+		assert.NoError(t, session.FindAll(entity.VNICollectionName, bson.M{"networkName": networkName}, &allocations))
+		return allocations
+	}
+
+	name1 := namesgenerator.GetRandomName(0)
+	vni1, err := AllocateVNI(session, name1)
+	assert.NoError(t, err)
+	defer ReleaseVNI(session, vni1)
+	assert.Equal(t, []entity.VNIAllocation{{VNI: vni1, NetworkName: name1}}, allocated(name1))
+
+	name2 := namesgenerator.GetRandomName(0)
+	vni2, err := AllocateVNI(session, name2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, vni1, vni2)
+
+	//The released VNI isn't allocated to the network anymore
+	err = ReleaseVNI(session, vni2)
+	assert.NoError(t, err)
+	assert.Len(t, allocated(name2), 0)
+
+	name3 := namesgenerator.GetRandomName(0)
+	vni3, err := AllocateVNI(session, name3)
+	assert.NoError(t, err)
+	defer ReleaseVNI(session, vni3)
+	assert.NotEqual(t, vni1, vni3)
+	assert.Equal(t, []entity.VNIAllocation{{VNI: vni3, NetworkName: name3}}, allocated(name3))
+}
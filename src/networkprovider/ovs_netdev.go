@@ -12,72 +12,46 @@ type userspaceNetworkProvider struct {
 	entity.Network
 }
 
-func (unp userspaceNetworkProvider) CreateNetwork(sp *serviceprovider.Container) error {
-	for _, node := range unp.Nodes {
-		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
-		if err != nil {
-			return err
-		}
-		if unp.IsDPDKPort {
-			if err := createOVSDPDKNetwork(
-				nodeIP,
-				unp.BridgeName,
-				node.PhyInterfaces,
-				unp.VlanTags,
-			); err != nil {
-				return err
-			}
-		} else {
-			if err := createOVSUserspaceNetwork(
-				nodeIP,
-				unp.BridgeName,
-				node.PhyInterfaces,
-				unp.VlanTags,
-			); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+func (unp userspaceNetworkProvider) CreateNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	return createOVSNetworkOnNodes(sp, unp.Network)
 }
 
-func (unp userspaceNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) error {
-	for _, node := range unp.Nodes {
-		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
-		if err != nil {
-			return err
-		}
-		if err := deleteOVSUserspaceNetwork(
-			nodeIP,
-			unp.BridgeName,
-		); err != nil {
-			return err
+func (unp userspaceNetworkProvider) UpdateNetwork(sp *serviceprovider.Container, network *entity.Network) error {
+	return updateOVSNetwork(sp, &unp.Network, network, func(nodeIP string, network *entity.Network, node entity.Node) error {
+		if network.IsDPDKPort {
+			return createOVSDPDKNetwork(sp, nodeIP, network.BridgeName, node.PhyInterfaces, network.VlanTags)
 		}
-	}
-	return nil
+		return createOVSUserspaceNetwork(sp, nodeIP, network.BridgeName, node.PhyInterfaces, network.VlanTags)
+	})
+}
+
+func (unp userspaceNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	return deleteOnNodes(sp, unp.Nodes, func(nodeIP string, node entity.Node) error {
+		return deleteOVSUserspaceNetwork(sp, nodeIP, unp.BridgeName)
+	})
 }
 
-func createOVSDPDKNetwork(nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+func createOVSDPDKNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
 	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
-	nc, err := networkcontroller.New(nodeAddr)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
 	if err != nil {
 		return err
 	}
 	return nc.CreateOVSDPDKNetwork(bridgeName, phyIfaces, vlanTags)
 }
 
-func createOVSUserspaceNetwork(nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+func createOVSUserspaceNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
 	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
-	nc, err := networkcontroller.New(nodeAddr)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
 	if err != nil {
 		return err
 	}
 	return nc.CreateOVSNetwork("netdev", bridgeName, phyIfaces, vlanTags)
 }
 
-func deleteOVSUserspaceNetwork(nodeIP string, bridgeName string) error {
+func deleteOVSUserspaceNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string) error {
 	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
-	nc, err := networkcontroller.New(nodeAddr)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
 	if err != nil {
 		return err
 	}
@@ -124,6 +124,7 @@ func TestNetworkSuite(t *testing.T) {
 func (suite *OVSNetdevNetworkTestSuite) TestCreateOVSDPDKNetwork() {
 	brName := namesgenerator.GetRandomName(0)
 	err := createOVSDPDKNetwork(
+		suite.sp,
 		DPDK_LOCAL_IP,
 		brName,
 		[]entity.PhyInterface{},
@@ -136,6 +137,7 @@ func (suite *OVSNetdevNetworkTestSuite) TestCreateOVSDPDKNetwork() {
 func (suite *OVSNetdevNetworkTestSuite) TestCreateOVSUserspaceNetwork() {
 	brName := namesgenerator.GetRandomName(0)
 	err := createOVSUserspaceNetwork(
+		suite.sp,
 		DPDK_LOCAL_IP,
 		brName,
 		[]entity.PhyInterface{},
@@ -149,7 +151,7 @@ func (suite *OVSNetdevNetworkTestSuite) TestDeleteOVSUserspaceNetwork() {
 	brName := namesgenerator.GetRandomName(0)
 	// ovs-vsctl add-br br0 -- set bridge br0 datapath_type=netdev
 	exec.Command("ovs-vsctl", "add-br", brName, "--", "set", "bridge", brName, "datapath_type=netdev").Run()
-	err := deleteOVSUserspaceNetwork(DPDK_LOCAL_IP, brName)
+	err := deleteOVSUserspaceNetwork(suite.sp, DPDK_LOCAL_IP, brName)
 	suite.NoError(err)
 }
 
@@ -167,7 +169,7 @@ func (suite *OVSNetdevNetworkTestSuite) TestCreateNetwork() {
 			np, err := GetNetworkProvider(tc.network)
 			suite.NoError(err)
 			np = np.(userspaceNetworkProvider)
-			err = np.CreateNetwork(suite.sp)
+			_, err = np.CreateNetwork(suite.sp)
 			suite.NoError(err)
 			defer exec.Command("ovs-vsctl", "del-br", tc.network.BridgeName).Run()
 		})
@@ -189,7 +191,7 @@ func (suite *OVSNetdevNetworkTestSuite) TestCreateNetworkFail() {
 	np, err := GetNetworkProvider(&network)
 	suite.NoError(err)
 	np = np.(userspaceNetworkProvider)
-	err = np.CreateNetwork(suite.sp)
+	_, err = np.CreateNetwork(suite.sp)
 	suite.Error(err)
 }
 
@@ -208,13 +210,13 @@ func (suite *OVSNetdevNetworkTestSuite) TestDeleteNetwork() {
 			np, err := GetNetworkProvider(tc.network)
 			suite.NoError(err)
 			np = np.(userspaceNetworkProvider)
-			err = np.CreateNetwork(suite.sp)
+			_, err = np.CreateNetwork(suite.sp)
 			suite.NoError(err)
 
 			// ovs-vsctl add-br br0 -- set bridge br0 datapath_type=netdev
 			exec.Command("ovs-vsctl", "add-br", tc.network.BridgeName, "--", "set", "bridge", tc.network.BridgeName, "datapath_type=netdev").Run()
 			//FIXME we need a function to check the bridge is exist
-			err = np.DeleteNetwork(suite.sp)
+			_, err = np.DeleteNetwork(suite.sp)
 			suite.NoError(err)
 		})
 	}
@@ -235,6 +237,6 @@ func (suite *OVSNetdevNetworkTestSuite) TestDeleteNetworkFail() {
 	np, err := GetNetworkProvider(&network)
 	suite.NoError(err)
 	np = np.(userspaceNetworkProvider)
-	err = np.DeleteNetwork(suite.sp)
+	_, err = np.DeleteNetwork(suite.sp)
 	suite.Error(err)
 }
@@ -12,54 +12,83 @@ type kernelspaceNetworkProvider struct {
 	entity.Network
 }
 
-func (knp kernelspaceNetworkProvider) CreateNetwork(sp *serviceprovider.Container) error {
-	for _, node := range knp.Nodes {
-		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
-		if err != nil {
-			return err
-		}
-		if err := createOVSNetwork(
-			nodeIP,
-			knp.BridgeName,
-			node.PhyInterfaces,
-			knp.VlanTags,
-		); err != nil {
-			return err
-		}
-	}
-	return nil
+func (knp kernelspaceNetworkProvider) CreateNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	return createOVSNetworkOnNodes(sp, knp.Network)
 }
 
-func (knp kernelspaceNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) error {
-	for _, node := range knp.Nodes {
-		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
-		if err != nil {
-			return err
-		}
-		if err := deleteOVSNetwork(
-			nodeIP,
-			knp.BridgeName,
-		); err != nil {
-			return err
-		}
-	}
-	return nil
+func (knp kernelspaceNetworkProvider) UpdateNetwork(sp *serviceprovider.Container, network *entity.Network) error {
+	return updateOVSNetwork(sp, &knp.Network, network, func(nodeIP string, network *entity.Network, node entity.Node) error {
+		return createOVSNetwork(sp, nodeIP, network.BridgeName, node.PhyInterfaces, network.VlanTags)
+	})
 }
 
-func createOVSNetwork(nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
+func (knp kernelspaceNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	return deleteOnNodes(sp, knp.Nodes, func(nodeIP string, node entity.Node) error {
+		return deleteOVSNetwork(sp, nodeIP, knp.BridgeName)
+	})
+}
+
+func createOVSNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string, phyIfaces []entity.PhyInterface, vlanTags []int32) error {
 	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
-	nc, err := networkcontroller.New(nodeAddr)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
 	if err != nil {
 		return err
 	}
 	return nc.CreateOVSNetwork("system", bridgeName, phyIfaces, vlanTags)
 }
 
-func deleteOVSNetwork(nodeIP string, bridgeName string) error {
+func deleteOVSNetwork(sp *serviceprovider.Container, nodeIP string, bridgeName string) error {
 	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
-	nc, err := networkcontroller.New(nodeAddr)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
 	if err != nil {
 		return err
 	}
 	return nc.DeleteOVSNetwork(bridgeName)
 }
+
+// Create the OVS bridges of the system/netdev network on all nodes concurrently,
+// the bridges created on other nodes are deleted if any node fails.
+func createOVSNetworkOnNodes(sp *serviceprovider.Container, network entity.Network) ([]entity.NodeResult, error) {
+	nodeIPs, err := getNodeIPs(sp, network.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &transaction{}
+	return tx.run(network.Nodes, func(i int, node entity.Node) error {
+		return createOVSNode(sp, tx, network, node, nodeIPs[i], nodeIPs)
+	})
+}
+
+func createOVSNode(sp *serviceprovider.Container, tx *transaction, network entity.Network, node entity.Node, nodeIP string, nodeIPs []string) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return nodeError(node.Name, StepConnect, err)
+	}
+
+	//The datapath type is the same as the network type
+	if err := nc.CreateOVSBridge(string(network.Type), network.BridgeName); err != nil {
+		return nodeError(node.Name, StepCreateBridge, err)
+	}
+	//The ports and tunnels are deleted with the bridge
+	tx.add(node.Name, func() error {
+		return deleteOVSNetwork(sp, nodeIP, network.BridgeName)
+	})
+
+	if network.IsDPDKPort {
+		err = nc.AddOVSDPDKPorts(network.BridgeName, node.PhyInterfaces, network.VlanTags)
+	} else {
+		err = nc.AddOVSPorts(network.BridgeName, node.PhyInterfaces, network.VlanTags)
+	}
+	if err != nil {
+		return nodeError(node.Name, StepAddPorts, err)
+	}
+
+	if network.OverlayType != "" {
+		if err := createOVSTunnels(sp, nodeIP, network, nodeIPs); err != nil {
+			return nodeError(node.Name, StepCreateTunnels, err)
+		}
+	}
+	return nil
+}
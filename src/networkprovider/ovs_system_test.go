@@ -133,6 +133,7 @@ func TestOVSNetworkSuite(t *testing.T) {
 func (suite *OVSSystemNetworkTestSuite) TestCreateOVSNetwork() {
 	brName := namesgenerator.GetRandomName(0)
 	err := createOVSNetwork(
+		suite.sp,
 		OVS_LOCAL_IP,
 		brName,
 		[]entity.PhyInterface{},
@@ -147,7 +148,7 @@ func (suite *OVSSystemNetworkTestSuite) TestDeleteOVSNetwork() {
 	brName := namesgenerator.GetRandomName(0)
 	// ovs-vsctl add-br br0 -- set bridge br0 datapath_type=netdev
 	exec.Command("ovs-vsctl", "add-br", brName, "--", "set", "bridge", brName, "datapath_type=netdev").Run()
-	err := deleteOVSNetwork(OVS_LOCAL_IP, brName)
+	err := deleteOVSNetwork(suite.sp, OVS_LOCAL_IP, brName)
 	suite.NoError(err)
 }
 
@@ -165,7 +166,7 @@ func (suite *OVSSystemNetworkTestSuite) TestCreateNetwork() {
 			np, err := GetNetworkProvider(tc.network)
 			suite.NoError(err)
 			np = np.(kernelspaceNetworkProvider)
-			err = np.CreateNetwork(suite.sp)
+			_, err = np.CreateNetwork(suite.sp)
 			suite.NoError(err)
 			defer exec.Command("ovs-vsctl", "del-br", tc.network.BridgeName).Run()
 		})
@@ -186,7 +187,7 @@ func (suite *OVSSystemNetworkTestSuite) TestCreateNetworkFail() {
 	np, err := GetNetworkProvider(&network)
 	suite.NoError(err)
 	np = np.(kernelspaceNetworkProvider)
-	err = np.CreateNetwork(suite.sp)
+	_, err = np.CreateNetwork(suite.sp)
 	suite.Error(err)
 }
 
@@ -205,13 +206,13 @@ func (suite *OVSSystemNetworkTestSuite) TestDeleteNetwork() {
 			np, err := GetNetworkProvider(tc.network)
 			suite.NoError(err)
 			np = np.(kernelspaceNetworkProvider)
-			err = np.CreateNetwork(suite.sp)
+			_, err = np.CreateNetwork(suite.sp)
 			suite.NoError(err)
 
 			// ovs-vsctl add-br br0 -- set bridge br0 datapath_type=netdev
 			exec.Command("ovs-vsctl", "add-br", tc.network.BridgeName, "--", "set", "bridge", tc.network.BridgeName, "datapath_type=netdev").Run()
 			//FIXME we need a function to check the bridge is exist
-			err = np.DeleteNetwork(suite.sp)
+			_, err = np.DeleteNetwork(suite.sp)
 			suite.NoError(err)
 		})
 	}
@@ -231,6 +232,6 @@ func (suite *OVSSystemNetworkTestSuite) TestDeleteNetworkFail() {
 	np, err := GetNetworkProvider(&network)
 	suite.NoError(err)
 	np = np.(kernelspaceNetworkProvider)
-	err = np.DeleteNetwork(suite.sp)
+	_, err = np.DeleteNetwork(suite.sp)
 	suite.Error(err)
 }
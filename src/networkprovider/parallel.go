@@ -0,0 +1,71 @@
+package networkprovider
+
+import (
+	"sync"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+)
+
+// MaxParallelNodes is the max number of the nodes operated at the same time
+const MaxParallelNodes = 10
+
+// Run the operation on each node concurrently, at most MaxParallelNodes at the same time.
+// The results are in the same order as the nodes.
+func runOnNodes(nodes []entity.Node, op func(i int, node entity.Node) error) []entity.NodeResult {
+	results := make([]entity.NodeResult, len(nodes))
+	sem := make(chan struct{}, MaxParallelNodes)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, node entity.Node) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = newNodeResult(node.Name, op(i, node))
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+func newNodeResult(node string, err error) entity.NodeResult {
+	switch e := err.(type) {
+	case nil:
+		return entity.NodeResult{Node: node, Success: true}
+	case NodeError:
+		return entity.NodeResult{Node: node, Step: e.Step, Message: e.Message}
+	default:
+		return entity.NodeResult{Node: node, Message: err.Error()}
+	}
+}
+
+// Return the NetworkError of the first failed node, or nil if the operation succeeds on all nodes
+func resultsError(results []entity.NodeResult) error {
+	for _, r := range results {
+		if !r.Success {
+			return &NetworkError{
+				NodeError: NodeError{r.Node, r.Step, r.Message},
+				Results:   results,
+			}
+		}
+	}
+	return nil
+}
+
+// Delete the network on all nodes concurrently, it continues on other nodes even if some node fails
+func deleteOnNodes(sp *serviceprovider.Container, nodes []entity.Node, del func(nodeIP string, node entity.Node) error) ([]entity.NodeResult, error) {
+	results := runOnNodes(nodes, func(i int, node entity.Node) error {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return nodeError(node.Name, StepGetNodeIP, err)
+		}
+		if err := del(nodeIP, node); err != nil {
+			return nodeError(node.Name, StepDeleteNetwork, err)
+		}
+		return nil
+	})
+	return results, resultsError(results)
+}
@@ -0,0 +1,147 @@
+package networkprovider
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+)
+
+type sriovNetworkProvider struct {
+	entity.Network
+}
+
+// The init container of the pod finds the physical function by the node name,
+// so each node should have exactly one physical function with the virtual functions.
+func (snp sriovNetworkProvider) validateNodes() error {
+	for _, node := range snp.Nodes {
+		if len(node.PhyInterfaces) != 1 {
+			return fmt.Errorf("the sriov network needs exactly one physical interface on node %s", node.Name)
+		}
+		phyIface := node.PhyInterfaces[0]
+		if phyIface.PCIID == "" {
+			return fmt.Errorf("the PCI ID of the physical interface %s on node %s must not be empty", phyIface.Name, node.Name)
+		}
+		if phyIface.NumVFs <= 0 {
+			return fmt.Errorf("the number of virtual functions of the physical interface %s on node %s must be greater than 0", phyIface.Name, node.Name)
+		}
+	}
+	return nil
+}
+
+func (snp sriovNetworkProvider) CreateNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	if err := snp.validateNodes(); err != nil {
+		return nil, err
+	}
+
+	nodeIPs, err := getNodeIPs(sp, snp.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	//The virtual functions created on other nodes are released if any node fails
+	tx := &transaction{}
+	return tx.run(snp.Nodes, func(i int, node entity.Node) error {
+		nodeIP := nodeIPs[i]
+		if err := createSRIOVNetwork(sp, nodeIP, node.PhyInterfaces); err != nil {
+			return nodeError(node.Name, StepCreateVFs, err)
+		}
+		tx.add(node.Name, func() error {
+			return deleteSRIOVNetwork(sp, nodeIP, node.PhyInterfaces)
+		})
+		return nil
+	})
+}
+
+func (snp sriovNetworkProvider) UpdateNetwork(sp *serviceprovider.Container, network *entity.Network) error {
+	if err := (sriovNetworkProvider{*network}).validateNodes(); err != nil {
+		return err
+	}
+	diff := diffNetwork(&snp.Network, network)
+
+	for _, node := range diff.removedNodes {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return err
+		}
+		if err := deleteSRIOVNetwork(sp, nodeIP, node.PhyInterfaces); err != nil {
+			return err
+		}
+	}
+
+	//The virtual functions are re-created if the physical interface is changed
+	for _, node := range diff.keptNodes {
+		if len(diff.removedIfaces[node.Name]) == 0 && len(diff.addedIfaces[node.Name]) == 0 {
+			continue
+		}
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return err
+		}
+		if err := deleteSRIOVNetwork(sp, nodeIP, diff.removedIfaces[node.Name]); err != nil {
+			return err
+		}
+		if err := createSRIOVNetwork(sp, nodeIP, diff.addedIfaces[node.Name]); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range diff.addedNodes {
+		nodeIP, err := sp.KubeCtl.GetNodeInternalIP(node.Name)
+		if err != nil {
+			return err
+		}
+		if err := createSRIOVNetwork(sp, nodeIP, node.PhyInterfaces); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (snp sriovNetworkProvider) DeleteNetwork(sp *serviceprovider.Container) ([]entity.NodeResult, error) {
+	return deleteOnNodes(sp, snp.Nodes, func(nodeIP string, node entity.Node) error {
+		return deleteSRIOVNetwork(sp, nodeIP, node.PhyInterfaces)
+	})
+}
+
+func createSRIOVNetwork(sp *serviceprovider.Container, nodeIP string, phyIfaces []entity.PhyInterface) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return err
+	}
+	return nc.CreateSRIOVNetwork(phyIfaces)
+}
+
+func deleteSRIOVNetwork(sp *serviceprovider.Container, nodeIP string, phyIfaces []entity.PhyInterface) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return err
+	}
+	return nc.DeleteSRIOVNetwork(phyIfaces)
+}
+
+// GetFreeVFs will return the number of free virtual functions of the sriov network on each node
+// The virtual functions are used by the running pods and the pods of deployments which are attached to the network.
+func GetFreeVFs(sp *serviceprovider.Container, network *entity.Network) (map[string]int, error) {
+	free := map[string]int{}
+	for _, node := range network.Nodes {
+		for _, phyIface := range node.PhyInterfaces {
+			free[node.Name] += int(phyIface.NumVFs)
+		}
+	}
+
+	usage, err := GetNetworkUsage(sp, network)
+	if err != nil {
+		return nil, err
+	}
+	for nodeName, used := range usage {
+		if _, ok := free[nodeName]; ok {
+			free[nodeName] -= used
+		}
+	}
+	return free, nil
+}
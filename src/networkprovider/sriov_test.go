@@ -0,0 +1,112 @@
+package networkprovider
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/moby/moby/pkg/namesgenerator"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/mgo.v2/bson"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSRIOVNetworkValidateNodes(t *testing.T) {
+	testCases := []struct {
+		cases         string
+		phyInterfaces []entity.PhyInterface
+		valid         bool
+	}{
+		{"valid", []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 8}}, true},
+		{"noInterface", []entity.PhyInterface{}, false},
+		{"tooManyInterfaces", []entity.PhyInterface{
+			{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 8},
+			{Name: "eth2", PCIID: "0000:03:00.1", NumVFs: 8},
+		}, false},
+		{"noPCIID", []entity.PhyInterface{{Name: "eth1", NumVFs: 8}}, false},
+		{"noVFs", []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0"}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			provider := sriovNetworkProvider{
+				entity.Network{
+					Type: entity.SRIOVNetworkType,
+					Nodes: []entity.Node{
+						{
+							Name:          "node1",
+							PhyInterfaces: tc.phyInterfaces,
+						},
+					},
+				},
+			}
+			err := provider.validateNodes()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGetFreeVFs(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	networkName := namesgenerator.GetRandomName(0)
+	network := entity.Network{
+		ID:   bson.NewObjectId(),
+		Type: entity.SRIOVNetworkType,
+		Name: networkName,
+		Nodes: []entity.Node{
+			{
+				Name:          "node1",
+				PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 4}},
+			},
+			{
+				Name:          "node2",
+				PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 2}},
+			},
+		},
+	}
+
+	podName := namesgenerator.GetRandomName(0)
+	pod := entity.Pod{
+		ID:        bson.NewObjectId(),
+		Name:      podName,
+		Namespace: "default",
+		Networks: []entity.PodNetwork{
+			{Name: networkName, IfName: "eth1"},
+			{Name: networkName, IfName: "eth2"},
+		},
+	}
+	err := session.Insert(entity.PodCollectionName, pod)
+	assert.NoError(t, err)
+	defer session.Remove(entity.PodCollectionName, "name", podName)
+
+	_, err = sp.KubeCtl.CreatePod(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: podName,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node1",
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}, "default")
+	assert.NoError(t, err)
+	defer sp.KubeCtl.DeletePod(podName, "default")
+
+	free, err := GetFreeVFs(sp, &network)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, free["node1"])
+	assert.Equal(t, 2, free["node2"])
+}
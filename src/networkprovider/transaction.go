@@ -0,0 +1,116 @@
+package networkprovider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/linkernetworks/vortex/src/entity"
+)
+
+// These are the steps of the network operation on each node
+const (
+	StepGetNodeIP     = "getNodeIP"
+	StepConnect       = "connect"
+	StepCreateBridge  = "createBridge"
+	StepAddPorts      = "addPorts"
+	StepDeletePorts   = "deletePorts"
+	StepUpdatePorts   = "updatePorts"
+	StepCreateTunnels = "createTunnels"
+	StepDeleteTunnels = "deleteTunnels"
+	StepCreateVFs     = "createVFs"
+	StepDeleteNetwork = "deleteNetwork"
+	StepRollback      = "rollback"
+)
+
+// NodeError is the error of the step which fails on the node
+type NodeError struct {
+	Node    string `json:"node"`
+	Step    string `json:"step"`
+	Message string `json:"message"`
+}
+
+func (e NodeError) Error() string {
+	return fmt.Sprintf("%s on node %s fail: %s", e.Step, e.Node, e.Message)
+}
+
+func nodeError(node, step string, err error) error {
+	return NodeError{node, step, err.Error()}
+}
+
+// NetworkError is returned if the network operation fails on some node
+// The changes on other nodes are undone and the errors of undoing them are in the Rollback.
+// The Results has the outcome of the operation on each node.
+type NetworkError struct {
+	NodeError
+	Rollback []NodeError         `json:"rollback,omitempty"`
+	Results  []entity.NodeResult `json:"results,omitempty"`
+}
+
+func (e *NetworkError) Error() string {
+	if len(e.Rollback) == 0 {
+		return e.NodeError.Error()
+	}
+	msgs := []string{}
+	for _, v := range e.Rollback {
+		msgs = append(msgs, v.Error())
+	}
+	return fmt.Sprintf("%s, and %s", e.NodeError.Error(), strings.Join(msgs, ", "))
+}
+
+type undo struct {
+	node string
+	do   func() error
+}
+
+// transaction records how to undo the changes on the nodes,
+// so the network is created on all nodes or none of them.
+// The nodes are operated concurrently, so the undos are guarded by the mutex.
+type transaction struct {
+	mu    sync.Mutex
+	undos []undo
+}
+
+func (t *transaction) add(node string, do func() error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.undos = append(t.undos, undo{node, do})
+}
+
+// Undo the changes in the reverse order, it continues even if some undo fails
+func (t *transaction) rollback() []NodeError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	errs := []NodeError{}
+	for i := len(t.undos) - 1; i >= 0; i-- {
+		if err := t.undos[i].do(); err != nil {
+			errs = append(errs, NodeError{t.undos[i].node, StepRollback, err.Error()})
+		}
+	}
+	t.undos = nil
+	return errs
+}
+
+// Run the operation on all nodes concurrently, and rollback the transaction if any node fails
+// The returned error is the NetworkError of the first failed node.
+func (t *transaction) run(nodes []entity.Node, op func(i int, node entity.Node) error) ([]entity.NodeResult, error) {
+	results := runOnNodes(nodes, op)
+	err := resultsError(results)
+	if err == nil {
+		return results, nil
+	}
+
+	changed := map[string]bool{}
+	for _, u := range t.undos {
+		changed[u.node] = true
+	}
+	nerr := err.(*NetworkError)
+	nerr.Rollback = t.rollback()
+	for _, v := range nerr.Rollback {
+		changed[v.Node] = false
+	}
+	for i := range results {
+		results[i].RolledBack = changed[results[i].Node]
+	}
+	return results, nerr
+}
@@ -0,0 +1,96 @@
+package networkprovider
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linkernetworks/vortex/src/entity"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	undone := []string{}
+	tx := &transaction{}
+	nodes := []entity.Node{{Name: "node1"}, {Name: "node2"}, {Name: "node3"}}
+	results, err := tx.run(nodes, func(i int, node entity.Node) error {
+		if node.Name == "node3" {
+			return nodeError(node.Name, StepAddPorts, fmt.Errorf("eth1 doesn't exist"))
+		}
+		tx.add(node.Name, func() error {
+			undone = append(undone, node.Name)
+			if node.Name == "node2" {
+				return fmt.Errorf("node2 is unreachable")
+			}
+			return nil
+		})
+		return nil
+	})
+	assert.Error(t, err)
+	//All changes are undone even if some undo fails
+	assert.ElementsMatch(t, []string{"node1", "node2"}, undone)
+
+	nerr, ok := err.(*NetworkError)
+	assert.True(t, ok)
+	assert.Equal(t, "node3", nerr.Node)
+	assert.Equal(t, StepAddPorts, nerr.Step)
+	assert.Equal(t, []NodeError{{"node2", StepRollback, "node2 is unreachable"}}, nerr.Rollback)
+	assert.Equal(t, "addPorts on node node3 fail: eth1 doesn't exist, and rollback on node node2 fail: node2 is unreachable", nerr.Error())
+
+	assert.Equal(t, []entity.NodeResult{
+		{Node: "node1", Success: true, RolledBack: true},
+		{Node: "node2", Success: true},
+		{Node: "node3", Step: StepAddPorts, Message: "eth1 doesn't exist"},
+	}, results)
+	assert.Equal(t, results, nerr.Results)
+
+	//The transaction is empty after rollback
+	assert.Equal(t, 0, len(tx.rollback()))
+}
+
+func TestRunOnNodes(t *testing.T) {
+	nodes := []entity.Node{}
+	for i := 0; i < MaxParallelNodes*3; i++ {
+		nodes = append(nodes, entity.Node{Name: fmt.Sprintf("node%d", i)})
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	results := runOnNodes(nodes, func(i int, node entity.Node) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if i == 1 {
+			return fmt.Errorf("node1 is unreachable")
+		}
+		return nil
+	})
+
+	//The nodes are operated concurrently but no more than the limit
+	assert.True(t, maxRunning > 1)
+	assert.True(t, maxRunning <= MaxParallelNodes)
+
+	//The results are in the same order as the nodes
+	assert.Equal(t, len(nodes), len(results))
+	for i, r := range results {
+		assert.Equal(t, nodes[i].Name, r.Node)
+		assert.Equal(t, i != 1, r.Success)
+	}
+	assert.Equal(t, "node1 is unreachable", results[1].Message)
+
+	err := resultsError(results)
+	assert.Error(t, err)
+	assert.Equal(t, "node1", err.(*NetworkError).Node)
+	assert.NoError(t, resultsError(results[2:]))
+}
@@ -0,0 +1,234 @@
+package networkprovider
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+)
+
+// networkDiff is the difference between the stored network and the updated one
+type networkDiff struct {
+	addedNodes   []entity.Node
+	removedNodes []entity.Node
+	// The nodes in both networks, the physical interfaces are from the updated network
+	keptNodes []entity.Node
+	// The key is the node name of the kept nodes
+	addedIfaces   map[string][]entity.PhyInterface
+	removedIfaces map[string][]entity.PhyInterface
+	keptIfaces    map[string][]entity.PhyInterface
+
+	vlanTagsChanged bool
+}
+
+func diffNetwork(old, updated *entity.Network) networkDiff {
+	diff := networkDiff{
+		addedIfaces:   map[string][]entity.PhyInterface{},
+		removedIfaces: map[string][]entity.PhyInterface{},
+		keptIfaces:    map[string][]entity.PhyInterface{},
+	}
+
+	oldNodes := map[string]entity.Node{}
+	for _, node := range old.Nodes {
+		oldNodes[node.Name] = node
+	}
+	updatedNodes := map[string]entity.Node{}
+	for _, node := range updated.Nodes {
+		updatedNodes[node.Name] = node
+	}
+
+	for _, node := range old.Nodes {
+		if _, ok := updatedNodes[node.Name]; !ok {
+			diff.removedNodes = append(diff.removedNodes, node)
+		}
+	}
+	for _, node := range updated.Nodes {
+		oldNode, ok := oldNodes[node.Name]
+		if !ok {
+			diff.addedNodes = append(diff.addedNodes, node)
+			continue
+		}
+		diff.keptNodes = append(diff.keptNodes, node)
+
+		//The physical interface is replaced if any field of it is changed
+		oldIfaces := map[string]entity.PhyInterface{}
+		for _, phyIface := range oldNode.PhyInterfaces {
+			oldIfaces[phyIface.Name] = phyIface
+		}
+		updatedIfaces := map[string]entity.PhyInterface{}
+		for _, phyIface := range node.PhyInterfaces {
+			updatedIfaces[phyIface.Name] = phyIface
+		}
+		for _, phyIface := range oldNode.PhyInterfaces {
+			if updatedIface, ok := updatedIfaces[phyIface.Name]; !ok || updatedIface != phyIface {
+				diff.removedIfaces[node.Name] = append(diff.removedIfaces[node.Name], phyIface)
+			}
+		}
+		for _, phyIface := range node.PhyInterfaces {
+			if oldIface, ok := oldIfaces[phyIface.Name]; !ok || oldIface != phyIface {
+				diff.addedIfaces[node.Name] = append(diff.addedIfaces[node.Name], phyIface)
+			} else {
+				diff.keptIfaces[node.Name] = append(diff.keptIfaces[node.Name], phyIface)
+			}
+		}
+	}
+
+	diff.vlanTagsChanged = !sameVlanTags(old.VlanTags, updated.VlanTags)
+	return diff
+}
+
+// ValidateNetworkUpdate will check the fields which can't be changed after the network is created
+func ValidateNetworkUpdate(old, updated *entity.Network) error {
+	switch {
+	case old.Name != updated.Name:
+		return fmt.Errorf("the name of network %s can't be changed", old.Name)
+	case old.Type != updated.Type:
+		return fmt.Errorf("the type of network %s can't be changed", old.Name)
+	case old.IsDPDKPort != updated.IsDPDKPort:
+		return fmt.Errorf("the isDPDKPort of network %s can't be changed", old.Name)
+	case old.OverlayType != updated.OverlayType:
+		return fmt.Errorf("the overlayType of network %s can't be changed", old.Name)
+	}
+	return nil
+}
+
+// Update the OVS bridges of the network by the delta on all nodes concurrently, the changes are undone if any node fails.
+// The createNode creates the bridge of the network on the node, it re-creates the bridges of the removed nodes to undo,
+// so they're deleted after the other nodes are updated.
+func updateOVSNetwork(sp *serviceprovider.Container, old, updated *entity.Network, createNode func(nodeIP string, network *entity.Network, node entity.Node) error) error {
+	diff := diffNetwork(old, updated)
+
+	nodeIPs, err := getNodeIPs(sp, updated.Nodes)
+	if err != nil {
+		return err
+	}
+	removedIPs, err := getNodeIPs(sp, diff.removedNodes)
+	if err != nil {
+		return err
+	}
+	oldIPs, err := getNodeIPs(sp, old.Nodes)
+	if err != nil {
+		return err
+	}
+	ips := map[string]string{}
+	for i, node := range updated.Nodes {
+		ips[node.Name] = nodeIPs[i]
+	}
+	added := map[string]bool{}
+	for _, node := range diff.addedNodes {
+		added[node.Name] = true
+	}
+
+	tx := &transaction{}
+	if _, err := tx.run(updated.Nodes, func(i int, node entity.Node) error {
+		if added[node.Name] {
+			if err := createNode(nodeIPs[i], updated, node); err != nil {
+				return nodeError(node.Name, StepCreateBridge, err)
+			}
+			tx.add(node.Name, func() error {
+				return deleteOVSNetwork(sp, nodeIPs[i], updated.BridgeName)
+			})
+			if updated.OverlayType != "" {
+				if err := createOVSTunnels(sp, nodeIPs[i], *updated, nodeIPs); err != nil {
+					return nodeError(node.Name, StepCreateTunnels, err)
+				}
+			}
+			return nil
+		}
+		return updateOVSNode(sp, tx, old, updated, &diff, node, nodeIPs[i], ips, removedIPs)
+	}); err != nil {
+		return err
+	}
+
+	_, err = tx.run(diff.removedNodes, func(i int, node entity.Node) error {
+		if err := deleteOVSNetwork(sp, removedIPs[i], old.BridgeName); err != nil {
+			return nodeError(node.Name, StepDeleteNetwork, err)
+		}
+		tx.add(node.Name, func() error {
+			if err := createNode(removedIPs[i], old, node); err != nil {
+				return err
+			}
+			if old.OverlayType != "" {
+				return createOVSTunnels(sp, removedIPs[i], *old, oldIPs)
+			}
+			return nil
+		})
+		return nil
+	})
+	return err
+}
+
+// Update the ports and tunnels of the bridge on the node which is kept in the network
+// The removed ports are deleted before the added ones since the changed physical interface is removed and added again.
+func updateOVSNode(sp *serviceprovider.Container, tx *transaction, old, updated *entity.Network, diff *networkDiff, node entity.Node, nodeIP string, ips map[string]string, removedIPs []string) error {
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return nodeError(node.Name, StepConnect, err)
+	}
+
+	for _, phyIface := range diff.removedIfaces[node.Name] {
+		if err := nc.DeleteOVSPort(updated.BridgeName, phyIface.Name); err != nil {
+			return nodeError(node.Name, StepDeletePorts, err)
+		}
+		removed := []entity.PhyInterface{phyIface}
+		tx.add(node.Name, func() error {
+			return addOVSPorts(nc, old, removed)
+		})
+	}
+	if added := diff.addedIfaces[node.Name]; len(added) > 0 {
+		if err := addOVSPorts(nc, updated, added); err != nil {
+			return nodeError(node.Name, StepAddPorts, err)
+		}
+		tx.add(node.Name, func() error {
+			for _, phyIface := range added {
+				if err := nc.DeleteOVSPort(updated.BridgeName, phyIface.Name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if kept := diff.keptIfaces[node.Name]; diff.vlanTagsChanged && len(kept) > 0 {
+		if err := nc.SetOVSPortsTrunk(kept, updated.VlanTags); err != nil {
+			return nodeError(node.Name, StepUpdatePorts, err)
+		}
+		tx.add(node.Name, func() error {
+			return nc.SetOVSPortsTrunk(kept, old.VlanTags)
+		})
+	}
+
+	if updated.OverlayType == "" {
+		return nil
+	}
+	for _, remoteIP := range removedIPs {
+		tunnelName := GenerateTunnelName(updated.OverlayType, updated.BridgeName, remoteIP)
+		if err := nc.DeleteOVSPort(updated.BridgeName, tunnelName); err != nil {
+			return nodeError(node.Name, StepDeleteTunnels, err)
+		}
+		remoteIP := remoteIP
+		tx.add(node.Name, func() error {
+			return nc.AddOVSTunnelPort(old.BridgeName, tunnelName, old.OverlayType, remoteIP, old.VNI)
+		})
+	}
+	for _, addedNode := range diff.addedNodes {
+		remoteIP := ips[addedNode.Name]
+		tunnelName := GenerateTunnelName(updated.OverlayType, updated.BridgeName, remoteIP)
+		if err := nc.AddOVSTunnelPort(updated.BridgeName, tunnelName, updated.OverlayType, remoteIP, updated.VNI); err != nil {
+			return nodeError(node.Name, StepCreateTunnels, err)
+		}
+		tx.add(node.Name, func() error {
+			return nc.DeleteOVSPort(updated.BridgeName, tunnelName)
+		})
+	}
+	return nil
+}
+
+func addOVSPorts(nc *networkcontroller.NetworkController, network *entity.Network, phyIfaces []entity.PhyInterface) error {
+	if network.IsDPDKPort {
+		return nc.AddOVSDPDKPorts(network.BridgeName, phyIfaces, network.VlanTags)
+	}
+	return nc.AddOVSPorts(network.BridgeName, phyIfaces, network.VlanTags)
+}
@@ -0,0 +1,65 @@
+package networkprovider
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffNetwork(t *testing.T) {
+	old := &entity.Network{
+		VlanTags: []int32{100, 200},
+		Nodes: []entity.Node{
+			{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}, {Name: "eth2"}}},
+			{Name: "node2", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}},
+		},
+	}
+	updated := &entity.Network{
+		VlanTags: []int32{200, 100},
+		Nodes: []entity.Node{
+			{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}, {Name: "eth3"}}},
+			{Name: "node3", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}},
+		},
+	}
+
+	diff := diffNetwork(old, updated)
+	assert.Equal(t, []entity.Node{updated.Nodes[1]}, diff.addedNodes)
+	assert.Equal(t, []entity.Node{old.Nodes[1]}, diff.removedNodes)
+	assert.Equal(t, []entity.Node{updated.Nodes[0]}, diff.keptNodes)
+	assert.Equal(t, []entity.PhyInterface{{Name: "eth3"}}, diff.addedIfaces["node1"])
+	assert.Equal(t, []entity.PhyInterface{{Name: "eth2"}}, diff.removedIfaces["node1"])
+	assert.Equal(t, []entity.PhyInterface{{Name: "eth1"}}, diff.keptIfaces["node1"])
+	assert.False(t, diff.vlanTagsChanged)
+
+	updated.VlanTags = []int32{100}
+	diff = diffNetwork(old, updated)
+	assert.True(t, diff.vlanTagsChanged)
+}
+
+func TestDiffNetworkReplaceInterface(t *testing.T) {
+	old := &entity.Network{
+		Nodes: []entity.Node{
+			{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 4}}},
+		},
+	}
+	updated := &entity.Network{
+		Nodes: []entity.Node{
+			{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 8}}},
+		},
+	}
+
+	diff := diffNetwork(old, updated)
+	assert.Equal(t, old.Nodes[0].PhyInterfaces, diff.removedIfaces["node1"])
+	assert.Equal(t, updated.Nodes[0].PhyInterfaces, diff.addedIfaces["node1"])
+	assert.Equal(t, 0, len(diff.keptIfaces["node1"]))
+}
+
+func TestValidateNetworkUpdate(t *testing.T) {
+	old := &entity.Network{Name: "net", Type: entity.OVSKernelspaceNetworkType}
+
+	assert.NoError(t, ValidateNetworkUpdate(old, &entity.Network{Name: "net", Type: entity.OVSKernelspaceNetworkType, VlanTags: []int32{100}}))
+	assert.Error(t, ValidateNetworkUpdate(old, &entity.Network{Name: "net2", Type: entity.OVSKernelspaceNetworkType}))
+	assert.Error(t, ValidateNetworkUpdate(old, &entity.Network{Name: "net", Type: entity.OVSUserspaceNetworkType}))
+	assert.Error(t, ValidateNetworkUpdate(old, &entity.Network{Name: "net", Type: entity.OVSKernelspaceNetworkType, OverlayType: entity.OverlayVXLAN}))
+}
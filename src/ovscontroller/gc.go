@@ -0,0 +1,161 @@
+package ovscontroller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/network-controller/utils"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+
+	"gopkg.in/mgo.v2/bson"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PortGCInterval is the interval to collect the orphaned ports of the OVS networks
+const PortGCInterval = 5 * time.Minute
+
+// The --nic flag of the network controller client in the init containers is the interface name of the veth in the pod
+var nicFlagRegexp = regexp.MustCompile(`--nic=([^\s"']+)`)
+
+// The veth of the pod which doesn't exist anymore is orphaned.
+// Only the veth ports are collected, the uplinks, tunnels and the ports added by the admin are kept.
+func isOrphanedPort(port entity.OVSNetworkPort) bool {
+	return port.Type == entity.OVSUnknownPortType && strings.HasPrefix(port.Name, "veth")
+}
+
+func portKey(port entity.OVSPortGCEntry) string {
+	return port.NodeName + "/" + port.BridgeName + "/" + port.PortName
+}
+
+// FindOrphanedPorts will dump the ports of the OVS networks on all their nodes and return the veth ports without the live pods
+func FindOrphanedPorts(sp *serviceprovider.Container) (*entity.OVSPortGCResult, error) {
+	inventory, err := ListPorts(sp, PortFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.OVSPortGCResult{
+		Ports:  []entity.OVSPortGCEntry{},
+		Errors: inventory.Errors,
+	}
+	for _, port := range inventory.Ports {
+		if !isOrphanedPort(port) {
+			continue
+		}
+		result.Ports = append(result.Ports, entity.OVSPortGCEntry{
+			NetworkName: port.NetworkName,
+			NodeName:    port.NodeName,
+			BridgeName:  port.BridgeName,
+			PortName:    port.Name,
+		})
+	}
+	return result, nil
+}
+
+// liveVeths maps the veth names of the live pods to the pods, including the pods which vortex doesn't track
+// The veth name is generated by the pod UID and the interface name given to the network controller client by the init containers.
+func liveVeths(pods []*corev1.Pod) map[string]string {
+	veths := map[string]string{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.InitContainers {
+			args := strings.Join(append(append([]string{}, container.Command...), container.Args...), " ")
+			for _, match := range nicFlagRegexp.FindAllStringSubmatch(args, -1) {
+				veths[utils.GenerateVethName(string(pod.UID), match[1])] = pod.Namespace + "/" + pod.Name
+			}
+		}
+	}
+	return veths
+}
+
+// RemoveOrphanedPorts will remove the ports through the network controller and save each removal as the audit entry
+// The ports which can't be removed are saved with the error as well, they'll be collected again in the next run.
+// The live pods are listed again before the removal, the ports of them are kept even if their records aren't saved yet.
+func RemoveOrphanedPorts(sp *serviceprovider.Container, ports []entity.OVSPortGCEntry) error {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	pods, err := sp.KubeCtl.GetPods("")
+	if err != nil {
+		return err
+	}
+	veths := liveVeths(pods)
+
+	for i := range ports {
+		port := &ports[i]
+		if pod, ok := veths[port.PortName]; ok {
+			port.Error = fmt.Sprintf("the port belongs to the live pod %s", pod)
+			logger.Infof("keep the port %s of bridge %s on node %s of the live pod %s", port.PortName, port.BridgeName, port.NodeName, pod)
+			continue
+		}
+		if err := removePort(sp, port); err != nil {
+			port.Error = err.Error()
+			logger.Warnf("remove the orphaned port %s of bridge %s on node %s fail: %v", port.PortName, port.BridgeName, port.NodeName, err)
+		} else {
+			port.Removed = true
+			logger.Infof("remove the orphaned port %s of bridge %s on node %s", port.PortName, port.BridgeName, port.NodeName)
+		}
+
+		now := time.Now()
+		port.ID = bson.NewObjectId()
+		port.CreatedAt = &now
+		if err := session.Insert(entity.OVSPortGCCollectionName, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removePort(sp *serviceprovider.Container, port *entity.OVSPortGCEntry) error {
+	nc, err := getNetworkController(sp, port.NodeName)
+	if err != nil {
+		return err
+	}
+	return nc.DeleteOVSPort(port.BridgeName, port.PortName)
+}
+
+// CollectOrphanedPorts will remove the orphaned ports periodically
+// The veth may be added to the bridge before the pod is saved, so the port is only removed
+// if it's still orphaned in the next run.
+func CollectOrphanedPorts(sp *serviceprovider.Container, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	suspects := map[string]bool{}
+	for range ticker.C {
+		result, err := FindOrphanedPorts(sp)
+		if err != nil {
+			logger.Warnf("find the orphaned ovs ports fail: %v", err)
+			continue
+		}
+
+		var orphans []entity.OVSPortGCEntry
+		suspects, orphans = confirmOrphans(suspects, result.Ports)
+		if len(orphans) == 0 {
+			continue
+		}
+		if err := RemoveOrphanedPorts(sp, orphans); err != nil {
+			logger.Warnf("save the audit entries of the orphaned ovs ports fail: %v", err)
+		}
+	}
+}
+
+// confirmOrphans will return the ports found in this run as the new suspects and the ports found in both runs
+func confirmOrphans(suspects map[string]bool, ports []entity.OVSPortGCEntry) (map[string]bool, []entity.OVSPortGCEntry) {
+	next := map[string]bool{}
+	orphans := []entity.OVSPortGCEntry{}
+	for _, port := range ports {
+		key := portKey(port)
+		next[key] = true
+		if suspects[key] {
+			orphans = append(orphans, port)
+		}
+	}
+	return next, orphans
+}
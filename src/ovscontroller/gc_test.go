@@ -0,0 +1,60 @@
+package ovscontroller
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/network-controller/utils"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsOrphanedPort(t *testing.T) {
+	port := func(name string, portType entity.OVSPortType) entity.OVSNetworkPort {
+		return entity.OVSNetworkPort{OVSPortInfo: entity.OVSPortInfo{Name: name, Type: portType}}
+	}
+	assert.True(t, isOrphanedPort(port("veth7e3a8c21", entity.OVSUnknownPortType)))
+	assert.False(t, isOrphanedPort(port("veth7e3a8c21", entity.OVSPodPortType)))
+	assert.False(t, isOrphanedPort(port("eth1", entity.OVSUnknownPortType)))
+	assert.False(t, isOrphanedPort(port("eth1", entity.OVSUplinkPortType)))
+}
+
+func TestConfirmOrphans(t *testing.T) {
+	a := entity.OVSPortGCEntry{NodeName: "node1", BridgeName: "system-47f8ce", PortName: "veth7e3a8c21"}
+	b := entity.OVSPortGCEntry{NodeName: "node2", BridgeName: "system-47f8ce", PortName: "veth7e3a8c21"}
+
+	//The ports are only suspects in the first run
+	suspects, orphans := confirmOrphans(map[string]bool{}, []entity.OVSPortGCEntry{a})
+	assert.Len(t, orphans, 0)
+
+	suspects, orphans = confirmOrphans(suspects, []entity.OVSPortGCEntry{a, b})
+	assert.Equal(t, []entity.OVSPortGCEntry{a}, orphans)
+
+	//The port has gone away, it's not a suspect anymore
+	suspects, orphans = confirmOrphans(suspects, []entity.OVSPortGCEntry{b})
+	assert.Equal(t, []entity.OVSPortGCEntry{b}, orphans)
+	assert.Equal(t, map[string]bool{portKey(b): true}, suspects)
+}
+
+func TestLiveVeths(t *testing.T) {
+	pod := func(name string, phase corev1.PodPhase, args ...string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: "uid-" + name},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Args: args}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	veths := liveVeths([]*corev1.Pod{
+		pod("a", corev1.PodRunning, "--bridge=system-47f8ce", "--nic=eth1"),
+		//The args of the ipam init container are the shell script
+		pod("b", corev1.PodPending, "ip=$(wget -qO- http://vortex/v1/ipam/leases) && /go/bin/client --nic=eth2 --ip=$ip"),
+		pod("c", corev1.PodSucceeded, "--nic=eth1"),
+	})
+	assert.Equal(t, map[string]string{
+		utils.GenerateVethName("uid-a", "eth1"): "default/a",
+		utils.GenerateVethName("uid-b", "eth2"): "default/b",
+	}, veths)
+}
@@ -0,0 +1,155 @@
+package ovscontroller
+
+import (
+	"time"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/mongo"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// These are the settings of the port stats history
+const (
+	// PortStatsInterval is the interval to poll the port stats of the OVS networks
+	PortStatsInterval = 30 * time.Second
+	// PortStatsRetention is how long the samples are kept, they're removed by the TTL index of mongodb
+	PortStatsRetention = 24 * time.Hour
+)
+
+func ensurePortStatsIndex(session *mongo.Session) error {
+	c := session.C(entity.OVSPortStatsCollectionName)
+	if err := c.EnsureIndex(mgo.Index{
+		Key:         []string{"createdAt"},
+		ExpireAfter: PortStatsRetention,
+	}); err != nil {
+		return err
+	}
+	return c.EnsureIndex(mgo.Index{
+		Key: []string{"namespace", "podName", "interfaceName", "createdAt"},
+	})
+}
+
+// CollectPortStats will dump the ports of the OVS networks on each node and save the counters as the samples
+func CollectPortStats(sp *serviceprovider.Container) (int, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	if err := ensurePortStatsIndex(session); err != nil {
+		return 0, err
+	}
+
+	networks := []entity.Network{}
+	q := bson.M{"type": bson.M{"$in": []entity.NetworkType{entity.OVSKernelspaceNetworkType, entity.OVSUserspaceNetworkType}}}
+	if err := session.FindAll(entity.NetworkCollectionName, q, &networks); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	now := time.Now()
+	for _, network := range networks {
+		for _, node := range network.Nodes {
+			//The node may be unreachable, the other nodes are still polled
+			ports, err := DumpPorts(sp, node.Name, network.BridgeName)
+			if err != nil {
+				logger.Warnf("dump the ports of bridge %s on node %s fail: %v", network.BridgeName, node.Name, err)
+				continue
+			}
+			for _, port := range ports {
+				if err := session.Insert(entity.OVSPortStatsCollectionName, &entity.OVSPortStatsSample{
+					ID:            bson.NewObjectId(),
+					NodeName:      node.Name,
+					BridgeName:    network.BridgeName,
+					PortName:      port.Name,
+					PodName:       port.PodName,
+					Namespace:     port.Namespace,
+					InterfaceName: port.InterfaceName,
+					Received:      port.Received,
+					Transmitted:   port.Transmitted,
+					CreatedAt:     now,
+				}); err != nil {
+					return count, err
+				}
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// PollPortStats will collect the port stats periodically
+func PollPortStats(sp *serviceprovider.Container, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := CollectPortStats(sp); err != nil {
+			logger.Warnf("collect the ovs port stats fail: %v", err)
+		}
+	}
+}
+
+// GetPortHistory will return the rates of the ports matched by the query since the time
+func GetPortHistory(session *mongo.Session, q bson.M, since time.Time) ([]entity.OVSPortHistory, error) {
+	q["createdAt"] = bson.M{"$gte": since}
+	samples := []entity.OVSPortStatsSample{}
+	if err := session.C(entity.OVSPortStatsCollectionName).Find(q).Sort("createdAt").All(&samples); err != nil {
+		return nil, err
+	}
+	return portHistory(samples), nil
+}
+
+// The samples are sorted by the time, the rates are calculated between the consecutive samples of the same port
+func portHistory(samples []entity.OVSPortStatsSample) []entity.OVSPortHistory {
+	histories := []entity.OVSPortHistory{}
+	index := map[string]int{}
+	last := map[string]entity.OVSPortStatsSample{}
+	for _, sample := range samples {
+		key := sample.NodeName + "/" + sample.BridgeName + "/" + sample.PortName
+		i, ok := index[key]
+		if !ok {
+			i = len(histories)
+			index[key] = i
+			histories = append(histories, entity.OVSPortHistory{
+				NodeName:      sample.NodeName,
+				BridgeName:    sample.BridgeName,
+				PortName:      sample.PortName,
+				PodName:       sample.PodName,
+				Namespace:     sample.Namespace,
+				InterfaceName: sample.InterfaceName,
+				Points:        []entity.OVSPortRatesPoint{},
+			})
+		}
+
+		prev, ok := last[key]
+		last[key] = sample
+		if !ok {
+			continue
+		}
+		seconds := sample.CreatedAt.Sub(prev.CreatedAt).Seconds()
+		//The counters are reset if the port is re-created
+		if seconds <= 0 || isReset(prev.Received, sample.Received) || isReset(prev.Transmitted, sample.Transmitted) {
+			continue
+		}
+		histories[i].Points = append(histories[i].Points, entity.OVSPortRatesPoint{
+			Timestamp:   sample.CreatedAt,
+			Received:    portRates(prev.Received, sample.Received, seconds),
+			Transmitted: portRates(prev.Transmitted, sample.Transmitted, seconds),
+		})
+	}
+	return histories
+}
+
+func isReset(prev, cur entity.OVSPortStats) bool {
+	return cur.Bytes < prev.Bytes || cur.Packets < prev.Packets || cur.Dropped < prev.Dropped || cur.Errors < prev.Errors
+}
+
+func portRates(prev, cur entity.OVSPortStats, seconds float64) entity.OVSPortRates {
+	return entity.OVSPortRates{
+		BytesPerSec:   float64(cur.Bytes-prev.Bytes) / seconds,
+		PacketsPerSec: float64(cur.Packets-prev.Packets) / seconds,
+		DroppedPerSec: float64(cur.Dropped-prev.Dropped) / seconds,
+		ErrorsPerSec:  float64(cur.Errors-prev.Errors) / seconds,
+	}
+}
@@ -0,0 +1,51 @@
+package ovscontroller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortHistory(t *testing.T) {
+	now := time.Now()
+	sample := func(port string, seconds int, bytes, packets uint64) entity.OVSPortStatsSample {
+		return entity.OVSPortStatsSample{
+			NodeName:    "node1",
+			BridgeName:  "br0",
+			PortName:    port,
+			Received:    entity.OVSPortStats{Bytes: bytes, Packets: packets},
+			Transmitted: entity.OVSPortStats{Bytes: bytes * 2, Packets: packets},
+			CreatedAt:   now.Add(time.Duration(seconds) * time.Second),
+		}
+	}
+
+	histories := portHistory([]entity.OVSPortStatsSample{
+		sample("veth1", 0, 1000, 10),
+		sample("veth2", 0, 0, 0),
+		sample("veth1", 10, 2000, 20),
+		//The counters are reset
+		sample("veth1", 20, 500, 5),
+		sample("veth1", 30, 1500, 15),
+	})
+
+	assert.Equal(t, 2, len(histories))
+	assert.Equal(t, "veth1", histories[0].PortName)
+	assert.Equal(t, []entity.OVSPortRatesPoint{
+		{
+			Timestamp:   now.Add(10 * time.Second),
+			Received:    entity.OVSPortRates{BytesPerSec: 100, PacketsPerSec: 1},
+			Transmitted: entity.OVSPortRates{BytesPerSec: 200, PacketsPerSec: 1},
+		},
+		{
+			Timestamp:   now.Add(30 * time.Second),
+			Received:    entity.OVSPortRates{BytesPerSec: 100, PacketsPerSec: 1},
+			Transmitted: entity.OVSPortRates{BytesPerSec: 200, PacketsPerSec: 1},
+		},
+	}, histories[0].Points)
+
+	//There's no rate for the single sample
+	assert.Equal(t, "veth2", histories[1].PortName)
+	assert.Equal(t, 0, len(histories[1].Points))
+}
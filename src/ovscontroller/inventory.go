@@ -0,0 +1,108 @@
+package ovscontroller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+
+	"gopkg.in/mgo.v2/bson"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MaxParallelDumps is the max number of the bridges dumped at the same time
+const MaxParallelDumps = 10
+
+// PortFilter selects the ports of the inventory, the empty field matches all ports
+type PortFilter struct {
+	PodName     string
+	Namespace   string
+	NetworkName string
+}
+
+func (f PortFilter) match(port entity.OVSPortInfo) bool {
+	return (f.PodName == "" || port.PodName == f.PodName) &&
+		(f.Namespace == "" || port.Namespace == f.Namespace)
+}
+
+// ListPorts will dump the ports of the OVS networks on all their nodes and return the ports matched by the filter
+// If both the pod name and namespace are given, only the node which the pod is scheduled to is dumped.
+func ListPorts(sp *serviceprovider.Container, filter PortFilter) (*entity.OVSPortInventory, error) {
+	inventory := &entity.OVSPortInventory{Ports: []entity.OVSNetworkPort{}}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	networks := []entity.Network{}
+	q := bson.M{"type": bson.M{"$in": []entity.NetworkType{entity.OVSKernelspaceNetworkType, entity.OVSUserspaceNetworkType}}}
+	if filter.NetworkName != "" {
+		q["name"] = filter.NetworkName
+	}
+	if err := session.FindAll(entity.NetworkCollectionName, q, &networks); err != nil {
+		return nil, err
+	}
+
+	podNode := ""
+	if filter.PodName != "" && filter.Namespace != "" {
+		pod, err := sp.KubeCtl.GetPod(filter.PodName, filter.Namespace)
+		if errors.IsNotFound(err) {
+			return inventory, nil
+		} else if err != nil {
+			return nil, err
+		}
+		podNode = pod.Spec.NodeName
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, MaxParallelDumps)
+	for _, network := range networks {
+		for _, node := range network.Nodes {
+			if podNode != "" && node.Name != podNode {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(networkName, nodeName, bridgeName string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				ports, err := DumpPorts(sp, nodeName, bridgeName)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					inventory.Errors = append(inventory.Errors, fmt.Sprintf("dump the ports of network %s on node %s fail: %v", networkName, nodeName, err))
+					return
+				}
+				for _, port := range ports {
+					if filter.match(port) {
+						inventory.Ports = append(inventory.Ports, entity.OVSNetworkPort{
+							NetworkName: networkName,
+							NodeName:    nodeName,
+							BridgeName:  bridgeName,
+							OVSPortInfo: port,
+						})
+					}
+				}
+			}(network.Name, node.Name, network.BridgeName)
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(inventory.Ports, func(i, j int) bool {
+		a, b := inventory.Ports[i], inventory.Ports[j]
+		if a.NetworkName != b.NetworkName {
+			return a.NetworkName < b.NetworkName
+		}
+		if a.NodeName != b.NodeName {
+			return a.NodeName < b.NodeName
+		}
+		return a.PortID < b.PortID
+	})
+	sort.Strings(inventory.Errors)
+	return inventory, nil
+}
@@ -0,0 +1,21 @@
+package ovscontroller
+
+import (
+	"testing"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortFilter(t *testing.T) {
+	port := entity.OVSPortInfo{Name: "veth7e3a8c21", PodName: "mypod", Namespace: "default", InterfaceName: "eth1"}
+	uplink := entity.OVSPortInfo{Name: "eth1"}
+
+	assert.True(t, PortFilter{}.match(port))
+	assert.True(t, PortFilter{}.match(uplink))
+	assert.True(t, PortFilter{PodName: "mypod", Namespace: "default"}.match(port))
+	assert.True(t, PortFilter{Namespace: "default"}.match(port))
+	assert.False(t, PortFilter{Namespace: "default"}.match(uplink))
+	assert.False(t, PortFilter{PodName: "mypod", Namespace: "vortex"}.match(port))
+	assert.False(t, PortFilter{PodName: "other"}.match(port))
+}
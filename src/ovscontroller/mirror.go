@@ -0,0 +1,240 @@
+package ovscontroller
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/linkernetworks/logger"
+	"github.com/linkernetworks/network-controller/utils"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	vortexutils "github.com/linkernetworks/vortex/src/utils"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/mgo.v2/bson"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// These are the settings of the mirrors
+const (
+	// DefaultMirrorTTL is the TTL in minutes if it's not set
+	DefaultMirrorTTL = 30
+	// MirrorReapInterval is the interval to remove the expired mirrors
+	MirrorReapInterval = time.Minute
+)
+
+// GenerateMirrorName will generate the name of the OVS mirror by the mirror ID
+func GenerateMirrorName(id bson.ObjectId) string {
+	return fmt.Sprintf("mirror-%s", vortexutils.SHA256String(id.Hex())[0:8])
+}
+
+// ValidateMirror will check the fields of the mirror which depend on each other
+func ValidateMirror(network *entity.Network, mirror *entity.Mirror) error {
+	if network.Type != entity.OVSKernelspaceNetworkType && network.Type != entity.OVSUserspaceNetworkType {
+		return fmt.Errorf("the mirror is unsupported for the network type %s", network.Type)
+	}
+	found := false
+	for _, node := range network.Nodes {
+		if node.Name == mirror.NodeName {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("the node %s isn't in the network %s", mirror.NodeName, network.Name)
+	}
+	if len(mirror.Sources) == 0 && len(mirror.VlanTags) == 0 {
+		return fmt.Errorf("the sources or vlanTags of the mirror are required")
+	}
+	if (mirror.OutputPort == "") == (mirror.OutputPod == nil) {
+		return fmt.Errorf("one of the outputPort and outputPod of the mirror is required")
+	}
+	//The mirrored traffic would be sent out of the node or to the other nodes
+	if mirror.OutputPort != "" {
+		switch classifyPort([]entity.Network{*network}, mirror.NodeName, network.BridgeName, mirror.OutputPort) {
+		case entity.OVSUplinkPortType, entity.OVSTunnelPortType, entity.OVSInternalPortType:
+			return fmt.Errorf("the outputPort %s is the uplink, tunnel or internal port of the network %s", mirror.OutputPort, network.Name)
+		}
+	}
+	if mirror.OutputPod != nil {
+		for _, source := range mirror.Sources {
+			if source == *mirror.OutputPod {
+				return fmt.Errorf("the outputPod %s/%s can't be one of the sources", source.Namespace, source.PodName)
+			}
+		}
+	}
+	return nil
+}
+
+// MirrorPortError is returned if the output port of the mirror isn't on the bridge or is one of the sources
+type MirrorPortError struct {
+	PortName string
+	Reason   string
+}
+
+func (e *MirrorPortError) Error() string {
+	return fmt.Sprintf("the port %s can't be the output of the mirror: %s", e.PortName, e.Reason)
+}
+
+// checkOutputPort will check the output port is on the bridge and isn't mirrored itself
+func checkOutputPort(bridgePorts []string, sources []string, outputPort string) error {
+	for _, port := range sources {
+		if port == outputPort {
+			return &MirrorPortError{outputPort, "the port is one of the sources"}
+		}
+	}
+	for _, port := range bridgePorts {
+		if port == outputPort {
+			return nil
+		}
+	}
+	return &MirrorPortError{outputPort, "the port isn't on the bridge"}
+}
+
+// MirrorPodError is returned if the pod of the mirror doesn't exist or isn't on the node of the mirror
+type MirrorPodError struct {
+	PodName   string
+	Namespace string
+	Reason    string
+}
+
+func (e *MirrorPodError) Error() string {
+	return fmt.Sprintf("the pod %s/%s can't be mirrored: %s", e.Namespace, e.PodName, e.Reason)
+}
+
+// The interface of the pod is the veth on the bridge, the pod should be on the node of the mirror
+func resolvePodPort(sp *serviceprovider.Container, nodeName string, iface entity.MirrorPodInterface) (string, error) {
+	pod, err := sp.KubeCtl.GetPod(iface.PodName, iface.Namespace)
+	if errors.IsNotFound(err) {
+		return "", &MirrorPodError{iface.PodName, iface.Namespace, "the pod doesn't exist"}
+	} else if err != nil {
+		return "", err
+	}
+	if pod.Spec.NodeName != nodeName {
+		return "", &MirrorPodError{iface.PodName, iface.Namespace, fmt.Sprintf("the pod isn't on the node %s", nodeName)}
+	}
+	return utils.GenerateVethName(string(pod.ObjectMeta.UID), iface.IfName), nil
+}
+
+// CreateMirror will create the OVS mirror on the bridge of the node and save the mirror
+func CreateMirror(sp *serviceprovider.Container, network *entity.Network, mirror *entity.Mirror) error {
+	ports := []string{}
+	for _, source := range mirror.Sources {
+		port, err := resolvePodPort(sp, mirror.NodeName, source)
+		if err != nil {
+			return err
+		}
+		ports = append(ports, port)
+	}
+	outputPort := mirror.OutputPort
+	if mirror.OutputPod != nil {
+		port, err := resolvePodPort(sp, mirror.NodeName, *mirror.OutputPod)
+		if err != nil {
+			return err
+		}
+		outputPort = port
+	}
+
+	if mirror.TTL == 0 {
+		mirror.TTL = DefaultMirrorTTL
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(mirror.TTL) * time.Minute)
+	mirror.ID = bson.NewObjectId()
+	mirror.NetworkID = network.ID
+	mirror.BridgeName = network.BridgeName
+	mirror.MirrorName = GenerateMirrorName(mirror.ID)
+	mirror.CreatedAt = &now
+	mirror.ExpiresAt = &expiresAt
+
+	nc, err := getNetworkController(sp, mirror.NodeName)
+	if err != nil {
+		return err
+	}
+	bridgePorts, err := nc.DumpOVSPorts(mirror.BridgeName)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, port := range bridgePorts {
+		names = append(names, port.Name)
+	}
+	if err := checkOutputPort(names, ports, outputPort); err != nil {
+		return err
+	}
+	if err := nc.CreateOVSMirror(mirror.BridgeName, mirror.MirrorName, ports, mirror.VlanTags, outputPort); err != nil {
+		return err
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	if err := session.Insert(entity.MirrorCollectionName, mirror); err != nil {
+		// the mirror isn't saved, so it can't be removed by the TTL
+		nc.DeleteOVSMirror(mirror.BridgeName, mirror.MirrorName)
+		return err
+	}
+	return nil
+}
+
+// DeleteMirror will delete the OVS mirror on the bridge of the node and remove the mirror
+// The mirror is removed if the node or the OVS mirror doesn't exist anymore, since there is nothing left to delete.
+func DeleteMirror(sp *serviceprovider.Container, mirror *entity.Mirror) error {
+	nc, err := getNetworkController(sp, mirror.NodeName)
+	if errors.IsNotFound(err) {
+		logger.Warnf("the node %s of the mirror %s doesn't exist, remove the mirror", mirror.NodeName, mirror.Name)
+	} else if err != nil {
+		return err
+	} else if err := nc.DeleteOVSMirror(mirror.BridgeName, mirror.MirrorName); err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	return session.Remove(entity.MirrorCollectionName, "_id", mirror.ID)
+}
+
+func getNetworkController(sp *serviceprovider.Container, nodeName string) (*networkcontroller.NetworkController, error) {
+	nodeIP, err := sp.KubeCtl.GetNodeInternalIP(nodeName)
+	if err != nil {
+		return nil, err
+	}
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	return sp.NetworkControllers.Get(nodeAddr)
+}
+
+// ReapMirrors will delete the expired mirrors, the mirrors which can't be deleted are kept and deleted in the next run
+func ReapMirrors(sp *serviceprovider.Container) (int, error) {
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	mirrors := []entity.Mirror{}
+	if err := session.FindAll(entity.MirrorCollectionName, bson.M{"expiresAt": bson.M{"$lte": time.Now()}}, &mirrors); err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for i := range mirrors {
+		if err := DeleteMirror(sp, &mirrors[i]); err != nil {
+			logger.Warnf("delete the expired mirror %s on node %s fail: %v", mirrors[i].Name, mirrors[i].NodeName, err)
+			continue
+		}
+		reaped++
+	}
+	return reaped, nil
+}
+
+// CollectExpiredMirrors will delete the expired mirrors periodically
+func CollectExpiredMirrors(sp *serviceprovider.Container, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		reaped, err := ReapMirrors(sp)
+		if err != nil {
+			logger.Warnf("delete the expired mirrors fail: %v", err)
+		} else if reaped > 0 {
+			logger.Infof("delete %d expired mirrors", reaped)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package ovscontroller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/entity"
+	kc "github.com/linkernetworks/vortex/src/kubernetes"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/mgo.v2/bson"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateMirror(t *testing.T) {
+	network := &entity.Network{
+		Name:        "my-net",
+		Type:        entity.OVSKernelspaceNetworkType,
+		BridgeName:  "vortex-br0",
+		OverlayType: "vxlan",
+		Nodes:       []entity.Node{{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}}},
+	}
+	source := entity.MirrorPodInterface{PodName: "web", Namespace: "default", IfName: "eth1"}
+
+	assert.NoError(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", Sources: []entity.MirrorPodInterface{source}, OutputPort: "eth3"}))
+	assert.NoError(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}, OutputPod: &source}))
+
+	//The node isn't in the network
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node2", VlanTags: []int32{100}, OutputPort: "eth3"}))
+	//Nothing is mirrored
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", OutputPort: "eth3"}))
+	//Both or none of the outputs
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}}))
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}, OutputPort: "eth3", OutputPod: &source}))
+
+	//The uplink, tunnel and internal ports of the network
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}, OutputPort: "eth1"}))
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}, OutputPort: "vxlan-node2"}))
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}, OutputPort: "vortex-br0"}))
+	//The output is one of the sources
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", Sources: []entity.MirrorPodInterface{source}, OutputPod: &source}))
+
+	network.Type = entity.LinuxBridgeNetworkType
+	assert.Error(t, ValidateMirror(network, &entity.Mirror{NodeName: "node1", VlanTags: []int32{100}, OutputPort: "eth3"}))
+}
+
+func TestCheckOutputPort(t *testing.T) {
+	bridgePorts := []string{"eth1", "veth1", "veth2", "eth3"}
+	assert.NoError(t, checkOutputPort(bridgePorts, []string{"veth1"}, "eth3"))
+	assert.IsType(t, &MirrorPortError{}, checkOutputPort(bridgePorts, []string{"veth1"}, "veth1"))
+	assert.IsType(t, &MirrorPortError{}, checkOutputPort(bridgePorts, []string{"veth1"}, "eth4"))
+}
+
+func TestGenerateMirrorName(t *testing.T) {
+	id := bson.NewObjectId()
+	assert.Equal(t, GenerateMirrorName(id), GenerateMirrorName(id))
+	assert.Len(t, GenerateMirrorName(id), len("mirror-")+8)
+}
+
+func TestResolvePodPort(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+	sp.KubeCtl = kc.New(fakeclientset.NewSimpleClientset())
+
+	_, err := sp.KubeCtl.Clientset.CoreV1().Pods("default").Create(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid-1"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+	})
+	assert.NoError(t, err)
+
+	port, err := resolvePodPort(sp, "node1", entity.MirrorPodInterface{PodName: "web", Namespace: "default", IfName: "eth1"})
+	assert.NoError(t, err)
+	assert.Contains(t, port, "veth")
+
+	_, err = resolvePodPort(sp, "node2", entity.MirrorPodInterface{PodName: "web", Namespace: "default", IfName: "eth1"})
+	assert.IsType(t, &MirrorPodError{}, err)
+
+	_, err = resolvePodPort(sp, "node1", entity.MirrorPodInterface{PodName: "db", Namespace: "default", IfName: "eth1"})
+	assert.IsType(t, &MirrorPodError{}, err)
+}
+
+func TestReapMirrorsOfMissingNode(t *testing.T) {
+	cf := config.MustRead("../../config/testing.json")
+	sp := serviceprovider.NewForTesting(cf)
+	sp.KubeCtl = kc.New(fakeclientset.NewSimpleClientset())
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	expiresAt := time.Now().Add(-time.Minute)
+	mirror := entity.Mirror{
+		ID:         bson.NewObjectId(),
+		Name:       "expired",
+		NodeName:   "missing-node",
+		BridgeName: "br0",
+		VlanTags:   []int32{100},
+		OutputPort: "eth3",
+		ExpiresAt:  &expiresAt,
+	}
+	mirror.MirrorName = GenerateMirrorName(mirror.ID)
+	assert.NoError(t, session.Insert(entity.MirrorCollectionName, &mirror))
+	defer session.Remove(entity.MirrorCollectionName, "_id", mirror.ID)
+
+	//The node doesn't exist, so the mirror is removed without the network controller
+	_, err := ReapMirrors(sp)
+	assert.NoError(t, err)
+	count, err := session.Count(entity.MirrorCollectionName, bson.M{"_id": mirror.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -2,12 +2,16 @@ package ovscontroller
 
 import (
 	"net"
+	"strings"
 
+	pb "github.com/linkernetworks/network-controller/messages"
 	"github.com/linkernetworks/network-controller/utils"
 	"github.com/linkernetworks/vortex/src/entity"
 	"github.com/linkernetworks/vortex/src/networkcontroller"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 	"gopkg.in/mgo.v2/bson"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 func DumpPorts(sp *serviceprovider.Container, nodeName string, bridgeName string) ([]entity.OVSPortInfo, error) {
@@ -17,7 +21,7 @@ func DumpPorts(sp *serviceprovider.Container, nodeName string, bridgeName string
 	}
 
 	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
-	nc, err := networkcontroller.New(nodeAddr)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -29,60 +33,37 @@ func DumpPorts(sp *serviceprovider.Container, nodeName string, bridgeName string
 	}
 
 	//We need to find a mapping for veth to podName
-	//1. lookup the mongodb to find all deployments which bridge name is equal to bridgeName
-	//2. lookup all current pods which is belogs to above deployments
-	//3. use the pod's UID and the interfae of each entity.DeploymentNetowrk to geneate the vetxXXXXXX
+	//1. lookup the mongodb to find all deployments and pods which bridge name is equal to bridgeName
+	//2. lookup all current pods which is belogs to above deployments or pods
+	//3. use the pod's UID and the interfae of each network to geneate the vetxXXXXXX
 	//4. use the vtxXXXXXXXXX as the key to combine the podName/interfaace and the OVSPorts
 	session := sp.Mongo.NewSession()
 	defer session.Close()
 
-	//1. lookup the mongodb to find all deployments which bridge name is equal to bridgeName
-	//In order to the following use, use the map here and the key is the deploynent name and the value is the deployment object.
-	//We need to mapping the deployment.Networks with Pod's UID and the connection is the label of the Pod is vortex=deployment.name.
+	//1. lookup the mongodb to find all deployments and pods which bridge name is equal to bridgeName
 	deployments := []entity.Deployment{}
-	session.FindAll(entity.DeploymentCollectionName, bson.M{"networks.bridgeName": bridgeName}, &deployments)
-	deployMap := map[string]*entity.Deployment{}
+	if err := session.FindAll(entity.DeploymentCollectionName, bson.M{"networks.bridgeName": bridgeName}, &deployments); err != nil {
+		return nil, err
+	}
+	podRecords := []entity.Pod{}
+	if err := session.FindAll(entity.PodCollectionName, bson.M{"networks.bridgeName": bridgeName}, &podRecords); err != nil {
+		return nil, err
+	}
 
-	for _, v := range deployments {
-		deployMap[v.Name] = &v
+	//The network is used to know which ports are the uplinks or tunnels of the node
+	networks := []entity.Network{}
+	if err := session.FindAll(entity.NetworkCollectionName, bson.M{"bridgeName": bridgeName}, &networks); err != nil {
+		return nil, err
 	}
 
-	//2. lookup all current pods which is belogs to above deployments
+	//2. lookup all current pods which is belogs to above deployments or pods
 	pods, err := sp.KubeCtl.GetPods("")
 	if err != nil {
 		return nil, err
 	}
 
-	//Create a local structure to combine the deploymentNetwork and PodName.
-	//We want to know the interface name in that Pod, so we need to keep the deploymentNetwork object.
-	type portData struct {
-		podName string
-		entity.DeploymentNetwork
-	}
-	interfaces := map[string]*portData{}
-	for _, v := range pods {
-		name, ok := v.Labels["vortex"]
-		if !ok {
-			continue
-		}
-		deploy, ok := deployMap[name]
-		if !ok {
-			continue
-		}
-
-		//3. use the pod's UID and the interfae of each entity.DeploymentNetowrk to geneate the vtxXXXXXXXXX
-		//for each deploymentNetwork, we get the vethname via veth+sha256(podUID + interfaceName in container)[0:8]
-		uid := v.ObjectMeta.UID
-		for _, k := range deploy.Networks {
-			vethName := utils.GenerateVethName(string(uid), k.IfName)
-			//Use the veth name as the key and the PodName/InterfaceName in the value, we will add those inforamtion
-			//to OVSPortInfo later.
-			interfaces[vethName] = &portData{
-				v.Name,
-				k,
-			}
-		}
-	}
+	//3. use the pod's UID and the interfae of each network to geneate the vtxXXXXXXXXX
+	interfaces := podInterfaces(pods, deployments, podRecords)
 
 	//4. use the vethxxx as the key to combine the podName/interfaace and the OVSPorts
 	ports := []entity.OVSPortInfo{}
@@ -103,11 +84,16 @@ func DumpPorts(sp *serviceprovider.Container, nodeName string, bridgeName string
 				Dropped: v.Transmitted.Dropped,
 				Errors:  v.Transmitted.Errors,
 			},
+			QoS: portQoS(v),
 		}
 
 		if i, ok := interfaces[port.Name]; ok {
-			port.InterfaceName = i.IfName
+			port.InterfaceName = i.ifName
 			port.PodName = i.podName
+			port.Namespace = i.namespace
+			port.Type = entity.OVSPodPortType
+		} else {
+			port.Type = classifyPort(networks, nodeName, bridgeName, port.Name)
 		}
 		ports = append(ports, port)
 
@@ -115,3 +101,87 @@ func DumpPorts(sp *serviceprovider.Container, nodeName string, bridgeName string
 
 	return ports, nil
 }
+
+// podInterface is the pod and the interface name in the container of a veth
+type podInterface struct {
+	podName   string
+	namespace string
+	ifName    string
+}
+
+// podInterfaces maps the veth names to the pods of the deployments and the pods created by vortex
+// The pods of a deployment are found by the label vortex=deployment.name, the other pods are found by the namespace and name.
+// We get the vethname via veth+sha256(podUID + interfaceName in container)[0:8]
+func podInterfaces(pods []*corev1.Pod, deployments []entity.Deployment, podRecords []entity.Pod) map[string]*podInterface {
+	deployMap := map[string]*entity.Deployment{}
+	for i := range deployments {
+		deployMap[deployments[i].Name] = &deployments[i]
+	}
+	podMap := map[string]*entity.Pod{}
+	for i := range podRecords {
+		podMap[podRecords[i].Namespace+"/"+podRecords[i].Name] = &podRecords[i]
+	}
+
+	interfaces := map[string]*podInterface{}
+	for _, v := range pods {
+		uid := string(v.ObjectMeta.UID)
+		add := func(ifName string) {
+			interfaces[utils.GenerateVethName(uid, ifName)] = &podInterface{
+				podName:   v.Name,
+				namespace: v.Namespace,
+				ifName:    ifName,
+			}
+		}
+		if name, ok := v.Labels["vortex"]; ok {
+			if deploy, ok := deployMap[name]; ok {
+				for _, k := range deploy.Networks {
+					add(k.IfName)
+				}
+			}
+		}
+		if pod, ok := podMap[v.Namespace+"/"+v.Name]; ok {
+			for _, k := range pod.Networks {
+				add(k.IfName)
+			}
+		}
+	}
+	return interfaces
+}
+
+// portQoS will return the bandwidth limits set on the OVS interface, or nil if it's not limited
+// It's what the bridge enforces, which may differ from the QoS of the pod's network if the port is changed out of vortex.
+func portQoS(v *pb.PortInfo) *entity.NetworkQoS {
+	if v.Qos == nil || (v.Qos.IngressRate == 0 && v.Qos.EgressRate == 0) {
+		return nil
+	}
+	return &entity.NetworkQoS{
+		IngressRate:  v.Qos.IngressRate,
+		IngressBurst: v.Qos.IngressBurst,
+		EgressRate:   v.Qos.EgressRate,
+		EgressBurst:  v.Qos.EgressBurst,
+	}
+}
+
+// classifyPort will tell what the port which doesn't belong to any pod is
+// The veth whose pod has been deleted is unknown, it's the orphaned port.
+func classifyPort(networks []entity.Network, nodeName, bridgeName, portName string) entity.OVSPortType {
+	if portName == bridgeName || portName == "LOCAL" {
+		return entity.OVSInternalPortType
+	}
+	for _, network := range networks {
+		if network.OverlayType != "" && strings.HasPrefix(portName, network.OverlayType+"-") {
+			return entity.OVSTunnelPortType
+		}
+		for _, node := range network.Nodes {
+			if node.Name != nodeName {
+				continue
+			}
+			for _, phyIface := range node.PhyInterfaces {
+				if phyIface.Name == portName {
+					return entity.OVSUplinkPortType
+				}
+			}
+		}
+	}
+	return entity.OVSUnknownPortType
+}
@@ -10,16 +10,20 @@ import (
 	"testing"
 	"time"
 
+	pb "github.com/linkernetworks/network-controller/messages"
+	"github.com/linkernetworks/network-controller/utils"
 	"github.com/linkernetworks/vortex/src/config"
-	_ "github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/entity"
 	kc "github.com/linkernetworks/vortex/src/kubernetes"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 
 	"github.com/moby/moby/pkg/namesgenerator"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -98,3 +102,58 @@ func (suite *OVSControllerTestSuite) TestDumpOVSPorts() {
 	suite.NoError(err)
 	suite.Equal(1, len(portStats))
 }
+
+func TestPodInterfaces(t *testing.T) {
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-5b6f5bd8c-2xqlm", Namespace: "default", UID: types.UID("uid-1"), Labels: map[string]string{"vortex": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", UID: types.UID("uid-2")}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "other", UID: types.UID("uid-3")}},
+	}
+	deployments := []entity.Deployment{
+		{Name: "web", Networks: []entity.DeploymentNetwork{{IfName: "eth1"}}},
+	}
+	podRecords := []entity.Pod{
+		{Name: "db", Namespace: "default", Networks: []entity.PodNetwork{{IfName: "eth1"}, {IfName: "eth2"}}},
+	}
+
+	interfaces := podInterfaces(pods, deployments, podRecords)
+	assert.Len(t, interfaces, 3)
+	assert.Equal(t, &podInterface{"web-5b6f5bd8c-2xqlm", "default", "eth1"}, interfaces[utils.GenerateVethName("uid-1", "eth1")])
+	assert.Equal(t, &podInterface{"db", "default", "eth1"}, interfaces[utils.GenerateVethName("uid-2", "eth1")])
+	assert.Equal(t, &podInterface{"db", "default", "eth2"}, interfaces[utils.GenerateVethName("uid-2", "eth2")])
+}
+
+func TestPortQoS(t *testing.T) {
+	assert.Nil(t, portQoS(&pb.PortInfo{}))
+	assert.Nil(t, portQoS(&pb.PortInfo{Qos: &pb.PortQoS{}}))
+	assert.Equal(t, &entity.NetworkQoS{IngressRate: 1000, IngressBurst: 100}, portQoS(&pb.PortInfo{Qos: &pb.PortQoS{IngressRate: 1000, IngressBurst: 100}}))
+	assert.Equal(t, &entity.NetworkQoS{EgressRate: 2000}, portQoS(&pb.PortInfo{Qos: &pb.PortQoS{EgressRate: 2000}}))
+}
+
+func TestClassifyPort(t *testing.T) {
+	networks := []entity.Network{
+		{
+			BridgeName:  "system-47f8ce",
+			OverlayType: "vxlan",
+			Nodes: []entity.Node{
+				{Name: "node1", PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}}},
+				{Name: "node2", PhyInterfaces: []entity.PhyInterface{{Name: "eth2"}}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		portName string
+		expected entity.OVSPortType
+	}{
+		{"eth1", entity.OVSUplinkPortType},
+		{"eth2", entity.OVSUnknownPortType},
+		{"vxlan-1a2b3c4d", entity.OVSTunnelPortType},
+		{"system-47f8ce", entity.OVSInternalPortType},
+		{"LOCAL", entity.OVSInternalPortType},
+		{"veth7e3a8c21", entity.OVSUnknownPortType},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, classifyPort(networks, "node1", "system-47f8ce", tc.portName), tc.portName)
+	}
+}
@@ -0,0 +1,228 @@
+package pod
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/linkernetworks/network-controller/utils"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+)
+
+// These are the limits of the packet capture, so a capture can't exhaust the node
+const (
+	DefaultCaptureDuration = 30 * time.Second
+	MaxCaptureDuration     = 5 * time.Minute
+	DefaultCaptureBytes    = 10 << 20
+	MaxCaptureBytes        = 100 << 20
+	// MaxNodeCaptures is the number of the concurrent captures on a node
+	MaxNodeCaptures = 2
+)
+
+// ErrTooManyCaptures is returned if the node already runs MaxNodeCaptures captures
+var ErrTooManyCaptures = errors.New("too many captures are running on the node, try it later")
+
+// The slots of the running captures of each node
+var captureSlots = struct {
+	sync.Mutex
+	nodes map[string]chan struct{}
+}{nodes: map[string]chan struct{}{}}
+
+func acquireCaptureSlot(nodeName string) (func(), error) {
+	captureSlots.Lock()
+	slots, ok := captureSlots.nodes[nodeName]
+	if !ok {
+		slots = make(chan struct{}, MaxNodeCaptures)
+		captureSlots.nodes[nodeName] = slots
+	}
+	captureSlots.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+	default:
+		return nil, ErrTooManyCaptures
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+// The filter is the pcap-filter expression, it's passed to the node as an argument of the capture
+var captureFilterRegexp = regexp.MustCompile(`^[a-zA-Z0-9 .:/()!&|<>=\[\]-]*$`)
+
+// CaptureOptions is the options of the packet capture of the pod's interface
+type CaptureOptions struct {
+	IfName   string
+	Filter   string
+	Duration time.Duration
+	MaxBytes int64
+}
+
+// CheckCaptureOptions will check the options by the pod and the limits, the defaults are set if they're empty
+func CheckCaptureOptions(pod *entity.Pod, opts *CaptureOptions) error {
+	found := false
+	for _, v := range pod.Networks {
+		if v.IfName != opts.IfName {
+			continue
+		}
+		//The virtual function is moved into the pod, it's not on the node
+		if v.NetworkType == entity.SRIOVNetworkType {
+			return fmt.Errorf("the interface %s of the sriov network can't be captured", opts.IfName)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("the interface %s isn't the custom network of the pod %s", opts.IfName, pod.Name)
+	}
+
+	if opts.Duration == 0 {
+		opts.Duration = DefaultCaptureDuration
+	}
+	if opts.Duration < time.Second || opts.Duration > MaxCaptureDuration {
+		return fmt.Errorf("the duration should be between 1s and %v", MaxCaptureDuration)
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = DefaultCaptureBytes
+	}
+	if opts.MaxBytes < 0 || opts.MaxBytes > MaxCaptureBytes {
+		return fmt.Errorf("the maxBytes should be between 1 and %d", MaxCaptureBytes)
+	}
+	if !captureFilterRegexp.MatchString(opts.Filter) {
+		return fmt.Errorf("the filter %q has invalid characters", opts.Filter)
+	}
+	return nil
+}
+
+// The pcap file is the global header and the records of the packets, each record has its own header
+// with the captured length of the packet at the offset 8, in the byte order of the magic number.
+const (
+	pcapGlobalHeaderLen = 24
+	pcapRecordHeaderLen = 16
+)
+
+// pcapLimitReader stops reading at the last packet which fits in the max bytes even if the node sends more,
+// so the pcap file isn't cut in the middle of a packet.
+type pcapLimitReader struct {
+	r         io.Reader
+	remaining int64
+	order     binary.ByteOrder
+	buf       []byte
+	err       error
+}
+
+func (l *pcapLimitReader) Read(p []byte) (int, error) {
+	for len(l.buf) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		l.buf, l.err = l.next()
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+// next will return the global header or the next record, the partial one at the end of the stream is dropped
+func (l *pcapLimitReader) next() ([]byte, error) {
+	if l.order == nil {
+		header := make([]byte, pcapGlobalHeaderLen)
+		if _, err := io.ReadFull(l.r, header); err != nil {
+			return nil, truncated(err)
+		}
+		switch binary.LittleEndian.Uint32(header) {
+		case 0xa1b2c3d4, 0xa1b23c4d:
+			l.order = binary.LittleEndian
+		case 0xd4c3b2a1, 0x4d3cb2a1:
+			l.order = binary.BigEndian
+		default:
+			return nil, fmt.Errorf("the capture isn't a pcap stream")
+		}
+		return l.take(header)
+	}
+
+	header := make([]byte, pcapRecordHeaderLen)
+	if _, err := io.ReadFull(l.r, header); err != nil {
+		return nil, truncated(err)
+	}
+	length := int64(l.order.Uint32(header[8:12]))
+	if pcapRecordHeaderLen+length > l.remaining {
+		return nil, io.EOF
+	}
+	record := make([]byte, pcapRecordHeaderLen+length)
+	copy(record, header)
+	if _, err := io.ReadFull(l.r, record[pcapRecordHeaderLen:]); err != nil {
+		return nil, truncated(err)
+	}
+	return l.take(record)
+}
+
+func (l *pcapLimitReader) take(data []byte) ([]byte, error) {
+	if int64(len(data)) > l.remaining {
+		return nil, io.EOF
+	}
+	l.remaining -= int64(len(data))
+	return data, nil
+}
+
+// The stream ended in the middle of the header or the packet
+func truncated(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		return io.EOF
+	}
+	return err
+}
+
+// captureReadCloser releases the slot of the node when the capture is closed
+type captureReadCloser struct {
+	io.Reader
+	stream  io.Closer
+	release func()
+}
+
+func (c *captureReadCloser) Close() error {
+	defer c.release()
+	return c.stream.Close()
+}
+
+// Capture will capture the packets of the pod's interface on its node and return the pcap stream
+// The stream is ended after the duration or the max bytes of the options, see CheckCaptureOptions.
+// ErrTooManyCaptures is returned if the node is busy, and the slot of the node is released when the stream is closed.
+func Capture(sp *serviceprovider.Container, pod *entity.Pod, opts CaptureOptions) (io.ReadCloser, error) {
+	kpod, err := sp.KubeCtl.GetPod(pod.Name, pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if kpod.Spec.NodeName == "" {
+		return nil, fmt.Errorf("the pod %s isn't scheduled to any node", pod.Name)
+	}
+
+	nodeIP, err := sp.KubeCtl.GetNodeInternalIP(kpod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+	nodeAddr := net.JoinHostPort(nodeIP, networkcontroller.DEFAULT_CONTROLLER_PORT)
+	nc, err := sp.NetworkControllers.Get(nodeAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := acquireCaptureSlot(kpod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+	vethName := utils.GenerateVethName(string(kpod.ObjectMeta.UID), opts.IfName)
+	stream, err := nc.CapturePackets(vethName, opts.Filter, opts.Duration, opts.MaxBytes)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &captureReadCloser{&pcapLimitReader{r: stream, remaining: opts.MaxBytes}, stream, release}, nil
+}
@@ -0,0 +1,105 @@
+package pod
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCaptureOptions(t *testing.T) {
+	pod := &entity.Pod{
+		Name: "web",
+		Networks: []entity.PodNetwork{
+			{IfName: "eth1", NetworkType: entity.OVSKernelspaceNetworkType},
+			{IfName: "eth2", NetworkType: entity.SRIOVNetworkType},
+		},
+	}
+
+	opts := CaptureOptions{IfName: "eth1", Filter: "tcp port 80 and (host 10.0.0.2 or host 10.0.0.3)"}
+	assert.NoError(t, CheckCaptureOptions(pod, &opts))
+	assert.Equal(t, DefaultCaptureDuration, opts.Duration)
+	assert.Equal(t, int64(DefaultCaptureBytes), opts.MaxBytes)
+
+	testCases := []struct {
+		cases string
+		opts  CaptureOptions
+	}{
+		{"NotCustomNetwork", CaptureOptions{IfName: "eth0"}},
+		{"SRIOVNetwork", CaptureOptions{IfName: "eth2"}},
+		{"TooLong", CaptureOptions{IfName: "eth1", Duration: MaxCaptureDuration + time.Second}},
+		{"TooShort", CaptureOptions{IfName: "eth1", Duration: time.Millisecond}},
+		{"TooLarge", CaptureOptions{IfName: "eth1", MaxBytes: MaxCaptureBytes + 1}},
+		{"InvalidFilter", CaptureOptions{IfName: "eth1", Filter: "tcp; reboot"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.cases, func(t *testing.T) {
+			assert.Error(t, CheckCaptureOptions(pod, &tc.opts))
+		})
+	}
+}
+
+func TestPcapLimitReader(t *testing.T) {
+	header := make([]byte, pcapGlobalHeaderLen)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	record := func(length int) []byte {
+		data := make([]byte, pcapRecordHeaderLen+length)
+		binary.LittleEndian.PutUint32(data[8:12], uint32(length))
+		binary.LittleEndian.PutUint32(data[12:16], uint32(length))
+		return data
+	}
+
+	stream := append([]byte{}, header...)
+	stream = append(stream, record(100)...)
+	stream = append(stream, record(100)...)
+
+	//The second packet doesn't fit in the max bytes
+	data, err := ioutil.ReadAll(&pcapLimitReader{r: bytes.NewReader(stream), remaining: 200})
+	assert.NoError(t, err)
+	assert.Equal(t, pcapGlobalHeaderLen+pcapRecordHeaderLen+100, len(data))
+
+	data, err = ioutil.ReadAll(&pcapLimitReader{r: bytes.NewReader(stream), remaining: int64(len(stream))})
+	assert.NoError(t, err)
+	assert.Equal(t, stream, data)
+
+	//The partial packet at the end of the stream is dropped
+	data, err = ioutil.ReadAll(&pcapLimitReader{r: bytes.NewReader(stream[:len(stream)-10]), remaining: MaxCaptureBytes})
+	assert.NoError(t, err)
+	assert.Equal(t, pcapGlobalHeaderLen+pcapRecordHeaderLen+100, len(data))
+
+	_, err = ioutil.ReadAll(&pcapLimitReader{r: bytes.NewReader(make([]byte, 100)), remaining: MaxCaptureBytes})
+	assert.Error(t, err)
+}
+
+func TestAcquireCaptureSlot(t *testing.T) {
+	releases := []func(){}
+	for i := 0; i < MaxNodeCaptures; i++ {
+		release, err := acquireCaptureSlot("capture-node")
+		assert.NoError(t, err)
+		releases = append(releases, release)
+	}
+	_, err := acquireCaptureSlot("capture-node")
+	assert.Equal(t, ErrTooManyCaptures, err)
+
+	//The other nodes aren't affected
+	release, err := acquireCaptureSlot("capture-node2")
+	assert.NoError(t, err)
+	release()
+
+	//The slot is released only once
+	releases[0]()
+	releases[0]()
+	release, err = acquireCaptureSlot("capture-node")
+	assert.NoError(t, err)
+	_, err = acquireCaptureSlot("capture-node")
+	assert.Equal(t, ErrTooManyCaptures, err)
+
+	release()
+	for _, release := range releases[1:] {
+		release()
+	}
+}
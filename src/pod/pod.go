@@ -6,12 +6,17 @@ import (
 
 	"github.com/linkernetworks/mongo"
 	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/ipam"
+	"github.com/linkernetworks/vortex/src/kubeutils"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
+	np "github.com/linkernetworks/vortex/src/networkprovider"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 	"github.com/linkernetworks/vortex/src/utils"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -35,16 +40,79 @@ func CheckPodParameter(sp *serviceprovider.Container, pod *entity.Pod) error {
 		}
 	}
 
+	//Check the resources
+	if err := kubeutils.CheckResources(pod.Containers); err != nil {
+		return err
+	}
+	if err := kubeutils.CheckContainers(pod.Containers); err != nil {
+		return err
+	}
+
 	//Check the network
 	for _, v := range pod.Networks {
-		count, err := session.Count(entity.NetworkCollectionName, bson.M{"name": v.Name})
-		if err != nil {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": v.Name}, &network); err != nil {
+			if err == mgo.ErrNotFound {
+				return fmt.Errorf("the network named %s doesn't exist", v.Name)
+			}
 			return fmt.Errorf("check the network name error:%v", err)
-		} else if count == 0 {
-			return fmt.Errorf("the network named %s doesn't exist", v.Name)
+		}
+		//The ip address is leased from the subnets if it's not set
+		if v.IPAddress == "" && len(v.Addresses) == 0 && len(network.Subnets) == 0 {
+			return fmt.Errorf("the ip address is required since the network %s doesn't have any subnet", v.Name)
+		}
+		if v.IPAddress != "" && v.Netmask == "" && len(network.Subnets) == 0 {
+			return fmt.Errorf("the netmask is required since the network %s doesn't have any subnet", v.Name)
+		}
+		if err := checkNetworkAddresses(v); err != nil {
+			return err
+		}
+		if v.QoS != nil && network.Type != entity.OVSKernelspaceNetworkType && network.Type != entity.OVSUserspaceNetworkType {
+			return fmt.Errorf("the qos is only for the OVS networks, the network %s is %s", v.Name, network.Type)
+		}
+		//The vlan of the linux network is the sub-interface of the physical interface attached to the bridge
+		if v.VlanTag != nil && network.Type == entity.LinuxBridgeNetworkType {
+			return fmt.Errorf("the vlanTag can't be set on the linux network %s, the vlan is set by the vlanTags of the network", v.Name)
+		}
+		//The mac address is set to the virtual function by the client
+		if v.MacAddress != "" && network.Type != entity.SRIOVNetworkType {
+			return fmt.Errorf("the macAddress is only for the sriov networks, the network %s is %s", v.Name, network.Type)
 		}
 	}
 
+	//Check the leases of the pod which has the same name, the pod is created in the default namespace if it's not set
+	namespace := pod.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	count, err := session.Count(entity.IPLeaseCollectionName, bson.M{"namespace": namespace, "podName": pod.Name})
+	if err != nil {
+		return fmt.Errorf("check the ip leases error:%v", err)
+	} else if count != 0 {
+		return fmt.Errorf("the pod %s has leased the ip addresses", pod.Name)
+	}
+
+	return nil
+}
+
+// The address family of each value should be the same as its ip address or destination
+func checkNetworkAddresses(network entity.PodNetwork) error {
+	if network.IPAddress != "" {
+		//The subnets are ipv4, so the ipv6 address can't get its netmask from the lease
+		if network.Netmask == "" && utils.IPFamily(network.IPAddress) == 6 {
+			return fmt.Errorf("the prefix length of the ipv6 address %s is required", network.IPAddress)
+		}
+		if network.Netmask != "" {
+			if err := utils.CheckNetmask(network.IPAddress, network.Netmask); err != nil {
+				return err
+			}
+		}
+	}
+	for _, route := range network.RoutesGw {
+		if utils.IPFamily(route.DstCIDR) != utils.IPFamily(route.Gateway) {
+			return fmt.Errorf("the gateway %s and the destination %s aren't the same address family", route.Gateway, route.DstCIDR)
+		}
+	}
 	return nil
 }
 
@@ -93,16 +161,79 @@ func generateNodeLabels(networks []entity.Network) []string {
 	return utils.Intersections(totalNames)
 }
 
-func generateClientCommand(network entity.PodNetwork) (command []string) {
-	ip := utils.IPToCIDR(network.IPAddress, network.Netmask)
+// Each address of the interface is passed by its own --ip flag, the ipv4 and ipv6 addresses can be mixed
+func generateIPArgs(network entity.PodNetwork) []string {
+	args := []string{}
+	if network.IPAddress != "" {
+		args = append(args, "--ip="+utils.IPToCIDR(network.IPAddress, network.Netmask))
+	}
+	for _, address := range network.Addresses {
+		args = append(args, "--ip="+address)
+	}
+	return args
+}
 
+//The client attaches the veth to the OVS bridge, or to the Linux bridge if it's the linux network
+func generateClientCommand(network entity.PodNetwork) (command []string) {
 	command = []string{
 		"--server=unix:///tmp/vortex.sock",
 		"--bridge=" + network.BridgeName,
+	}
+	if network.NetworkType == entity.LinuxBridgeNetworkType {
+		command = append(command, "--linux-bridge")
+	}
+	command = append(command, "--nic="+network.IfName)
+	command = append(command, generateIPArgs(network)...)
+
+	if network.VlanTag != nil {
+		command = append(command, "--vlan="+strconv.Itoa((int)(*network.VlanTag)))
+	}
+	if qos := network.QoS; qos != nil {
+		if qos.IngressRate > 0 {
+			command = append(command, "--ingress-rate="+strconv.FormatInt(qos.IngressRate, 10))
+			if qos.IngressBurst > 0 {
+				command = append(command, "--ingress-burst="+strconv.FormatInt(qos.IngressBurst, 10))
+			}
+		}
+		if qos.EgressRate > 0 {
+			command = append(command, "--egress-rate="+strconv.FormatInt(qos.EgressRate, 10))
+			if qos.EgressBurst > 0 {
+				command = append(command, "--egress-burst="+strconv.FormatInt(qos.EgressBurst, 10))
+			}
+		}
+	}
+	if len(network.RoutesGw) != 0 {
+		for _, netroute := range network.RoutesGw {
+			command = append(command, "--route-gw="+netroute.DstCIDR+","+netroute.Gateway)
+		}
+	}
+	if len(network.RoutesIntf) != 0 {
+		for _, netroute := range network.RoutesIntf {
+			command = append(command, "--route-intf="+netroute.DstCIDR)
+		}
+	}
+	return
+}
+
+//The sriov init step moves a free virtual function of the physical function on the current node into the pod
+//and the physical function is chosen by the NODE_NAME
+func generateSRIOVClientCommand(network entity.PodNetwork) (command []string) {
+	command = []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--sriov",
+		"--node=$(NODE_NAME)",
 		"--nic=" + network.IfName,
-		"--ip=" + ip,
 	}
+	command = append(command, generateIPArgs(network)...)
 
+	for _, node := range network.Nodes {
+		for _, phyIface := range node.PhyInterfaces {
+			command = append(command, "--pf="+node.Name+","+phyIface.Name)
+		}
+	}
+	if network.MacAddress != "" {
+		command = append(command, "--mac="+network.MacAddress)
+	}
 	if network.VlanTag != nil {
 		command = append(command, "--vlan="+strconv.Itoa((int)(*network.VlanTag)))
 	}
@@ -123,37 +254,52 @@ func generateInitContainer(networks []entity.PodNetwork) ([]corev1.Container, er
 	containers := []corev1.Container{}
 
 	for i, v := range networks {
-		containers = append(containers, corev1.Container{
-			Name:    fmt.Sprintf("init-network-client-%d", i),
-			Image:   "sdnvortex/network-controller:v0.4.8",
-			Command: []string{"/go/bin/client"},
-			Args:    generateClientCommand(v),
-			Env: []corev1.EnvVar{
-				{
-					Name: "POD_NAME",
-					ValueFrom: &corev1.EnvVarSource{
-						FieldRef: &corev1.ObjectFieldSelector{
-							FieldPath: "metadata.name",
-						},
+		args := generateClientCommand(v)
+		envVars := []corev1.EnvVar{
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.name",
 					},
 				},
-				{
-					Name: "POD_NAMESPACE",
-					ValueFrom: &corev1.EnvVarSource{
-						FieldRef: &corev1.ObjectFieldSelector{
-							FieldPath: "metadata.namespace",
-						},
+			},
+			{
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.namespace",
 					},
 				},
-				{
-					Name: "POD_UUID",
-					ValueFrom: &corev1.EnvVarSource{
-						FieldRef: &corev1.ObjectFieldSelector{
-							FieldPath: "metadata.uid",
-						},
+			},
+			{
+				Name: "POD_UUID",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.uid",
 					},
 				},
 			},
+		}
+
+		if v.NetworkType == entity.SRIOVNetworkType {
+			args = generateSRIOVClientCommand(v)
+			envVars = append(envVars, corev1.EnvVar{
+				Name: "NODE_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "spec.nodeName",
+					},
+				},
+			})
+		}
+
+		containers = append(containers, corev1.Container{
+			Name:    fmt.Sprintf("init-network-client-%d", i),
+			Image:   networkcontroller.ClientImage,
+			Command: []string{"/go/bin/client"},
+			Args:    args,
+			Env:     envVars,
 			VolumeMounts: []corev1.VolumeMount{
 				{
 					Name:      "grpc-sock",
@@ -178,6 +324,10 @@ func generateNetwork(session *mongo.Session, pod *entity.Pod) ([]string, []corev
 		}
 		networks = append(networks, network)
 		pod.Networks[i].BridgeName = network.BridgeName
+		pod.Networks[i].NetworkType = network.Type
+		if network.Type == entity.SRIOVNetworkType {
+			pod.Networks[i].Nodes = network.Nodes
+		}
 	}
 
 	nodes := generateNodeLabels(networks)
@@ -185,6 +335,80 @@ func generateNetwork(session *mongo.Session, pod *entity.Pod) ([]string, []corev
 	return nodes, containers, err
 }
 
+//The ip address of the interface is leased from the subnets of network if it's not set,
+//and the static ip address is also leased to avoid the conflict with the leased ones.
+func generateIPLeases(sp *serviceprovider.Container, session *mongo.Session, pod *entity.Pod) error {
+	for i, v := range pod.Networks {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": v.Name}, &network); err != nil {
+			return err
+		}
+		//The subnets are ipv4, the ipv6 address is always static
+		if len(network.Subnets) == 0 || utils.IPFamily(v.IPAddress) == 6 {
+			continue
+		}
+
+		lease := entity.IPLease{
+			Namespace: pod.Namespace,
+			PodName:   pod.Name,
+			IfName:    v.IfName,
+			IPAddress: v.IPAddress,
+		}
+		var err error
+		if v.IPAddress == "" {
+			err = ipam.Allocate(sp, &network, &lease)
+		} else {
+			err = ipam.Reserve(sp, &network, &lease)
+		}
+		if err != nil {
+			return err
+		}
+
+		pod.Networks[i].IPAddress = lease.IPAddress
+		if v.Netmask == "" {
+			pod.Networks[i].Netmask = lease.Netmask
+		}
+	}
+	return nil
+}
+
+//The pod can only be scheduled to the node which has enough free virtual functions for its sriov networks
+func generateSRIOVNodes(sp *serviceprovider.Container, session *mongo.Session, pod *entity.Pod, nodeNames []string) ([]string, error) {
+	required := map[string]int{}
+	for _, v := range pod.Networks {
+		if v.NetworkType == entity.SRIOVNetworkType {
+			required[v.Name]++
+		}
+	}
+
+	for name, count := range required {
+		network := entity.Network{}
+		if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": name}, &network); err != nil {
+			return nil, err
+		}
+		free, err := np.GetFreeVFs(sp, &network)
+		if err != nil {
+			return nil, err
+		}
+
+		names := []string{}
+		for _, node := range network.Nodes {
+			if free[node.Name] >= count {
+				names = append(names, node.Name)
+			}
+		}
+		if len(nodeNames) == 0 {
+			nodeNames = names
+		} else {
+			nodeNames = utils.Intersection(names, nodeNames)
+		}
+		if len(nodeNames) == 0 {
+			return nil, fmt.Errorf("there's no node with enough free virtual functions of the network %s", name)
+		}
+	}
+	return nodeNames, nil
+}
+
 func generateContainerSecurity(pod *entity.Pod) *corev1.SecurityContext {
 	if !pod.Capability {
 		return &corev1.SecurityContext{}
@@ -244,6 +468,10 @@ func CreatePod(sp *serviceprovider.Container, pod *entity.Pod) error {
 		return err
 	}
 
+	if pod.Namespace == "" {
+		pod.Namespace = "default"
+	}
+
 	nodeAffinity := pod.NodeAffinity
 	initContainers := []corev1.Container{}
 	hostNetwork := false
@@ -251,11 +479,17 @@ func CreatePod(sp *serviceprovider.Container, pod *entity.Pod) error {
 	case entity.PodHostNetwork:
 		hostNetwork = true
 	case entity.PodCustomNetwork:
+		if err = generateIPLeases(sp, session, pod); err != nil {
+			break
+		}
 		var tmp []string
 		tmp, initContainers, err = generateNetwork(session, pod)
 		if len(tmp) != 0 {
 			nodeAffinity = utils.Intersection(nodeAffinity, tmp)
 		}
+		if err == nil {
+			nodeAffinity, err = generateSRIOVNodes(sp, session, pod, nodeAffinity)
+		}
 	case entity.PodClusterNetwork:
 		//For cluster network, we won't set the nodeAffinity and any network options.
 	default:
@@ -263,6 +497,7 @@ func CreatePod(sp *serviceprovider.Container, pod *entity.Pod) error {
 	}
 
 	if err != nil {
+		ipam.ReleasePod(session, pod.Namespace, pod.Name)
 		return err
 	}
 
@@ -275,17 +510,50 @@ func CreatePod(sp *serviceprovider.Container, pod *entity.Pod) error {
 		},
 	})
 
+	//The hugepages are mounted only to the containers which request them
+	if volume := kubeutils.GenerateHugepageVolume(pod.Containers); volume != nil {
+		volumes = append(volumes, *volume)
+	}
+
 	var containers []corev1.Container
 	securityContext := generateContainerSecurity(pod)
 	envVars := generateEnvVars(pod)
 	for _, container := range pod.Containers {
+		resources, err := kubeutils.GenerateResources(container)
+		if err != nil {
+			ipam.ReleasePod(session, pod.Namespace, pod.Name)
+			return err
+		}
+		livenessProbe, err := kubeutils.GenerateProbe(container.LivenessProbe)
+		if err != nil {
+			ipam.ReleasePod(session, pod.Namespace, pod.Name)
+			return err
+		}
+		readinessProbe, err := kubeutils.GenerateProbe(container.ReadinessProbe)
+		if err != nil {
+			ipam.ReleasePod(session, pod.Namespace, pod.Name)
+			return err
+		}
+		mounts := volumeMounts
+		if kubeutils.HasHugepages(container) {
+			mounts = append(append([]corev1.VolumeMount{}, volumeMounts...), corev1.VolumeMount{
+				Name:      kubeutils.HugepageVolumeName,
+				MountPath: kubeutils.HugepageMountPath,
+			})
+		}
 		containers = append(containers, corev1.Container{
 			Name:            container.Name,
 			Image:           container.Image,
 			Command:         container.Command,
-			VolumeMounts:    volumeMounts,
+			Args:            container.Args,
+			WorkingDir:      container.WorkingDir,
+			Ports:           kubeutils.GeneratePorts(container),
+			VolumeMounts:    mounts,
 			SecurityContext: securityContext,
-			Env:             envVars,
+			Env:             kubeutils.GenerateEnvVars(envVars, container),
+			Resources:       resources,
+			LivenessProbe:   livenessProbe,
+			ReadinessProbe:  readinessProbe,
 		})
 	}
 
@@ -307,14 +575,24 @@ func CreatePod(sp *serviceprovider.Container, pod *entity.Pod) error {
 		},
 	}
 
-	if pod.Namespace == "" {
-		pod.Namespace = "default"
+	if err = kubeutils.CheckNodeAllocatable(sp, &p.Spec, 1); err != nil {
+		ipam.ReleasePod(session, pod.Namespace, pod.Name)
+		return err
+	}
+
+	if _, err = sp.KubeCtl.CreatePod(&p, pod.Namespace); err != nil {
+		ipam.ReleasePod(session, pod.Namespace, pod.Name)
 	}
-	_, err = sp.KubeCtl.CreatePod(&p, pod.Namespace)
 	return err
 }
 
-// DeletePod will delete pod
+// DeletePod will delete pod and release its ip leases
 func DeletePod(sp *serviceprovider.Container, pod *entity.Pod) error {
-	return sp.KubeCtl.DeletePod(pod.Name, pod.Namespace)
+	if err := sp.KubeCtl.DeletePod(pod.Name, pod.Namespace); err != nil {
+		return err
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	return ipam.ReleasePod(session, pod.Namespace, pod.Name)
 }
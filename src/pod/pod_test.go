@@ -7,10 +7,15 @@ import (
 
 	"github.com/linkernetworks/vortex/src/config"
 	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/ipam"
+	"github.com/linkernetworks/vortex/src/kubeutils"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 	"github.com/moby/moby/pkg/namesgenerator"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/mgo.v2/bson"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func init() {
@@ -99,6 +104,28 @@ func (suite *PodTestSuite) TestCheckPodParameterFail() {
 	}
 }
 
+func (suite *PodTestSuite) TestCheckPodParameterWithLeases() {
+	session := suite.sp.Mongo.NewSession()
+	defer session.Close()
+
+	podName := namesgenerator.GetRandomName(0)
+	lease := entity.IPLease{
+		ID:          bson.NewObjectId(),
+		NetworkName: namesgenerator.GetRandomName(0),
+		IPAddress:   "10.0.0.10",
+		Namespace:   "default",
+		PodName:     podName,
+		IfName:      "eth1",
+	}
+	suite.NoError(session.Insert(entity.IPLeaseCollectionName, &lease))
+	defer session.Remove(entity.IPLeaseCollectionName, "_id", lease.ID)
+
+	//The pod without the namespace is created in the default namespace
+	suite.Error(CheckPodParameter(suite.sp, &entity.Pod{ID: bson.NewObjectId(), Name: podName}))
+	suite.Error(CheckPodParameter(suite.sp, &entity.Pod{ID: bson.NewObjectId(), Name: podName, Namespace: "default"}))
+	suite.NoError(CheckPodParameter(suite.sp, &entity.Pod{ID: bson.NewObjectId(), Name: podName, Namespace: "other"}))
+}
+
 func (suite *PodTestSuite) TestGenerateVolume() {
 	volumeName := namesgenerator.GetRandomName(0)
 	pod := &entity.Pod{
@@ -296,6 +323,212 @@ func (suite *PodTestSuite) TestGenerateClientCommand() {
 		"--route-intf=192.168.3.0/24",
 	}
 	suite.Equal(ans, command)
+
+	//The burst is ignored without the rate
+	podNetwork.QoS = &entity.NetworkQoS{
+		IngressRate:  10000,
+		IngressBurst: 1000,
+		EgressBurst:  1000,
+	}
+	command = generateClientCommand(podNetwork)
+	ans = []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--bridge=" + bName,
+		"--nic=" + ifName,
+		"--ip=1.2.3.4/24",
+		"--vlan=123",
+		"--ingress-rate=10000",
+		"--ingress-burst=1000",
+		"--route-gw=192.168.2.0/24,192.168.2.254",
+		"--route-intf=192.168.3.0/24",
+	}
+	suite.Equal(ans, command)
+}
+
+func (suite *PodTestSuite) TestGenerateClientCommandWithLinuxBridge() {
+	podNetwork := entity.PodNetwork{
+		Name:        "my-linux-net",
+		IfName:      "eth1",
+		IPAddress:   "1.2.3.4",
+		Netmask:     "255.255.255.0",
+		BridgeName:  "vortex-br0",
+		NetworkType: entity.LinuxBridgeNetworkType,
+	}
+	command := generateClientCommand(podNetwork)
+	ans := []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--bridge=vortex-br0",
+		"--linux-bridge",
+		"--nic=eth1",
+		"--ip=1.2.3.4/24",
+	}
+	suite.Equal(ans, command)
+}
+
+func (suite *PodTestSuite) TestCheckPodParameterWithLinuxBridge() {
+	session := suite.sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := entity.Network{
+		ID:         bson.NewObjectId(),
+		Name:       namesgenerator.GetRandomName(0),
+		Type:       entity.LinuxBridgeNetworkType,
+		BridgeName: "vortex-br0",
+		VlanTags:   []int32{100},
+	}
+	session.Insert(entity.NetworkCollectionName, network)
+	defer session.Remove(entity.NetworkCollectionName, "name", network.Name)
+
+	pod := &entity.Pod{
+		ID:   bson.NewObjectId(),
+		Name: namesgenerator.GetRandomName(0),
+		Networks: []entity.PodNetwork{
+			{Name: network.Name, IfName: "eth1", IPAddress: "1.2.3.4", Netmask: "255.255.255.0"},
+		},
+	}
+	suite.NoError(CheckPodParameter(suite.sp, pod))
+
+	//The vlan belongs to the sub-interface of the bridge
+	vlanTag := int32(100)
+	pod.Networks[0].VlanTag = &vlanTag
+	suite.Error(CheckPodParameter(suite.sp, pod))
+}
+
+func (suite *PodTestSuite) TestCheckPodParameterWithMacAddress() {
+	session := suite.sp.Mongo.NewSession()
+	defer session.Close()
+
+	network := entity.Network{
+		ID:         bson.NewObjectId(),
+		Name:       namesgenerator.GetRandomName(0),
+		Type:       entity.OVSKernelspaceNetworkType,
+		BridgeName: "vortex-br0",
+	}
+	session.Insert(entity.NetworkCollectionName, network)
+	defer session.Remove(entity.NetworkCollectionName, "name", network.Name)
+
+	//The mac address is only for the virtual function of the sriov network
+	pod := &entity.Pod{
+		ID:   bson.NewObjectId(),
+		Name: namesgenerator.GetRandomName(0),
+		Networks: []entity.PodNetwork{
+			{Name: network.Name, IfName: "eth1", IPAddress: "1.2.3.4", Netmask: "255.255.255.0", MacAddress: "02:00:00:00:00:01"},
+		},
+	}
+	suite.Error(CheckPodParameter(suite.sp, pod))
+}
+
+func (suite *PodTestSuite) TestGenerateClientCommandWithIPv6() {
+	podNetwork := entity.PodNetwork{
+		Name:      "my-net",
+		IfName:    "eth1",
+		IPAddress: "2001:db8::10",
+		Netmask:   "64",
+		Addresses: []string{"10.0.0.10/24", "2001:db8:1::10/64"},
+		RoutesGw: []entity.PodRouteGw{
+			{
+				DstCIDR: "2001:db8:2::/64",
+				Gateway: "2001:db8::1",
+			},
+		},
+		RoutesIntf: []entity.PodRouteIntf{
+			{
+				DstCIDR: "2001:db8:3::/64",
+			},
+		},
+		BridgeName: "system-62fc3f",
+	}
+	command := generateClientCommand(podNetwork)
+	ans := []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--bridge=system-62fc3f",
+		"--nic=eth1",
+		"--ip=2001:db8::10/64",
+		"--ip=10.0.0.10/24",
+		"--ip=2001:db8:1::10/64",
+		"--route-gw=2001:db8:2::/64,2001:db8::1",
+		"--route-intf=2001:db8:3::/64",
+	}
+	suite.Equal(ans, command)
+
+	//Only the additional addresses
+	podNetwork.IPAddress = ""
+	podNetwork.Netmask = ""
+	command = generateClientCommand(podNetwork)
+	suite.Equal("--ip=10.0.0.10/24", command[3])
+	suite.Equal("--ip=2001:db8:1::10/64", command[4])
+}
+
+func (suite *PodTestSuite) TestCheckNetworkAddresses() {
+	suite.NoError(checkNetworkAddresses(entity.PodNetwork{IPAddress: "1.2.3.4"}))
+	suite.NoError(checkNetworkAddresses(entity.PodNetwork{IPAddress: "1.2.3.4", Netmask: "24"}))
+	suite.NoError(checkNetworkAddresses(entity.PodNetwork{IPAddress: "2001:db8::10", Netmask: "64"}))
+
+	testCases := []struct {
+		cases   string
+		network entity.PodNetwork
+	}{
+		{"IPv6WithoutPrefixLength", entity.PodNetwork{IPAddress: "2001:db8::10"}},
+		{"IPv6WithDottedNetmask", entity.PodNetwork{IPAddress: "2001:db8::10", Netmask: "255.255.255.0"}},
+		{"IPv4WithIPv6PrefixLength", entity.PodNetwork{IPAddress: "1.2.3.4", Netmask: "64"}},
+		{"MixedRoute", entity.PodNetwork{RoutesGw: []entity.PodRouteGw{{DstCIDR: "2001:db8:2::/64", Gateway: "192.168.2.254"}}}},
+	}
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			suite.Error(checkNetworkAddresses(tc.network))
+		})
+	}
+}
+
+func (suite *PodTestSuite) TestGenerateSRIOVClientCommand() {
+	ifName := namesgenerator.GetRandomName(0)
+	var vlanTag int32
+	vlanTag = 100
+	network := entity.PodNetwork{
+		Name:       "my-sriov-net",
+		IfName:     ifName,
+		IPAddress:  "1.2.3.4",
+		Netmask:    "255.255.255.0",
+		MacAddress: "aa:bb:cc:dd:ee:ff",
+		VlanTag:    &vlanTag,
+		RoutesGw: []entity.PodRouteGw{
+			{
+				DstCIDR: "192.168.2.0/24",
+				Gateway: "192.168.2.254",
+			},
+		},
+		NetworkType: entity.SRIOVNetworkType,
+		Nodes: []entity.Node{
+			{
+				Name:          "node1",
+				PhyInterfaces: []entity.PhyInterface{{Name: "eth1", PCIID: "0000:03:00.0", NumVFs: 4}},
+			},
+			{
+				Name:          "node2",
+				PhyInterfaces: []entity.PhyInterface{{Name: "eth2", PCIID: "0000:03:00.0", NumVFs: 4}},
+			},
+		},
+	}
+	command := generateSRIOVClientCommand(network)
+	ans := []string{
+		"--server=unix:///tmp/vortex.sock",
+		"--sriov",
+		"--node=$(NODE_NAME)",
+		"--nic=" + ifName,
+		"--ip=1.2.3.4/24",
+		"--pf=node1,eth1",
+		"--pf=node2,eth2",
+		"--mac=aa:bb:cc:dd:ee:ff",
+		"--vlan=100",
+		"--route-gw=192.168.2.0/24,192.168.2.254",
+	}
+	suite.Equal(ans, command)
+
+	containers, err := generateInitContainer([]entity.PodNetwork{network})
+	suite.NoError(err)
+	suite.Equal(1, len(containers))
+	suite.Equal(ans, containers[0].Args)
+	suite.Equal("NODE_NAME", containers[0].Env[len(containers[0].Env)-1].Name)
 }
 
 func (suite *PodTestSuite) TestGenerateNetwork() {
@@ -359,6 +592,46 @@ func (suite *PodTestSuite) TestGenerateNetworkFail() {
 	suite.Nil(containers)
 }
 
+func (suite *PodTestSuite) TestGenerateIPLeases() {
+	networkName := namesgenerator.GetRandomName(0)
+	network := entity.Network{
+		ID:      bson.NewObjectId(),
+		Name:    networkName,
+		Subnets: []entity.Subnet{{CIDR: "10.1.0.0/24", Gateway: "10.1.0.1"}},
+	}
+	session := suite.sp.Mongo.NewSession()
+	defer session.Close()
+
+	session.Insert(entity.NetworkCollectionName, network)
+	defer session.Remove(entity.NetworkCollectionName, "name", network.Name)
+
+	pod := &entity.Pod{
+		ID:        bson.NewObjectId(),
+		Name:      namesgenerator.GetRandomName(0),
+		Namespace: "default",
+		Networks: []entity.PodNetwork{
+			{Name: networkName, IfName: "eth1"},
+			{Name: networkName, IfName: "eth2", IPAddress: "10.1.0.100"},
+		},
+	}
+
+	err := generateIPLeases(suite.sp, session, pod)
+	suite.NoError(err)
+	defer ipam.ReleasePod(session, pod.Namespace, pod.Name)
+	suite.Equal("10.1.0.2", pod.Networks[0].IPAddress)
+	suite.Equal("255.255.255.0", pod.Networks[0].Netmask)
+	suite.Equal("10.1.0.100", pod.Networks[1].IPAddress)
+	suite.Equal("255.255.255.0", pod.Networks[1].Netmask)
+
+	count, err := session.Count(entity.IPLeaseCollectionName, bson.M{"networkName": networkName})
+	suite.NoError(err)
+	suite.Equal(2, count)
+
+	//The pod with the same name can't be created before the leases are released
+	err = CheckPodParameter(suite.sp, pod)
+	suite.Error(err)
+}
+
 func (suite *PodTestSuite) TestGenerateAffinity() {
 	affinity := generateAffinity([]string{})
 	suite.Nil(affinity.NodeAffinity)
@@ -469,3 +742,125 @@ func (suite *PodTestSuite) TestCreatePodWithNetworkTypes() {
 		})
 	}
 }
+
+func (suite *PodTestSuite) TestCreatePodWithResources() {
+	nodeName := namesgenerator.GetRandomName(0)
+	_, err := suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Create(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				"hugepages-2Mi":       resource.MustParse("1Gi"),
+			},
+		},
+	})
+	suite.NoError(err)
+	defer suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Delete(nodeName, &metav1.DeleteOptions{})
+
+	pod := &entity.Pod{
+		ID:   bson.NewObjectId(),
+		Name: namesgenerator.GetRandomName(0),
+		Containers: []entity.Container{
+			{
+				Name:    "dpdk",
+				Image:   "busybox",
+				Command: []string{"sleep", "3600"},
+				Resources: &entity.ContainerResources{
+					Limits: map[string]string{"cpu": "2", "memory": "1Gi", "hugepages-2Mi": "512Mi"},
+				},
+			},
+			{
+				Name:    "busybox",
+				Image:   "busybox",
+				Command: []string{"sleep", "3600"},
+			},
+		},
+		NetworkType:  entity.PodClusterNetwork,
+		NodeAffinity: []string{nodeName},
+	}
+	suite.NoError(CheckPodParameter(suite.sp, pod))
+	err = CreatePod(suite.sp, pod)
+	suite.NoError(err)
+	defer DeletePod(suite.sp, pod)
+
+	result, err := suite.sp.KubeCtl.GetPod(pod.Name, pod.Namespace)
+	suite.NoError(err)
+	cpu := result.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU]
+	suite.Equal("2", cpu.String())
+	hugepages := result.Spec.Containers[0].Resources.Limits["hugepages-2Mi"]
+	suite.Equal("512Mi", hugepages.String())
+	//The hugepages are only mounted to the container which requests them
+	suite.Contains(result.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: kubeutils.HugepageVolumeName, MountPath: kubeutils.HugepageMountPath})
+	suite.NotContains(result.Spec.Containers[1].VolumeMounts, corev1.VolumeMount{Name: kubeutils.HugepageVolumeName, MountPath: kubeutils.HugepageMountPath})
+
+	//The node doesn't have enough hugepages for the pod
+	another := &entity.Pod{
+		ID:           bson.NewObjectId(),
+		Name:         namesgenerator.GetRandomName(0),
+		Containers:   append([]entity.Container{}, pod.Containers...),
+		NetworkType:  entity.PodClusterNetwork,
+		NodeAffinity: []string{nodeName},
+	}
+	another.Containers[0].Resources = &entity.ContainerResources{
+		Limits: map[string]string{"cpu": "2", "memory": "1Gi", "hugepages-2Mi": "2Gi"},
+	}
+	err = CreatePod(suite.sp, another)
+	suite.Error(err)
+	_, ok := err.(*kubeutils.ResourceError)
+	suite.True(ok)
+}
+
+func (suite *PodTestSuite) TestCreatePodWithContainerOptions() {
+	pod := &entity.Pod{
+		ID:   bson.NewObjectId(),
+		Name: namesgenerator.GetRandomName(0),
+		Containers: []entity.Container{
+			{
+				Name:       "web",
+				Image:      "nginx",
+				Command:    []string{"nginx"},
+				Args:       []string{"-g", "daemon off;"},
+				WorkingDir: "/usr/share/nginx",
+				EnvVars:    map[string]string{"MODE": "web"},
+				Ports:      []entity.ContainerPort{{Name: "http", ContainerPort: 80}},
+				LivenessProbe: &entity.ContainerProbe{
+					Type: entity.ContainerProbeHTTPGet,
+					Path: "/healthz",
+					Port: 80,
+				},
+				ReadinessProbe: &entity.ContainerProbe{
+					Type: entity.ContainerProbeTCPSocket,
+					Port: 80,
+				},
+			},
+			{
+				Name:    "busybox",
+				Image:   "busybox",
+				Command: []string{"sleep", "3600"},
+			},
+		},
+		NetworkType: entity.PodClusterNetwork,
+		EnvVars: map[string]string{
+			"MODE": "default",
+		},
+	}
+	suite.NoError(CheckPodParameter(suite.sp, pod))
+	err := CreatePod(suite.sp, pod)
+	suite.NoError(err)
+	defer DeletePod(suite.sp, pod)
+
+	result, err := suite.sp.KubeCtl.GetPod(pod.Name, pod.Namespace)
+	suite.NoError(err)
+	web, busybox := result.Spec.Containers[0], result.Spec.Containers[1]
+	suite.Equal([]string{"-g", "daemon off;"}, web.Args)
+	suite.Equal("/usr/share/nginx", web.WorkingDir)
+	suite.Equal(int32(80), web.Ports[0].ContainerPort)
+	suite.Equal("/healthz", web.LivenessProbe.HTTPGet.Path)
+	suite.NotNil(web.ReadinessProbe.TCPSocket)
+	suite.Nil(busybox.LivenessProbe)
+
+	//The shared environment variables are overridden by the container
+	suite.Equal([]corev1.EnvVar{{Name: "MODE", Value: "web"}}, web.Env)
+	suite.Equal([]corev1.EnvVar{{Name: "MODE", Value: "default"}}, busybox.Env)
+}
@@ -616,6 +616,27 @@ func GetController(sp *serviceprovider.Container, id string) (entity.ControllerM
 	return controller, nil
 }
 
+// GetDeploymentMetric will get the average value of the metric of the deployment's pods
+// The pods are created by the ReplicaSet of the deployment, so their names are the deployment name and two hashes.
+func GetDeploymentMetric(sp *serviceprovider.Container, namespace string, id string, metric string) (float64, error) {
+	expression := Expression{}
+	expression.Metrics = []string{metric}
+	expression.QueryLabels = map[string]string{"namespace": namespace, "pod": id + "-[a-z0-9]+-[a-z0-9]+"}
+
+	str := basicExpr(expression.Metrics)
+	str = queryExpr(str, expression.QueryLabels)
+	str = avgExpr(str)
+	results, err := query(sp, str)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, fmt.Errorf("the metric %s of the deployment %s doesn't have any value", metric, id)
+	}
+
+	return float64(results[0].Value), nil
+}
+
 // GetNode will get node metrics
 func GetNode(sp *serviceprovider.Container, id string, rs RangeSetting) (entity.NodeMetrics, error) {
 	node := entity.NodeMetrics{}
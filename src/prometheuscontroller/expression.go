@@ -64,6 +64,13 @@ func sumExpr(expr string) string {
 	return expr
 }
 
+// Append the avg syntax
+func avgExpr(expr string) string {
+	expr = fmt.Sprintf("avg(%s)", expr)
+
+	return expr
+}
+
 // Append a duration for expression
 func durationExpr(expr string, duration int) string {
 	expr = fmt.Sprintf("%s[%vm]", expr, duration)
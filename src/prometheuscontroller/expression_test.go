@@ -66,6 +66,11 @@ func (suite *PrometheusExprTestSuite) TestSumExpr() {
 	suite.Equal(`sum(TEST_STRING)`, str)
 }
 
+func (suite *PrometheusExprTestSuite) TestAvgExpr() {
+	str := avgExpr("TEST_STRING")
+	suite.Equal(`avg(TEST_STRING)`, str)
+}
+
 func (suite *PrometheusExprTestSuite) TestDurationExpr() {
 	var duration = 1
 	str := durationExpr("TEST_STRING", duration)
@@ -1,12 +1,21 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/deployment"
+	"github.com/linkernetworks/vortex/src/ipam"
+	"github.com/linkernetworks/vortex/src/networkpolicy"
+	"github.com/linkernetworks/vortex/src/ovscontroller"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
 )
 
@@ -31,9 +40,47 @@ func (a *App) Start(host, port string) error {
 
 	a.InitilizeService()
 
+	// release the ip leases of the pods which have gone away
+	go ipam.CollectStaleLeases(a.ServiceProvider, time.Minute)
+
+	// keep the history of the ovs port stats to calculate the rates
+	go ovscontroller.PollPortStats(a.ServiceProvider, ovscontroller.PortStatsInterval)
+
+	// remove the veth ports left on the ovs bridges by the pods which died uncleanly
+	go ovscontroller.CollectOrphanedPorts(a.ServiceProvider, ovscontroller.PortGCInterval)
+
+	// push the flows of the network policies again when the pods come and go
+	go networkpolicy.SyncPolicies(a.ServiceProvider, networkpolicy.PolicySyncInterval)
+
+	// remove the mirrors after their TTL
+	go ovscontroller.CollectExpiredMirrors(a.ServiceProvider, ovscontroller.MirrorReapInterval)
+
+	// scale the deployments by the prometheus metrics of their autoscalers
+	go deployment.AutoscaleDeployments(a.ServiceProvider, deployment.AutoscaleInterval)
+
 	bind := net.JoinHostPort(host, port)
+	srv := &http.Server{Addr: bind, Handler: a.AppRoute()}
 
-	return http.ListenAndServe(bind, a.AppRoute())
+	// stop accepting the requests and close the connections to the nodes after the in-flight requests finish
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		logger.Infof("Shutting down the server")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warnf("Shutdown the server fail: %v", err)
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	<-done
+	return a.ServiceProvider.Close()
 }
 
 // InitilizeService weavering services with global variables inside server package
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/utils/timeutils"
 	"github.com/linkernetworks/vortex/src/deployment"
 	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/kubeutils"
 	response "github.com/linkernetworks/vortex/src/net/http"
 	"github.com/linkernetworks/vortex/src/server/backend"
 	"github.com/linkernetworks/vortex/src/service"
@@ -69,6 +71,8 @@ func createAppHandler(ctx *web.Context) {
 	if err := deployment.CreateDeployment(sp, &p.Deployment); err != nil {
 		if errors.IsAlreadyExists(err) {
 			response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("Deployment Name: %s already existed", p.Deployment.Name))
+		} else if _, ok := err.(*kubeutils.ResourceError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
 		} else {
 			response.InternalServerError(req.Request, resp.ResponseWriter, err)
 		}
@@ -94,6 +98,10 @@ func createAppHandler(ctx *web.Context) {
 		return
 	}
 
+	if _, err := deployment.RecordRevision(sp, &p.Deployment, 0); err != nil {
+		logger.Warnf("record the revision of the deployment %s fail: %v", p.Deployment.Name, err)
+	}
+
 	p.Service.OwnerID = bson.ObjectIdHex(userID)
 	if err := session.Insert(entity.ServiceCollectionName, &p.Service); err != nil {
 		if mgo.IsDup(err) {
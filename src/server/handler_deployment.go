@@ -7,10 +7,12 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/utils/timeutils"
 	"github.com/linkernetworks/vortex/src/deployment"
 	"github.com/linkernetworks/vortex/src/entity"
 	"github.com/linkernetworks/vortex/src/kubernetes"
+	"github.com/linkernetworks/vortex/src/kubeutils"
 	response "github.com/linkernetworks/vortex/src/net/http"
 	"github.com/linkernetworks/vortex/src/net/http/query"
 	"github.com/linkernetworks/vortex/src/server/backend"
@@ -65,6 +67,8 @@ func createDeploymentHandler(ctx *web.Context) {
 			response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("Create setting has conflict: %v", err))
 		} else if errors.IsInvalid(err) {
 			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("Create setting is invalid: %v", err))
+		} else if _, ok := err.(*kubeutils.ResourceError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
 		} else {
 			response.InternalServerError(req.Request, resp.ResponseWriter, err)
 		}
@@ -79,11 +83,346 @@ func createDeploymentHandler(ctx *web.Context) {
 		}
 		return
 	}
+	if _, err := deployment.RecordRevision(sp, &p, 0); err != nil {
+		logger.Warnf("record the revision of the deployment %s fail: %v", p.Name, err)
+	}
 	// find owner in user entity
 	p.CreatedBy, _ = backend.FindUserByID(session, p.OwnerID)
 	resp.WriteHeaderAndEntity(http.StatusCreated, p)
 }
 
+func updateDeploymentHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	p := entity.Deployment{}
+	if err := req.ReadEntity(&p); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := sp.Validator.Struct(p); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	stored := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": bson.ObjectIdHex(id)}, &stored); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	// keep the fields generated when the deployment is created
+	p.ID = stored.ID
+	p.OwnerID = stored.OwnerID
+	p.CreatedAt = stored.CreatedAt
+	p.Autoscaler = stored.Autoscaler
+	p.LeaseToken = stored.LeaseToken
+	if err := deployment.CheckDeploymentUpdate(sp, &stored, &p); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := deployment.UpdateDeployment(sp, &stored, &p); err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else if errors.IsConflict(err) {
+			response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("Update setting has conflict: %v", err))
+		} else if errors.IsInvalid(err) {
+			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("Update setting is invalid: %v", err))
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := session.C(entity.DeploymentCollectionName).UpdateId(p.ID, &p); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if _, err := deployment.RecordRevision(sp, &p, 0); err != nil {
+		logger.Warnf("record the revision of the deployment %s fail: %v", p.Name, err)
+	}
+
+	p.CreatedBy, _ = backend.FindUserByID(session, p.OwnerID)
+	resp.WriteEntity(p)
+}
+
+func listDeploymentRevisionsHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	p := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": bson.ObjectIdHex(id)}, &p); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	revisions, err := deployment.ListRevisions(sp, &p)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	resp.WriteEntity(revisions)
+}
+
+func rollbackDeploymentHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	number, err := strconv.ParseInt(req.QueryParameter("revision"), 10, 64)
+	if err != nil || number < 1 {
+		response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("the revision should be a positive number"))
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	stored := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": bson.ObjectIdHex(id)}, &stored); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	revision, err := deployment.GetRevision(session, stored.ID, number)
+	if err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, fmt.Errorf("the revision %d of the deployment %s doesn't exist", number, stored.Name))
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	p, err := deployment.GenerateRollback(&stored, revision)
+	if err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if err := deployment.CheckDeploymentUpdate(sp, &stored, p); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := deployment.UpdateDeployment(sp, &stored, p); err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else if errors.IsConflict(err) {
+			response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("Rollback setting has conflict: %v", err))
+		} else if errors.IsInvalid(err) {
+			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("Rollback setting is invalid: %v", err))
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := session.C(entity.DeploymentCollectionName).UpdateId(p.ID, p); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if _, err := deployment.RecordRevision(sp, p, number); err != nil {
+		logger.Warnf("record the revision of the deployment %s fail: %v", p.Name, err)
+	}
+
+	p.CreatedBy, _ = backend.FindUserByID(session, p.OwnerID)
+	resp.WriteEntity(p)
+}
+
+func scaleDeploymentHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	scale := entity.DeploymentScale{}
+	if err := req.ReadEntity(&scale); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := sp.Validator.Struct(scale); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	p := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": bson.ObjectIdHex(id)}, &p); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	// the replicas are managed by the autoscaler
+	if p.Autoscaler != nil {
+		response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("the deployment %s is scaled by the autoscaler, please delete the autoscaler first", p.Name))
+		return
+	}
+
+	if err := deployment.CheckScale(sp, &p, scale.Replicas); err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := deployment.ScaleDeployment(sp, &p, scale.Replicas); err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := session.C(entity.DeploymentCollectionName).UpdateId(p.ID, bson.M{"$set": bson.M{"replicas": p.Replicas}}); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	p.CreatedBy, _ = backend.FindUserByID(session, p.OwnerID)
+	resp.WriteEntity(p)
+}
+
+func setDeploymentAutoscalerHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	autoscaler := entity.DeploymentAutoscaler{}
+	if err := req.ReadEntity(&autoscaler); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := sp.Validator.Struct(autoscaler); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	p := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": bson.ObjectIdHex(id)}, &p); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	p.Autoscaler = &autoscaler
+	if err := deployment.CheckAutoscaler(sp, &p); err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := deployment.SetAutoscaler(sp, &p); err != nil {
+		if errors.IsInvalid(err) {
+			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("Autoscaler setting is invalid: %v", err))
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := session.C(entity.DeploymentCollectionName).UpdateId(p.ID, bson.M{"$set": bson.M{"autoscaler": p.Autoscaler}}); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	p.CreatedBy, _ = backend.FindUserByID(session, p.OwnerID)
+	resp.WriteEntity(p)
+}
+
+func deleteDeploymentAutoscalerHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	p := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": bson.ObjectIdHex(id)}, &p); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	if err := deployment.DeleteAutoscaler(sp, &p); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := session.C(entity.DeploymentCollectionName).UpdateId(p.ID, bson.M{"$unset": bson.M{"autoscaler": ""}}); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	resp.WriteEntity(response.ActionResponse{
+		Error:   false,
+		Message: "Delete success",
+	})
+}
+
 func deleteDeploymentHandler(ctx *web.Context) {
 	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
 
@@ -107,6 +446,11 @@ func deleteDeploymentHandler(ctx *web.Context) {
 		return
 	}
 
+	if err := deployment.DeleteRevisions(session, p.ID); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
 	if err := session.Remove(entity.DeploymentCollectionName, "_id", bson.ObjectIdHex(id)); err != nil {
 		switch err {
 		case mgo.ErrNotFound:
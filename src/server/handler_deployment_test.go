@@ -18,6 +18,7 @@ import (
 	"github.com/moby/moby/pkg/namesgenerator"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/mgo.v2/bson"
+	appsv1 "k8s.io/api/apps/v1"
 )
 
 func init() {
@@ -155,6 +156,470 @@ func (suite *DeploymentTestSuite) TestCreateDeploymentFail() {
 	assertResponseCode(suite.T(), http.StatusBadRequest, httpWriter)
 }
 
+func (suite *DeploymentTestSuite) TestUpdateDeployment() {
+	containers := []entity.Container{
+		{
+			Name:    namesgenerator.GetRandomName(0),
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		},
+	}
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:           bson.NewObjectId(),
+		OwnerID:      bson.NewObjectId(),
+		Name:         tName,
+		Namespace:    "default",
+		Labels:       map[string]string{},
+		EnvVars:      map[string]string{},
+		Containers:   containers,
+		Volumes:      []entity.DeploymentVolume{},
+		Networks:     []entity.DeploymentNetwork{},
+		NetworkType:  entity.DeploymentClusterNetwork,
+		NodeAffinity: []string{},
+		Replicas:     1,
+	}
+	err := p.CreateDeployment(suite.sp, &deploy)
+	suite.NoError(err)
+	defer p.DeleteDeployment(suite.sp, &deploy)
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+
+	deploy.Containers[0].Image = "busybox:1.29"
+	deploy.EnvVars = map[string]string{"DEBUG": "1"}
+	deploy.Replicas = 2
+	deploy.Strategy = &entity.DeploymentStrategy{
+		Type:           entity.DeploymentRollingUpdateStrategy,
+		MaxSurge:       "1",
+		MaxUnavailable: "0",
+	}
+	bodyBytes, err := json.MarshalIndent(deploy, "", "  ")
+	suite.NoError(err)
+
+	bodyReader := strings.NewReader(string(bodyBytes))
+	httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex(), bodyReader)
+	suite.NoError(err)
+
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	//The kubernetes deployment is patched
+	k8sDeploy, err := suite.sp.KubeCtl.GetDeployment(tName, "default")
+	suite.NoError(err)
+	suite.Equal(int32(2), *k8sDeploy.Spec.Replicas)
+	suite.Equal("busybox:1.29", k8sDeploy.Spec.Template.Spec.Containers[0].Image)
+	suite.Equal("DEBUG", k8sDeploy.Spec.Template.Spec.Containers[0].Env[0].Name)
+	suite.Equal(appsv1.RollingUpdateDeploymentStrategyType, k8sDeploy.Spec.Strategy.Type)
+
+	//The mongo record is kept in sync
+	retDeployment := entity.Deployment{}
+	err = suite.session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": deploy.ID}, &retDeployment)
+	suite.NoError(err)
+	suite.Equal("busybox:1.29", retDeployment.Containers[0].Image)
+	suite.Equal(int32(2), retDeployment.Replicas)
+	suite.Equal(entity.DeploymentRollingUpdateStrategy, retDeployment.Strategy.Type)
+}
+
+func (suite *DeploymentTestSuite) TestUpdateDeploymentFail() {
+	containers := []entity.Container{
+		{
+			Name:    namesgenerator.GetRandomName(0),
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		},
+	}
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:           bson.NewObjectId(),
+		OwnerID:      bson.NewObjectId(),
+		Name:         tName,
+		Namespace:    "default",
+		Labels:       map[string]string{},
+		EnvVars:      map[string]string{},
+		Containers:   containers,
+		Volumes:      []entity.DeploymentVolume{},
+		Networks:     []entity.DeploymentNetwork{},
+		NetworkType:  entity.DeploymentClusterNetwork,
+		NodeAffinity: []string{},
+		Replicas:     1,
+	}
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+
+	renamed := deploy
+	renamed.Name = namesgenerator.GetRandomName(0)
+	hostNetwork := deploy
+	hostNetwork.NetworkType = entity.DeploymentHostNetwork
+	invalidStrategy := deploy
+	invalidStrategy.Strategy = &entity.DeploymentStrategy{Type: entity.DeploymentRollingUpdateStrategy, MaxSurge: "one"}
+	invalidResources := deploy
+	invalidResources.Containers = []entity.Container{
+		{Name: containers[0].Name, Image: "busybox", Resources: &entity.ContainerResources{Requests: map[string]string{"cpu": "2"}, Limits: map[string]string{"cpu": "1"}}},
+	}
+	duplicatedPorts := deploy
+	duplicatedPorts.Containers = []entity.Container{
+		{Name: containers[0].Name, Image: "busybox", Ports: []entity.ContainerPort{{ContainerPort: 80}, {ContainerPort: 80}}},
+	}
+	//None of the nodes has enough cpus
+	notEnoughResources := deploy
+	notEnoughResources.Containers = []entity.Container{
+		{Name: containers[0].Name, Image: "busybox", Resources: &entity.ContainerResources{Requests: map[string]string{"cpu": "1000"}}},
+	}
+
+	testCases := []struct {
+		cases     string
+		id        string
+		deploy    entity.Deployment
+		errorCode int
+	}{
+		{"ChangeName", deploy.ID.Hex(), renamed, http.StatusBadRequest},
+		{"ChangeNetworkType", deploy.ID.Hex(), hostNetwork, http.StatusBadRequest},
+		{"InvalidStrategy", deploy.ID.Hex(), invalidStrategy, http.StatusBadRequest},
+		{"InvalidResources", deploy.ID.Hex(), invalidResources, http.StatusBadRequest},
+		{"NotEnoughResources", deploy.ID.Hex(), notEnoughResources, http.StatusBadRequest},
+		{"DuplicatedPorts", deploy.ID.Hex(), duplicatedPorts, http.StatusBadRequest},
+		{"DeploymentNotFound", bson.NewObjectId().Hex(), deploy, http.StatusNotFound},
+		//The kubernetes deployment doesn't exist
+		{"KubernetesNotFound", deploy.ID.Hex(), deploy, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			bodyBytes, err := json.MarshalIndent(tc.deploy, "", "  ")
+			suite.NoError(err)
+
+			bodyReader := strings.NewReader(string(bodyBytes))
+			httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+tc.id, bodyReader)
+			suite.NoError(err)
+
+			httpRequest.Header.Add("Content-Type", "application/json")
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, tc.errorCode, httpWriter)
+		})
+	}
+}
+
+func (suite *DeploymentTestSuite) TestScaleDeployment() {
+	containers := []entity.Container{
+		{
+			Name:    namesgenerator.GetRandomName(0),
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		},
+	}
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:           bson.NewObjectId(),
+		OwnerID:      bson.NewObjectId(),
+		Name:         tName,
+		Namespace:    "default",
+		Labels:       map[string]string{},
+		EnvVars:      map[string]string{},
+		Containers:   containers,
+		Volumes:      []entity.DeploymentVolume{},
+		Networks:     []entity.DeploymentNetwork{},
+		NetworkType:  entity.DeploymentClusterNetwork,
+		NodeAffinity: []string{},
+		Replicas:     1,
+	}
+	err := p.CreateDeployment(suite.sp, &deploy)
+	suite.NoError(err)
+	defer p.DeleteDeployment(suite.sp, &deploy)
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+
+	bodyReader := strings.NewReader(`{"replicas": 3}`)
+	httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex()+"/scale", bodyReader)
+	suite.NoError(err)
+
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	k8sDeploy, err := suite.sp.KubeCtl.GetDeployment(tName, "default")
+	suite.NoError(err)
+	suite.Equal(int32(3), *k8sDeploy.Spec.Replicas)
+
+	retDeployment := entity.Deployment{}
+	err = suite.session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": deploy.ID}, &retDeployment)
+	suite.NoError(err)
+	suite.Equal(int32(3), retDeployment.Replicas)
+}
+
+func (suite *DeploymentTestSuite) TestScaleDeploymentFail() {
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:          bson.NewObjectId(),
+		OwnerID:     bson.NewObjectId(),
+		Name:        tName,
+		Namespace:   "default",
+		NetworkType: entity.DeploymentCustomNetwork,
+		Networks: []entity.DeploymentNetwork{
+			{Name: namesgenerator.GetRandomName(0), IfName: "eth1", IPAddress: "1.2.3.4", Netmask: "255.255.255.0"},
+		},
+		Replicas: 1,
+	}
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+
+	autoscaled := deploy
+	autoscaled.ID = bson.NewObjectId()
+	autoscaled.Name = namesgenerator.GetRandomName(0)
+	autoscaled.Networks = []entity.DeploymentNetwork{}
+	autoscaled.Autoscaler = &entity.DeploymentAutoscaler{MinReplicas: 1, MaxReplicas: 3, TargetCPUUtilization: 50}
+	suite.session.C(entity.DeploymentCollectionName).Insert(autoscaled)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", autoscaled.Name)
+
+	testCases := []struct {
+		cases     string
+		id        string
+		body      string
+		errorCode int
+	}{
+		{"InvalidReplicas", deploy.ID.Hex(), `{"replicas": 0}`, http.StatusBadRequest},
+		{"StaticIPAddress", deploy.ID.Hex(), `{"replicas": 2}`, http.StatusBadRequest},
+		{"Autoscaled", autoscaled.ID.Hex(), `{"replicas": 2}`, http.StatusConflict},
+		{"DeploymentNotFound", bson.NewObjectId().Hex(), `{"replicas": 2}`, http.StatusNotFound},
+		//The kubernetes deployment doesn't exist
+		{"KubernetesNotFound", deploy.ID.Hex(), `{"replicas": 1}`, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			bodyReader := strings.NewReader(tc.body)
+			httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+tc.id+"/scale", bodyReader)
+			suite.NoError(err)
+
+			httpRequest.Header.Add("Content-Type", "application/json")
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, tc.errorCode, httpWriter)
+		})
+	}
+}
+
+func (suite *DeploymentTestSuite) TestDeploymentAutoscaler() {
+	containers := []entity.Container{
+		{
+			Name:    namesgenerator.GetRandomName(0),
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		},
+	}
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:           bson.NewObjectId(),
+		OwnerID:      bson.NewObjectId(),
+		Name:         tName,
+		Namespace:    "default",
+		Labels:       map[string]string{},
+		EnvVars:      map[string]string{},
+		Containers:   containers,
+		Volumes:      []entity.DeploymentVolume{},
+		Networks:     []entity.DeploymentNetwork{},
+		NetworkType:  entity.DeploymentClusterNetwork,
+		NodeAffinity: []string{},
+		Replicas:     1,
+	}
+	err := p.CreateDeployment(suite.sp, &deploy)
+	suite.NoError(err)
+	defer p.DeleteDeployment(suite.sp, &deploy)
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+
+	testCases := []struct {
+		cases     string
+		body      string
+		errorCode int
+	}{
+		{"MaxLessThanMin", `{"minReplicas": 3, "maxReplicas": 2, "targetCPUUtilization": 50}`, http.StatusBadRequest},
+		{"WithoutTarget", `{"minReplicas": 1, "maxReplicas": 3}`, http.StatusBadRequest},
+		{"InvalidMetric", `{"minReplicas": 1, "maxReplicas": 3, "metric": "up{}", "targetValue": 10}`, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			bodyReader := strings.NewReader(tc.body)
+			httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex()+"/autoscaler", bodyReader)
+			suite.NoError(err)
+
+			httpRequest.Header.Add("Content-Type", "application/json")
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, tc.errorCode, httpWriter)
+		})
+	}
+
+	bodyReader := strings.NewReader(`{"minReplicas": 1, "maxReplicas": 3, "targetCPUUtilization": 50}`)
+	httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex()+"/autoscaler", bodyReader)
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	hpa, err := suite.sp.KubeCtl.GetHorizontalPodAutoscaler(tName, "default")
+	suite.NoError(err)
+	suite.Equal(int32(3), hpa.Spec.MaxReplicas)
+
+	retDeployment := entity.Deployment{}
+	err = suite.session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": deploy.ID}, &retDeployment)
+	suite.NoError(err)
+	suite.Equal(int32(50), retDeployment.Autoscaler.TargetCPUUtilization)
+
+	httpRequest, err = http.NewRequest("DELETE", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex()+"/autoscaler", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	_, err = suite.sp.KubeCtl.GetHorizontalPodAutoscaler(tName, "default")
+	suite.Error(err)
+	retDeployment = entity.Deployment{}
+	err = suite.session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": deploy.ID}, &retDeployment)
+	suite.NoError(err)
+	suite.Nil(retDeployment.Autoscaler)
+}
+
+func (suite *DeploymentTestSuite) TestRollbackDeployment() {
+	containers := []entity.Container{
+		{
+			Name:    namesgenerator.GetRandomName(0),
+			Image:   "busybox",
+			Command: []string{"sleep", "3600"},
+		},
+	}
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:           bson.NewObjectId(),
+		OwnerID:      bson.NewObjectId(),
+		Name:         tName,
+		Namespace:    "default",
+		Labels:       map[string]string{},
+		EnvVars:      map[string]string{},
+		Containers:   containers,
+		Volumes:      []entity.DeploymentVolume{},
+		Networks:     []entity.DeploymentNetwork{},
+		NetworkType:  entity.DeploymentClusterNetwork,
+		NodeAffinity: []string{},
+		Replicas:     1,
+	}
+	err := p.CreateDeployment(suite.sp, &deploy)
+	suite.NoError(err)
+	defer p.DeleteDeployment(suite.sp, &deploy)
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+	_, err = p.RecordRevision(suite.sp, &deploy, 0)
+	suite.NoError(err)
+	defer p.DeleteRevisions(suite.session, deploy.ID)
+
+	//The update is the second revision
+	updated := deploy
+	updated.Containers = []entity.Container{containers[0]}
+	updated.Containers[0].Image = "busybox:1.29"
+	updated.Replicas = 2
+	bodyBytes, err := json.MarshalIndent(updated, "", "  ")
+	suite.NoError(err)
+
+	bodyReader := strings.NewReader(string(bodyBytes))
+	httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex(), bodyReader)
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	httpRequest, err = http.NewRequest("GET", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex()+"/revisions", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	revisions := []entity.DeploymentRevision{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &revisions)
+	suite.NoError(err)
+	suite.Len(revisions, 2)
+	suite.Equal(int64(1), revisions[0].Revision)
+	suite.Equal("busybox", revisions[0].Deployment.Containers[0].Image)
+	suite.Equal("busybox:1.29", revisions[1].Deployment.Containers[0].Image)
+
+	httpRequest, err = http.NewRequest("POST", "http://localhost:7890/v1/deployments/"+deploy.ID.Hex()+"/rollback?revision=1", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	//The kubernetes deployment and the mongo record are restored
+	k8sDeploy, err := suite.sp.KubeCtl.GetDeployment(tName, "default")
+	suite.NoError(err)
+	suite.Equal(int32(1), *k8sDeploy.Spec.Replicas)
+	suite.Equal("busybox", k8sDeploy.Spec.Template.Spec.Containers[0].Image)
+
+	retDeployment := entity.Deployment{}
+	err = suite.session.FindOne(entity.DeploymentCollectionName, bson.M{"_id": deploy.ID}, &retDeployment)
+	suite.NoError(err)
+	suite.Equal("busybox", retDeployment.Containers[0].Image)
+	suite.Equal(int32(1), retDeployment.Replicas)
+
+	//The rollback is the third revision
+	revision, err := p.GetRevision(suite.session, deploy.ID, 3)
+	suite.NoError(err)
+	suite.Equal(int64(1), revision.RollbackTo)
+}
+
+func (suite *DeploymentTestSuite) TestRollbackDeploymentFail() {
+	tName := namesgenerator.GetRandomName(0)
+	deploy := entity.Deployment{
+		ID:          bson.NewObjectId(),
+		OwnerID:     bson.NewObjectId(),
+		Name:        tName,
+		Namespace:   "default",
+		NetworkType: entity.DeploymentClusterNetwork,
+		Replicas:    1,
+	}
+	suite.session.C(entity.DeploymentCollectionName).Insert(deploy)
+	defer suite.session.Remove(entity.DeploymentCollectionName, "name", tName)
+
+	testCases := []struct {
+		cases     string
+		id        string
+		revision  string
+		errorCode int
+	}{
+		{"WithoutRevision", deploy.ID.Hex(), "", http.StatusBadRequest},
+		{"InvalidRevision", deploy.ID.Hex(), "0", http.StatusBadRequest},
+		{"RevisionNotFound", deploy.ID.Hex(), "1", http.StatusNotFound},
+		{"DeploymentNotFound", bson.NewObjectId().Hex(), "1", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			httpRequest, err := http.NewRequest("POST", "http://localhost:7890/v1/deployments/"+tc.id+"/rollback?revision="+tc.revision, nil)
+			suite.NoError(err)
+
+			httpRequest.Header.Add("Content-Type", "application/json")
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, tc.errorCode, httpWriter)
+		})
+	}
+}
+
 func (suite *DeploymentTestSuite) TestDeleteDeployment() {
 	namespace := "default"
 	containers := []entity.Container{
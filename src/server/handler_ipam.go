@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/linkernetworks/vortex/src/deployment"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/ipam"
+	response "github.com/linkernetworks/vortex/src/net/http"
+	"github.com/linkernetworks/vortex/src/net/http/query"
+	"github.com/linkernetworks/vortex/src/networkpolicy"
+	"github.com/linkernetworks/vortex/src/utils"
+	"github.com/linkernetworks/vortex/src/web"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func leaseIPHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	//Get the parameter
+	query := query.New(req.Request.URL.Query())
+	params := map[string]string{}
+	for _, key := range []string{"network", "namespace", "pod", "ifName", "deployment", "token"} {
+		value, exist := query.Str(key)
+		if !exist {
+			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("The %s must not be empty", key))
+			return
+		}
+		params[key] = value
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	//The init container has the lease token of the deployment in its lease url
+	deploy := entity.Deployment{}
+	if err := session.FindOne(entity.DeploymentCollectionName, bson.M{"namespace": params["namespace"], "name": params["deployment"]}, &deploy); err != nil {
+		if err == mgo.ErrNotFound {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+	if deploy.LeaseToken == "" || subtle.ConstantTimeCompare([]byte(deploy.LeaseToken), []byte(params["token"])) != 1 {
+		response.Forbidden(req.Request, resp.ResponseWriter, fmt.Errorf("The token of the deployment %s is invalid", params["deployment"]))
+		return
+	}
+
+	//Only the pod created by the deployment can lease the ip address
+	pod, err := sp.KubeCtl.GetPod(params["pod"], params["namespace"])
+	if err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+	if pod.Labels[deployment.DefaultLabel] != params["deployment"] {
+		response.Forbidden(req.Request, resp.ResponseWriter, fmt.Errorf("The pod %s isn't created by the deployment %s", params["pod"], params["deployment"]))
+		return
+	}
+
+	network := entity.Network{}
+	if err := session.FindOne(entity.NetworkCollectionName, bson.M{"name": params["network"]}, &network); err != nil {
+		if err == mgo.ErrNotFound {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	lease := entity.IPLease{
+		Namespace:      params["namespace"],
+		PodName:        params["pod"],
+		IfName:         params["ifName"],
+		DeploymentName: params["deployment"],
+	}
+	if err := ipam.Allocate(sp, &network, &lease); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	// the pod may be selected by the network policies, the flows are pushed in the background
+	// so an unreachable node doesn't stall the pods
+	networkpolicy.Trigger(&network)
+
+	//The init container reads the ip address in the CIDR format
+	if output, _ := query.Str("output"); output == "cidr" {
+		resp.Header().Set("Content-Type", "text/plain")
+		resp.Write([]byte(utils.IPToCIDR(lease.IPAddress, lease.Netmask)))
+		return
+	}
+	resp.WriteEntity(lease)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/linkernetworks/vortex/src/entity"
+	response "github.com/linkernetworks/vortex/src/net/http"
+	"github.com/linkernetworks/vortex/src/ovscontroller"
+	"github.com/linkernetworks/vortex/src/web"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func createMirrorHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+
+	mirror := entity.Mirror{}
+	if err := req.ReadEntity(&mirror); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if err := sp.Validator.Struct(mirror); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	var network entity.Network
+	if err := session.C(entity.NetworkCollectionName).FindId(bson.ObjectIdHex(id)).One(&network); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	if err := ovscontroller.ValidateMirror(&network, &mirror); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session.C(entity.MirrorCollectionName).EnsureIndex(mgo.Index{
+		Key:    []string{"networkID", "name"},
+		Unique: true,
+	})
+	if count, err := session.Count(entity.MirrorCollectionName, bson.M{"networkID": network.ID, "name": mirror.Name}); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	} else if count != 0 {
+		response.Conflict(req.Request, resp, fmt.Errorf("Mirror Name: %s already existed", mirror.Name))
+		return
+	}
+
+	if err := ovscontroller.CreateMirror(sp, &network, &mirror); err != nil {
+		if _, ok := err.(*ovscontroller.MirrorPodError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		} else if _, ok := err.(*ovscontroller.MirrorPortError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		} else if mgo.IsDup(err) {
+			response.Conflict(req.Request, resp, fmt.Errorf("Mirror Name: %s already existed", mirror.Name))
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusCreated, mirror)
+}
+
+func listMirrorsHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	mirrors := []entity.Mirror{}
+	if err := session.FindAll(entity.MirrorCollectionName, bson.M{"networkID": bson.ObjectIdHex(id)}, &mirrors); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteEntity(mirrors)
+}
+
+func getMirrorHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+	mirrorID := req.PathParameter("mirrorID")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	mirror := entity.Mirror{}
+	if err := session.FindOne(entity.MirrorCollectionName, bson.M{"_id": bson.ObjectIdHex(mirrorID), "networkID": bson.ObjectIdHex(id)}, &mirror); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	resp.WriteEntity(mirror)
+}
+
+func deleteMirrorHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+	mirrorID := req.PathParameter("mirrorID")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	mirror := entity.Mirror{}
+	if err := session.FindOne(entity.MirrorCollectionName, bson.M{"_id": bson.ObjectIdHex(mirrorID), "networkID": bson.ObjectIdHex(id)}, &mirror); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	if err := ovscontroller.DeleteMirror(sp, &mirror); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	resp.WriteEntity(response.ActionResponse{
+		Error:   false,
+		Message: "Delete success",
+	})
+}
@@ -8,9 +8,11 @@ import (
 
 	"github.com/linkernetworks/utils/timeutils"
 	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/ipam"
 	"github.com/linkernetworks/vortex/src/kubeutils"
 	response "github.com/linkernetworks/vortex/src/net/http"
 	"github.com/linkernetworks/vortex/src/net/http/query"
+	"github.com/linkernetworks/vortex/src/networkpolicy"
 	np "github.com/linkernetworks/vortex/src/networkprovider"
 	"github.com/linkernetworks/vortex/src/server/backend"
 	"github.com/linkernetworks/vortex/src/web"
@@ -49,14 +51,60 @@ func createNetworkHandler(ctx *web.Context) {
 			Unique: true,
 		})
 
+	// the bridge name is generated by the network name, so the existing network should be checked before creating the bridges
+	if count, err := session.Count(entity.NetworkCollectionName, bson.M{"name": network.Name}); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	} else if count != 0 {
+		response.Conflict(req.Request, resp, fmt.Errorf("Network Name: %s already existed", network.Name))
+		return
+	}
+
+	if err := np.ValidateOverlay(&network); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := ipam.ValidateSubnets(network.Subnets); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	// the physical interfaces should exist on the nodes and can't be shared with other networks
+	if err := np.CheckPhyInterfaces(sp, &network); err != nil {
+		if _, ok := err.(*np.InterfaceError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	// the VNI is used by the overlay tunnels, so allocate it before creating the network
+	if network.OverlayType != "" {
+		vni, err := np.AllocateVNI(session, network.Name)
+		if err != nil {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+		network.VNI = vni
+	}
+
 	networkProvider, err := np.GetNetworkProvider(&network)
 	if err != nil {
+		if network.OverlayType != "" {
+			np.ReleaseVNI(session, network.VNI)
+		}
 		response.BadRequest(req.Request, resp.ResponseWriter, err)
 		return
 	}
 
-	if err := networkProvider.CreateNetwork(sp); err != nil {
-		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+	results, err := networkProvider.CreateNetwork(sp)
+	if err != nil {
+		if network.OverlayType != "" {
+			np.ReleaseVNI(session, network.VNI)
+		}
+		writeNetworkError(req.Request, resp.ResponseWriter, err)
 		return
 	}
 
@@ -64,6 +112,11 @@ func createNetworkHandler(ctx *web.Context) {
 	network.CreatedAt = timeutils.Now()
 	network.OwnerID = bson.ObjectIdHex(userID)
 	if err := session.Insert(entity.NetworkCollectionName, &network); err != nil {
+		// the network isn't saved, so delete it from the nodes
+		networkProvider.DeleteNetwork(sp)
+		if network.OverlayType != "" {
+			np.ReleaseVNI(session, network.VNI)
+		}
 		if mgo.IsDup(err) {
 			response.Conflict(req.Request, resp, fmt.Errorf("Network Name: %s already existed", network.Name))
 		} else {
@@ -74,9 +127,115 @@ func createNetworkHandler(ctx *web.Context) {
 
 	// find owner in user entity
 	network.CreatedBy, _ = backend.FindUserByID(session, network.OwnerID)
+	network.Results = results
 	resp.WriteHeaderAndEntity(http.StatusCreated, network)
 }
 
+func updateNetworkHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	network := entity.Network{}
+	if err := req.ReadEntity(&network); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	c := session.C(entity.NetworkCollectionName)
+
+	var stored entity.Network
+	if err := c.FindId(bson.ObjectIdHex(id)).One(&stored); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	if err := np.ValidateNetworkUpdate(&stored, &network); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	// keep the fields generated when the network is created
+	network.ID = stored.ID
+	network.OwnerID = stored.OwnerID
+	network.BridgeName = stored.BridgeName
+	network.VNI = stored.VNI
+	network.CreatedAt = stored.CreatedAt
+
+	if err := sp.Validator.Struct(network); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if err := ipam.ValidateSubnets(network.Subnets); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	// the leased ip addresses are used by the pods, so the subnets should still have them
+	outside, err := ipam.OutsideLeases(session, &network)
+	if err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if len(outside) != 0 {
+		response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("The ip address %s is leased by the pod %s/%s, it should be in the subnets and not the gateway", outside[0].IPAddress, outside[0].Namespace, outside[0].PodName))
+		return
+	}
+
+	// the physical interfaces should exist on the nodes and can't be shared with other networks
+	if err := np.CheckPhyInterfaces(sp, &network); err != nil {
+		if _, ok := err.(*np.InterfaceError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	// the node can't be removed if the running pods are attached to the network on it
+	usage, err := np.GetNetworkUsage(sp, &stored)
+	if err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	nodes := map[string]bool{}
+	for _, node := range network.Nodes {
+		nodes[node.Name] = true
+	}
+	for nodeName, used := range usage {
+		if used > 0 && !nodes[nodeName] {
+			response.MethodNotAllow(req.Request, resp.ResponseWriter, fmt.Errorf("The Network %s still used by some Pods on node %s, please close those Pod first", stored.Name, nodeName))
+			return
+		}
+	}
+
+	networkProvider, err := np.GetNetworkProvider(&stored)
+	if err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := networkProvider.UpdateNetwork(sp, &network); err != nil {
+		writeNetworkError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	if err := c.UpdateId(network.ID, &network); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	// find owner in user entity
+	network.CreatedBy, _ = backend.FindUserByID(session, network.OwnerID)
+	resp.WriteEntity(network)
+}
+
 func listNetworkHandler(ctx *web.Context) {
 	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
 
@@ -190,6 +349,77 @@ func getNetworkStatusHandler(ctx *web.Context) {
 	resp.WriteEntity(nameList)
 }
 
+func getNetworkHealthHandler(ctx *web.Context) {
+	checkNetworkHealth(ctx, false)
+}
+
+// The drift of the network is repaired on the nodes, so it's the POST instead of the health check
+func repairNetworkHandler(ctx *web.Context) {
+	checkNetworkHealth(ctx, true)
+}
+
+func checkNetworkHealth(ctx *web.Context, repair bool) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	c := session.C(entity.NetworkCollectionName)
+
+	var network entity.Network
+	if err := c.FindId(bson.ObjectIdHex(id)).One(&network); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	health, err := np.CheckNetworkHealth(sp, &network, repair)
+	if err != nil {
+		if _, ok := err.(*np.UnsupportedError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+	resp.WriteEntity(health)
+}
+
+func listNetworkLeasesHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+	c := session.C(entity.NetworkCollectionName)
+
+	var network entity.Network
+	if err := c.FindId(bson.ObjectIdHex(id)).One(&network); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	leases := []entity.IPLease{}
+	if err := session.FindAll(entity.IPLeaseCollectionName, bson.M{"networkName": network.Name}, &leases); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteEntity(leases)
+}
+
 func deleteNetworkHandler(ctx *web.Context) {
 	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
 
@@ -226,11 +456,21 @@ func deleteNetworkHandler(ctx *web.Context) {
 		return
 	}
 
-	if err := networkProvider.DeleteNetwork(sp); err != nil {
-		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+	results, err := networkProvider.DeleteNetwork(sp)
+	if err != nil {
+		writeNetworkError(req.Request, resp.ResponseWriter, err)
 		return
 	}
 
+	if network.OverlayType != "" {
+		np.ReleaseVNI(session, network.VNI)
+	}
+	ipam.ReleaseNetwork(session, network.Name)
+	// the flows and mirrors are deleted with the bridges
+	session.C(entity.NetworkPolicyCollectionName).RemoveAll(bson.M{"networkID": network.ID})
+	session.C(entity.MirrorCollectionName).RemoveAll(bson.M{"networkID": network.ID})
+	networkpolicy.Forget(&network)
+
 	if err := session.Remove(entity.NetworkCollectionName, "_id", bson.ObjectIdHex(id)); err != nil {
 		switch err {
 		case mgo.ErrNotFound:
@@ -242,8 +482,29 @@ func deleteNetworkHandler(ctx *web.Context) {
 		}
 	}
 
-	resp.WriteEntity(response.ActionResponse{
-		Error:   false,
-		Message: "Delete success",
+	resp.WriteEntity(networkActionResponse{
+		ActionResponse: response.ActionResponse{
+			Error:   false,
+			Message: "Delete success",
+		},
+		Results: results,
 	})
 }
+
+// networkActionResponse is the action response with the result of each node
+type networkActionResponse struct {
+	response.ActionResponse
+	Results []entity.NodeResult `json:"results"`
+}
+
+// The NetworkError has the node and step of the failure, so it's written as the details of the error
+func writeNetworkError(req *http.Request, resp http.ResponseWriter, err error) {
+	nerr, ok := err.(*np.NetworkError)
+	if !ok {
+		response.InternalServerError(req, resp, err)
+		return
+	}
+	payload := response.NewErrorPayload(err)
+	payload.Details = nerr
+	response.WritePayload(req, resp, http.StatusInternalServerError, payload)
+}
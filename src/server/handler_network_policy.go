@@ -0,0 +1,173 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/linkernetworks/utils/timeutils"
+	"github.com/linkernetworks/vortex/src/entity"
+	response "github.com/linkernetworks/vortex/src/net/http"
+	"github.com/linkernetworks/vortex/src/networkpolicy"
+	"github.com/linkernetworks/vortex/src/web"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func createNetworkPolicyHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+
+	policy := entity.NetworkPolicy{}
+	if err := req.ReadEntity(&policy); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if err := sp.Validator.Struct(policy); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if err := networkpolicy.Validate(&policy); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	var network entity.Network
+	if err := session.C(entity.NetworkCollectionName).FindId(bson.ObjectIdHex(id)).One(&network); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	// the flows are pushed to the OVS bridges
+	if network.Type != entity.OVSKernelspaceNetworkType && network.Type != entity.OVSUserspaceNetworkType {
+		response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("The network policy is unsupported for the network type %s", network.Type))
+		return
+	}
+
+	session.C(entity.NetworkPolicyCollectionName).EnsureIndex(mgo.Index{
+		Key:    []string{"networkID", "name"},
+		Unique: true,
+	})
+	session.C(entity.NetworkPolicyCollectionName).EnsureIndex(mgo.Index{
+		Key:    []string{"networkID", "priority"},
+		Unique: true,
+	})
+
+	// the flows of the policies with the same priority would have the same OpenFlow priority, which one wins is undefined
+	if count, err := session.Count(entity.NetworkPolicyCollectionName, bson.M{"networkID": network.ID, "priority": policy.Priority}); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	} else if count != 0 {
+		response.Conflict(req.Request, resp, fmt.Errorf("Network Policy Priority: %d already existed", policy.Priority))
+		return
+	}
+
+	policy.ID = bson.NewObjectId()
+	policy.NetworkID = network.ID
+	policy.CreatedAt = timeutils.Now()
+	if err := session.Insert(entity.NetworkPolicyCollectionName, &policy); err != nil {
+		if mgo.IsDup(err) {
+			response.Conflict(req.Request, resp, fmt.Errorf("Network Policy Name: %s or Priority: %d already existed", policy.Name, policy.Priority))
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	if err := networkpolicy.Sync(sp, &network); err != nil {
+		// the policy isn't applied, so remove it and push the flows of the other policies again
+		session.Remove(entity.NetworkPolicyCollectionName, "_id", policy.ID)
+		networkpolicy.Sync(sp, &network)
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusCreated, policy)
+}
+
+func listNetworkPoliciesHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	policies := []entity.NetworkPolicy{}
+	if err := session.FindAll(entity.NetworkPolicyCollectionName, bson.M{"networkID": bson.ObjectIdHex(id)}, &policies); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteEntity(policies)
+}
+
+func getNetworkPolicyHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+	policyID := req.PathParameter("policyID")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	policy := entity.NetworkPolicy{}
+	if err := session.FindOne(entity.NetworkPolicyCollectionName, bson.M{"_id": bson.ObjectIdHex(policyID), "networkID": bson.ObjectIdHex(id)}, &policy); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	resp.WriteEntity(policy)
+}
+
+func deleteNetworkPolicyHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	id := req.PathParameter("id")
+	policyID := req.PathParameter("policyID")
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	var network entity.Network
+	if err := session.C(entity.NetworkCollectionName).FindId(bson.ObjectIdHex(id)).One(&network); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	if err := session.C(entity.NetworkPolicyCollectionName).Remove(bson.M{"_id": bson.ObjectIdHex(policyID), "networkID": network.ID}); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	// the flows are pushed again by the periodic sync if some nodes are unreachable
+	if err := networkpolicy.Sync(sp, &network); err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	resp.WriteEntity(response.ActionResponse{
+		Error:   false,
+		Message: "Delete success",
+	})
+}
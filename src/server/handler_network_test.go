@@ -204,6 +204,68 @@ func (suite *NetworkTestSuite) TestDeleteNetwork() {
 	suite.Equal(err.Error(), mgo.ErrNotFound.Error())
 }
 
+func (suite *NetworkTestSuite) TestUpdateNetwork() {
+	tName := namesgenerator.GetRandomName(0)
+	network := entity.Network{
+		ID:         bson.NewObjectId(),
+		OwnerID:    bson.NewObjectId(),
+		IsDPDKPort: true, //for fake network, true means success,
+		Name:       tName,
+		VlanTags:   []int32{},
+		Type:       entity.FakeNetworkType,
+		BridgeName: namesgenerator.GetRandomName(0),
+		Nodes: []entity.Node{
+			entity.Node{
+				Name:          namesgenerator.GetRandomName(0),
+				PhyInterfaces: []entity.PhyInterface{},
+			},
+		},
+	}
+
+	//Create data into mongo manually
+	suite.session.C(entity.NetworkCollectionName).Insert(network)
+	defer suite.session.Remove(entity.NetworkCollectionName, "name", tName)
+
+	updated := network
+	updated.VlanTags = []int32{100}
+	updated.Nodes = append(updated.Nodes, entity.Node{
+		Name:          namesgenerator.GetRandomName(0),
+		PhyInterfaces: []entity.PhyInterface{{Name: "eth1"}},
+	})
+	bodyBytes, err := json.MarshalIndent(updated, "", "  ")
+	suite.NoError(err)
+
+	httpRequest, err := http.NewRequest("PUT", "http://localhost:7890/v1/networks/"+network.ID.Hex(), strings.NewReader(string(bodyBytes)))
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	stored := entity.Network{}
+	err = suite.session.FindOne(entity.NetworkCollectionName, bson.M{"_id": network.ID}, &stored)
+	suite.NoError(err)
+	suite.Equal(2, len(stored.Nodes))
+	suite.Equal([]int32{100}, stored.VlanTags)
+	suite.Equal(network.BridgeName, stored.BridgeName)
+
+	//The type of network can't be changed
+	updated.Type = entity.OVSKernelspaceNetworkType
+	bodyBytes, err = json.MarshalIndent(updated, "", "  ")
+	suite.NoError(err)
+
+	httpRequest, err = http.NewRequest("PUT", "http://localhost:7890/v1/networks/"+network.ID.Hex(), strings.NewReader(string(bodyBytes)))
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusBadRequest, httpWriter)
+}
+
 func (suite *NetworkTestSuite) TestDeleteEmptyNetwork() {
 	//Remove with non-exist network id
 	httpRequest, err := http.NewRequest("DELETE", "http://localhost:7890/v1/networks/"+bson.NewObjectId().Hex(), nil)
@@ -385,6 +447,64 @@ func (suite *NetworkTestSuite) TestGetNetworkStatus() {
 	suite.NoError(err)
 }
 
+func (suite *NetworkTestSuite) TestGetNetworkHealth() {
+	tName := namesgenerator.GetRandomName(0)
+	nodeName := namesgenerator.GetRandomName(0)
+	network := entity.Network{
+		ID:       bson.NewObjectId(),
+		OwnerID:  bson.NewObjectId(),
+		Name:     tName,
+		VlanTags: []int32{},
+		Type:     entity.FakeNetworkType,
+		Nodes: []entity.Node{
+			entity.Node{
+				Name:          nodeName,
+				PhyInterfaces: []entity.PhyInterface{},
+			},
+		},
+	}
+	//Create data into mongo manually
+	suite.session.C(entity.NetworkCollectionName).Insert(network)
+	defer suite.session.Remove(entity.NetworkCollectionName, "name", tName)
+
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/health", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	health := entity.NetworkHealth{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &health)
+	suite.NoError(err)
+	suite.True(health.Healthy)
+	suite.Equal([]entity.NodeHealth{{Node: nodeName, Healthy: true}}, health.Nodes)
+
+	//Repair the network
+	httpRequest, err = http.NewRequest("POST", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/repair", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	health = entity.NetworkHealth{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &health)
+	suite.NoError(err)
+	suite.True(health.Healthy)
+
+	//The network doesn't exist
+	httpRequest, err = http.NewRequest("POST", "http://localhost:7890/v1/networks/"+bson.NewObjectId().Hex()+"/repair", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusNotFound, httpWriter)
+}
+
 func (suite *NetworkTestSuite) TestListNetwork() {
 	networks := []entity.Network{}
 
@@ -476,3 +596,210 @@ func (suite *NetworkTestSuite) TestListNetworkWithInvalidPage() {
 	suite.wc.Dispatch(httpWriter, httpRequest)
 	assertResponseCode(suite.T(), http.StatusInternalServerError, httpWriter)
 }
+
+func (suite *NetworkTestSuite) TestNetworkPolicies() {
+	tName := namesgenerator.GetRandomName(0)
+	//The network without nodes, the flows aren't pushed to any node
+	network := entity.Network{
+		ID:       bson.NewObjectId(),
+		OwnerID:  bson.NewObjectId(),
+		Name:     tName,
+		VlanTags: []int32{},
+		Type:     entity.OVSKernelspaceNetworkType,
+		Nodes:    []entity.Node{},
+	}
+	suite.session.C(entity.NetworkCollectionName).Insert(network)
+	defer suite.session.Remove(entity.NetworkCollectionName, "name", tName)
+
+	policy := entity.NetworkPolicy{
+		Name:        "deny-db",
+		PodSelector: map[string]string{"app": "db"},
+		Rules: []entity.NetworkPolicyRule{
+			{Action: entity.PolicyActionAllow, Direction: entity.PolicyIngress, PodSelector: map[string]string{"app": "web"}, Protocol: entity.PolicyProtocolTCP, Port: 5432},
+			{Action: entity.PolicyActionDeny, Direction: entity.PolicyIngress},
+		},
+	}
+	bodyBytes, err := json.MarshalIndent(policy, "", "  ")
+	suite.NoError(err)
+
+	httpRequest, err := http.NewRequest("POST", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies", strings.NewReader(string(bodyBytes)))
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusCreated, httpWriter)
+	defer suite.session.Remove(entity.NetworkPolicyCollectionName, "networkID", network.ID)
+
+	created := entity.NetworkPolicy{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &created)
+	suite.NoError(err)
+	suite.Equal(network.ID, created.NetworkID)
+
+	//The name is existed
+	httpRequest, err = http.NewRequest("POST", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies", strings.NewReader(string(bodyBytes)))
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusConflict, httpWriter)
+
+	//The priority is existed
+	policy.Name = "deny-web"
+	bodyBytes, err = json.MarshalIndent(policy, "", "  ")
+	suite.NoError(err)
+	httpRequest, err = http.NewRequest("POST", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies", strings.NewReader(string(bodyBytes)))
+	suite.NoError(err)
+	httpRequest.Header.Add("Content-Type", "application/json")
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusConflict, httpWriter)
+
+	httpRequest, err = http.NewRequest("GET", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	policies := []entity.NetworkPolicy{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &policies)
+	suite.NoError(err)
+	suite.Equal(1, len(policies))
+	suite.Equal(policy.Rules, policies[0].Rules)
+
+	httpRequest, err = http.NewRequest("DELETE", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies/"+created.ID.Hex(), nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	httpRequest, err = http.NewRequest("GET", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies/"+created.ID.Hex(), nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusNotFound, httpWriter)
+}
+
+func (suite *NetworkTestSuite) TestCreateNetworkPolicyFail() {
+	tName := namesgenerator.GetRandomName(0)
+	network := entity.Network{
+		ID:       bson.NewObjectId(),
+		OwnerID:  bson.NewObjectId(),
+		Name:     tName,
+		VlanTags: []int32{},
+		Type:     entity.FakeNetworkType,
+		Nodes:    []entity.Node{},
+	}
+	suite.session.C(entity.NetworkCollectionName).Insert(network)
+	defer suite.session.Remove(entity.NetworkCollectionName, "name", tName)
+
+	testCases := []struct {
+		cases  string
+		policy entity.NetworkPolicy
+	}{
+		{"UnsupportedNetworkType", entity.NetworkPolicy{
+			Name:  "deny-all",
+			Rules: []entity.NetworkPolicyRule{{Action: entity.PolicyActionDeny, Direction: entity.PolicyIngress}},
+		}},
+		{"PortWithoutProtocol", entity.NetworkPolicy{
+			Name:  "deny-all",
+			Rules: []entity.NetworkPolicyRule{{Action: entity.PolicyActionDeny, Direction: entity.PolicyIngress, Protocol: entity.PolicyProtocolICMP, Port: 80}},
+		}},
+		{"InvalidAction", entity.NetworkPolicy{
+			Name:  "deny-all",
+			Rules: []entity.NetworkPolicyRule{{Action: "reject", Direction: entity.PolicyIngress}},
+		}},
+		{"EmptyRules", entity.NetworkPolicy{
+			Name: "deny-all",
+		}},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			bodyBytes, err := json.MarshalIndent(tc.policy, "", "  ")
+			suite.NoError(err)
+
+			httpRequest, err := http.NewRequest("POST", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/policies", strings.NewReader(string(bodyBytes)))
+			suite.NoError(err)
+			httpRequest.Header.Add("Content-Type", "application/json")
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, http.StatusBadRequest, httpWriter)
+		})
+	}
+}
+
+func (suite *NetworkTestSuite) TestCreateMirrorFail() {
+	tName := namesgenerator.GetRandomName(0)
+	network := entity.Network{
+		ID:       bson.NewObjectId(),
+		OwnerID:  bson.NewObjectId(),
+		Name:     tName,
+		VlanTags: []int32{},
+		Type:     entity.OVSKernelspaceNetworkType,
+		Nodes: []entity.Node{
+			entity.Node{
+				Name:          "node1",
+				PhyInterfaces: []entity.PhyInterface{},
+			},
+		},
+	}
+	suite.session.C(entity.NetworkCollectionName).Insert(network)
+	defer suite.session.Remove(entity.NetworkCollectionName, "name", tName)
+
+	testCases := []struct {
+		cases  string
+		mirror entity.Mirror
+		code   int
+	}{
+		{"WithoutOutput", entity.Mirror{Name: "debug", NodeName: "node1", VlanTags: []int32{100}}, http.StatusBadRequest},
+		{"InvalidNode", entity.Mirror{Name: "debug", NodeName: "node2", VlanTags: []int32{100}, OutputPort: "eth3"}, http.StatusBadRequest},
+		{"InvalidTTL", entity.Mirror{Name: "debug", NodeName: "node1", VlanTags: []int32{100}, OutputPort: "eth3", TTL: 1441}, http.StatusBadRequest},
+		{"PodNotFound", entity.Mirror{
+			Name:       "debug",
+			NodeName:   "node1",
+			Sources:    []entity.MirrorPodInterface{{PodName: namesgenerator.GetRandomName(0), Namespace: "default", IfName: "eth1"}},
+			OutputPort: "eth3",
+		}, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			bodyBytes, err := json.MarshalIndent(tc.mirror, "", "  ")
+			suite.NoError(err)
+
+			httpRequest, err := http.NewRequest("POST", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/mirrors", strings.NewReader(string(bodyBytes)))
+			suite.NoError(err)
+			httpRequest.Header.Add("Content-Type", "application/json")
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, tc.code, httpWriter)
+		})
+	}
+
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/mirrors", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	mirrors := []entity.Mirror{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &mirrors)
+	suite.NoError(err)
+	suite.Equal(0, len(mirrors))
+
+	httpRequest, err = http.NewRequest("DELETE", "http://localhost:7890/v1/networks/"+network.ID.Hex()+"/mirrors/"+bson.NewObjectId().Hex(), nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusNotFound, httpWriter)
+}
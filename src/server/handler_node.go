@@ -0,0 +1,30 @@
+package server
+
+import (
+	response "github.com/linkernetworks/vortex/src/net/http"
+	np "github.com/linkernetworks/vortex/src/networkprovider"
+	"github.com/linkernetworks/vortex/src/web"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func listNodeInterfacesHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+	nodeName := req.PathParameter("node")
+
+	if _, err := sp.KubeCtl.GetNode(nodeName); err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	interfaces, err := np.ListNodeInterfaces(sp, nodeName)
+	if err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteEntity(interfaces)
+}
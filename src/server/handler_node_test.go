@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restful "github.com/emicklei/go-restful"
+	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/moby/moby/pkg/namesgenerator"
+	"github.com/stretchr/testify/suite"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type NodeTestSuite struct {
+	suite.Suite
+	sp        *serviceprovider.Container
+	wc        *restful.Container
+	JWTBearer string
+}
+
+func (suite *NodeTestSuite) SetupSuite() {
+	cf := config.MustRead("../../config/testing.json")
+	suite.sp = serviceprovider.NewForTesting(cf)
+
+	//init restful container
+	suite.wc = restful.NewContainer()
+	suite.wc.Add(newNodeService(suite.sp))
+	suite.wc.Add(newUserService(suite.sp))
+
+	token, _ := loginGetToken(suite.wc)
+	suite.NotEmpty(token)
+	suite.JWTBearer = "Bearer " + token
+}
+
+func (suite *NodeTestSuite) TearDownSuite() {}
+
+func TestNodeSuite(t *testing.T) {
+	suite.Run(t, new(NodeTestSuite))
+}
+
+func (suite *NodeTestSuite) TestListNodeInterfaces() {
+	nodeName := namesgenerator.GetRandomName(0)
+	_, err := suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Create(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+	})
+	suite.NoError(err)
+	defer suite.sp.KubeCtl.Clientset.CoreV1().Nodes().Delete(nodeName, &metav1.DeleteOptions{})
+
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/nodes/"+nodeName+"/interfaces", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	//The interfaces are empty and the error is set if the prometheus or the network controller can't be reached
+	interfaces := entity.NodeInterfaces{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &interfaces)
+	suite.NoError(err)
+	suite.Equal(nodeName, interfaces.Node)
+	suite.NotNil(interfaces.Interfaces)
+	if interfaces.MetricsError != "" {
+		suite.Equal(0, len(interfaces.Interfaces))
+	}
+}
+
+func (suite *NodeTestSuite) TestListNodeInterfacesFail() {
+	//The node doesn't exist
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/nodes/"+namesgenerator.GetRandomName(0)+"/interfaces", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusNotFound, httpWriter)
+
+	//The token is required
+	httpRequest, err = http.NewRequest("GET", "http://localhost:7890/v1/nodes/node1/interfaces", nil)
+	suite.NoError(err)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusUnauthorized, httpWriter)
+}
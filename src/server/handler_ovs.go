@@ -2,11 +2,15 @@ package server
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	response "github.com/linkernetworks/vortex/src/net/http"
 	"github.com/linkernetworks/vortex/src/net/http/query"
 	"github.com/linkernetworks/vortex/src/ovscontroller"
 	"github.com/linkernetworks/vortex/src/web"
+
+	"gopkg.in/mgo.v2/bson"
 )
 
 func getOVSPortInfoHandler(ctx *web.Context) {
@@ -33,3 +37,86 @@ func getOVSPortInfoHandler(ctx *web.Context) {
 	}
 	resp.WriteEntity(portStats)
 }
+
+func getOVSPortHistoryHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	//The ports are selected by the pod and interface, or the node, bridge and port name
+	query := query.New(req.Request.URL.Query())
+	q := bson.M{}
+	for _, key := range []string{"podName", "namespace", "interfaceName", "nodeName", "bridgeName", "portName"} {
+		if value, exist := query.Str(key); exist {
+			q[key] = value
+		}
+	}
+	if _, ok := q["podName"]; !ok {
+		if _, ok := q["portName"]; !ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("The podName or portName must not be empty"))
+			return
+		}
+	} else if _, ok := q["namespace"]; !ok {
+		//The pods of the same name in the other namespaces aren't matched
+		q["namespace"] = "default"
+	}
+
+	// the time window in minutes
+	interval, err := query.TimeDuration("interval", 60)
+	if err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	histories, err := ovscontroller.GetPortHistory(session, q, time.Now().Add(-time.Minute*interval))
+	if err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteEntity(histories)
+}
+
+func listOVSPortsHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	query := query.New(req.Request.URL.Query())
+	filter := ovscontroller.PortFilter{}
+	filter.PodName, _ = query.Str("podName")
+	filter.Namespace, _ = query.Str("namespace")
+	filter.NetworkName, _ = query.Str("networkName")
+
+	inventory, err := ovscontroller.ListPorts(sp, filter)
+	if err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	resp.WriteEntity(inventory)
+}
+
+func collectOVSPortsHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	query := query.New(req.Request.URL.Query())
+	dryRun := false
+	if v, ok := query.Str("dryRun"); ok {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+
+	result, err := ovscontroller.FindOrphanedPorts(sp)
+	if err != nil {
+		response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	if !dryRun {
+		if err := ovscontroller.RemoveOrphanedPorts(sp, result.Ports); err != nil {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	resp.WriteEntity(result)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
@@ -12,7 +13,9 @@ import (
 	"github.com/linkernetworks/vortex/src/config"
 	"github.com/linkernetworks/vortex/src/entity"
 	"github.com/linkernetworks/vortex/src/serviceprovider"
+	"github.com/moby/moby/pkg/namesgenerator"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/mgo.v2/bson"
 )
 
 func init() {
@@ -21,10 +24,11 @@ func init() {
 
 type OVSTestSuite struct {
 	suite.Suite
-	sp      *serviceprovider.Container
-	wc      *restful.Container
-	session *mongo.Session
-	storage entity.Storage
+	sp        *serviceprovider.Container
+	wc        *restful.Container
+	session   *mongo.Session
+	storage   entity.Storage
+	JWTBearer string
 }
 
 func (suite *OVSTestSuite) SetupSuite() {
@@ -38,6 +42,11 @@ func (suite *OVSTestSuite) SetupSuite() {
 	suite.wc = restful.NewContainer()
 	service := newOVSService(suite.sp)
 	suite.wc.Add(service)
+	suite.wc.Add(newUserService(suite.sp))
+
+	token, _ := loginGetToken(suite.wc)
+	suite.NotEmpty(token)
+	suite.JWTBearer = "Bearer " + token
 }
 
 func (suite *OVSTestSuite) TearDownSuite() {
@@ -68,3 +77,111 @@ func (suite *OVSTestSuite) TestGetOVSPortStatsFail() {
 	suite.wc.Dispatch(httpWriter, httpRequest)
 	assertResponseCode(suite.T(), http.StatusInternalServerError, httpWriter)
 }
+
+func (suite *OVSTestSuite) TestGetOVSPortHistory() {
+	podName := namesgenerator.GetRandomName(0)
+	now := time.Now()
+	for i, bytes := range []uint64{1000, 4000} {
+		err := suite.session.Insert(entity.OVSPortStatsCollectionName, &entity.OVSPortStatsSample{
+			ID:            bson.NewObjectId(),
+			NodeName:      "node1",
+			BridgeName:    "system-62fc3f",
+			PortName:      "veth7e3a8c21",
+			PodName:       podName,
+			Namespace:     "default",
+			InterfaceName: "eth1",
+			Received:      entity.OVSPortStats{Bytes: bytes},
+			CreatedAt:     now.Add(time.Duration(i-1) * 30 * time.Second),
+		})
+		suite.NoError(err)
+	}
+	defer suite.session.Remove(entity.OVSPortStatsCollectionName, "podName", podName)
+
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/ovs/portinfos/history?podName="+podName+"&interfaceName=eth1&interval=10", nil)
+	suite.NoError(err)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	histories := []entity.OVSPortHistory{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &histories)
+	suite.NoError(err)
+	suite.Equal(1, len(histories))
+	suite.Equal("veth7e3a8c21", histories[0].PortName)
+	suite.Equal("default", histories[0].Namespace)
+	suite.Equal(1, len(histories[0].Points))
+	suite.Equal(float64(100), histories[0].Points[0].Received.BytesPerSec)
+
+	//The pod of the same name in the other namespace
+	httpRequest, err = http.NewRequest("GET", "http://localhost:7890/v1/ovs/portinfos/history?podName="+podName+"&namespace=other&interval=10", nil)
+	suite.NoError(err)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	histories = []entity.OVSPortHistory{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &histories)
+	suite.NoError(err)
+	suite.Equal(0, len(histories))
+}
+
+func (suite *OVSTestSuite) TestGetOVSPortHistoryFail() {
+	//The podName or portName is required
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/ovs/portinfos/history?nodeName=node1", nil)
+	suite.NoError(err)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusBadRequest, httpWriter)
+
+	httpRequest, err = http.NewRequest("GET", "http://localhost:7890/v1/ovs/portinfos/history?podName=pod1&interval=abc", nil)
+	suite.NoError(err)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusBadRequest, httpWriter)
+}
+
+func (suite *OVSTestSuite) TestListOVSPorts() {
+	//There's no network with the name
+	httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/ovs/ports?networkName="+namesgenerator.GetRandomName(0), nil)
+	suite.NoError(err)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	inventory := entity.OVSPortInventory{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &inventory)
+	suite.NoError(err)
+	suite.Equal(0, len(inventory.Ports))
+	suite.Equal(0, len(inventory.Errors))
+}
+
+func (suite *OVSTestSuite) TestCollectOVSPorts() {
+	httpRequest, err := http.NewRequest("POST", "http://localhost:7890/v1/ovs/gc?dryRun=true", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusOK, httpWriter)
+
+	result := entity.OVSPortGCResult{}
+	err = json.Unmarshal(httpWriter.Body.Bytes(), &result)
+	suite.NoError(err)
+	for _, port := range result.Ports {
+		suite.False(port.Removed)
+	}
+
+	httpRequest, err = http.NewRequest("POST", "http://localhost:7890/v1/ovs/gc?dryRun=abc", nil)
+	suite.NoError(err)
+	httpRequest.Header.Add("Authorization", suite.JWTBearer)
+	httpWriter = httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusBadRequest, httpWriter)
+}
+
+func (suite *OVSTestSuite) TestCollectOVSPortsWithoutToken() {
+	httpRequest, err := http.NewRequest("POST", "http://localhost:7890/v1/ovs/gc?dryRun=true", nil)
+	suite.NoError(err)
+	httpWriter := httptest.NewRecorder()
+	suite.wc.Dispatch(httpWriter, httpRequest)
+	assertResponseCode(suite.T(), http.StatusUnauthorized, httpWriter)
+}
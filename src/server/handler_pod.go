@@ -5,9 +5,13 @@ import (
 	"math"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/utils/timeutils"
+	"github.com/linkernetworks/vortex/src/container"
 	"github.com/linkernetworks/vortex/src/entity"
+	"github.com/linkernetworks/vortex/src/kubeutils"
 	response "github.com/linkernetworks/vortex/src/net/http"
 	"github.com/linkernetworks/vortex/src/net/http/query"
 	"github.com/linkernetworks/vortex/src/pod"
@@ -60,6 +64,8 @@ func createPodHandler(ctx *web.Context) {
 			response.Conflict(req.Request, resp.ResponseWriter, fmt.Errorf("Create setting has conflict: %v", err))
 		} else if errors.IsInvalid(err) {
 			response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("Create setting is invalid: %v", err))
+		} else if _, ok := err.(*kubeutils.ResourceError); ok {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
 		} else {
 			response.InternalServerError(req.Request, resp.ResponseWriter, err)
 		}
@@ -198,3 +204,66 @@ func getPodHandler(ctx *web.Context) {
 	pod.CreatedBy, _ = backend.FindUserByID(session, pod.OwnerID)
 	resp.WriteEntity(pod)
 }
+
+func capturePodHandler(ctx *web.Context) {
+	sp, req, resp := ctx.ServiceProvider, ctx.Request, ctx.Response
+
+	id := req.PathParameter("id")
+	query := query.New(req.Request.URL.Query())
+	opts := pod.CaptureOptions{}
+	var exist bool
+	if opts.IfName, exist = query.Str("ifName"); !exist {
+		response.BadRequest(req.Request, resp.ResponseWriter, fmt.Errorf("The ifName must not be empty"))
+		return
+	}
+	opts.Filter, _ = query.Str("filter")
+	if v, ok := query.Str("duration"); ok {
+		var err error
+		if opts.Duration, err = time.ParseDuration(v); err != nil {
+			response.BadRequest(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	maxBytes, err := query.Int64("maxBytes", 0)
+	if err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+	opts.MaxBytes = maxBytes
+
+	session := sp.Mongo.NewSession()
+	defer session.Close()
+
+	var p entity.Pod
+	if err := session.C(entity.PodCollectionName).FindId(bson.ObjectIdHex(id)).One(&p); err != nil {
+		switch err {
+		case mgo.ErrNotFound:
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+			return
+		default:
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+			return
+		}
+	}
+	if err := pod.CheckCaptureOptions(&p, &opts); err != nil {
+		response.BadRequest(req.Request, resp.ResponseWriter, err)
+		return
+	}
+
+	stream, err := pod.Capture(sp, &p, opts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			response.NotFound(req.Request, resp.ResponseWriter, err)
+		} else if err == pod.ErrTooManyCaptures {
+			response.TooManyRequests(req.Request, resp.ResponseWriter, err)
+		} else {
+			response.InternalServerError(req.Request, resp.ResponseWriter, err)
+		}
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-%s-%s.pcap", p.Name, opts.IfName, time.Now().Format("20060102150405"))
+	if err := container.HandleFileDownload(resp, stream, "application/vnd.tcpdump.pcap", fileName); err != nil {
+		logger.Warnf("stream the capture of pod %s fail: %v", p.Name, err)
+	}
+}
@@ -263,6 +263,46 @@ func (suite *PodTestSuite) TestGetPodWithInvalidID() {
 	assertResponseCode(suite.T(), http.StatusNotFound, httpWriter)
 }
 
+func (suite *PodTestSuite) TestCapturePodFail() {
+	tName := namesgenerator.GetRandomName(0)
+	pod := entity.Pod{
+		ID:        bson.NewObjectId(),
+		Name:      tName,
+		Namespace: "default",
+		Networks: []entity.PodNetwork{
+			{Name: "ovs", IfName: "eth1", NetworkType: entity.OVSKernelspaceNetworkType},
+		},
+	}
+	suite.session.C(entity.PodCollectionName).Insert(pod)
+	defer suite.session.Remove(entity.PodCollectionName, "name", tName)
+
+	testCases := []struct {
+		cases     string
+		id        string
+		query     string
+		errorCode int
+	}{
+		{"WithoutIfName", pod.ID.Hex(), "", http.StatusBadRequest},
+		{"InvalidDuration", pod.ID.Hex(), "ifName=eth1&duration=30", http.StatusBadRequest},
+		{"InvalidMaxBytes", pod.ID.Hex(), "ifName=eth1&maxBytes=abc", http.StatusBadRequest},
+		{"TooLong", pod.ID.Hex(), "ifName=eth1&duration=1h", http.StatusBadRequest},
+		{"UnknownInterface", pod.ID.Hex(), "ifName=eth9", http.StatusBadRequest},
+		{"PodNotFound", bson.NewObjectId().Hex(), "ifName=eth1", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		suite.T().Run(tc.cases, func(t *testing.T) {
+			httpRequest, err := http.NewRequest("GET", "http://localhost:7890/v1/pods/"+tc.id+"/capture?"+tc.query, nil)
+			suite.NoError(err)
+
+			httpRequest.Header.Add("Authorization", suite.JWTBearer)
+			httpWriter := httptest.NewRecorder()
+			suite.wc.Dispatch(httpWriter, httpRequest)
+			assertResponseCode(t, tc.errorCode, httpWriter)
+		})
+	}
+}
+
 func (suite *PodTestSuite) TestListPod() {
 	namespace := "default"
 	pods := []entity.Pod{}
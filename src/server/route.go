@@ -29,6 +29,8 @@ func (a *App) AppRoute() *mux.Router {
 	container.Add(newMonitoringService(a.ServiceProvider))
 	container.Add(newAppService(a.ServiceProvider))
 	container.Add(newOVSService(a.ServiceProvider))
+	container.Add(newIPAMService(a.ServiceProvider))
+	container.Add(newNodeService(a.ServiceProvider))
 
 	router.PathPrefix("/v1/").Handler(container)
 	return router
@@ -73,11 +75,39 @@ func newNetworkService(sp *serviceprovider.Container) *restful.WebService {
 	webService.Route(webService.GET("/").To(handler.RESTfulServiceHandler(sp, listNetworkHandler)))
 	webService.Route(webService.GET("/{id}").To(handler.RESTfulServiceHandler(sp, getNetworkHandler)))
 	webService.Route(webService.GET("/status/{id}").To(handler.RESTfulServiceHandler(sp, getNetworkStatusHandler)))
+	webService.Route(webService.GET("/{id}/health").To(handler.RESTfulServiceHandler(sp, getNetworkHealthHandler)))
+	webService.Route(webService.POST("/{id}/repair").To(handler.RESTfulServiceHandler(sp, repairNetworkHandler)))
+	webService.Route(webService.GET("/{id}/leases").To(handler.RESTfulServiceHandler(sp, listNetworkLeasesHandler)))
+	webService.Route(webService.GET("/{id}/policies").To(handler.RESTfulServiceHandler(sp, listNetworkPoliciesHandler)))
+	webService.Route(webService.GET("/{id}/policies/{policyID}").To(handler.RESTfulServiceHandler(sp, getNetworkPolicyHandler)))
+	webService.Route(webService.POST("/{id}/policies").To(handler.RESTfulServiceHandler(sp, createNetworkPolicyHandler)))
+	webService.Route(webService.DELETE("/{id}/policies/{policyID}").To(handler.RESTfulServiceHandler(sp, deleteNetworkPolicyHandler)))
+	webService.Route(webService.GET("/{id}/mirrors").To(handler.RESTfulServiceHandler(sp, listMirrorsHandler)))
+	webService.Route(webService.GET("/{id}/mirrors/{mirrorID}").To(handler.RESTfulServiceHandler(sp, getMirrorHandler)))
+	webService.Route(webService.POST("/{id}/mirrors").To(handler.RESTfulServiceHandler(sp, createMirrorHandler)))
+	webService.Route(webService.DELETE("/{id}/mirrors/{mirrorID}").To(handler.RESTfulServiceHandler(sp, deleteMirrorHandler)))
 	webService.Route(webService.POST("/").To(handler.RESTfulServiceHandler(sp, createNetworkHandler)))
+	webService.Route(webService.PUT("/{id}").To(handler.RESTfulServiceHandler(sp, updateNetworkHandler)))
 	webService.Route(webService.DELETE("/{id}").To(handler.RESTfulServiceHandler(sp, deleteNetworkHandler)))
 	return webService
 }
 
+// The init containers of the deployment lease the ip addresses with the lease token of the deployment instead of the user token
+func newIPAMService(sp *serviceprovider.Container) *restful.WebService {
+	webService := new(restful.WebService)
+	webService.Path("/v1/ipam").Consumes(restful.MIME_JSON, "application/x-www-form-urlencoded").Produces(restful.MIME_JSON, "text/plain")
+	webService.Route(webService.POST("/leases").To(handler.RESTfulServiceHandler(sp, leaseIPHandler)))
+	return webService
+}
+
+func newNodeService(sp *serviceprovider.Container) *restful.WebService {
+	webService := new(restful.WebService)
+	webService.Path("/v1/nodes").Consumes(restful.MIME_JSON, restful.MIME_JSON).Produces(restful.MIME_JSON, restful.MIME_JSON)
+	webService.Filter(validateTokenMiddleware)
+	webService.Route(webService.GET("/{node}/interfaces").To(handler.RESTfulServiceHandler(sp, listNodeInterfacesHandler)))
+	return webService
+}
+
 func newStorageService(sp *serviceprovider.Container) *restful.WebService {
 	webService := new(restful.WebService)
 	webService.Path("/v1/storage").Consumes(restful.MIME_JSON, restful.MIME_JSON).Produces(restful.MIME_JSON, restful.MIME_JSON)
@@ -114,6 +144,7 @@ func newPodService(sp *serviceprovider.Container) *restful.WebService {
 	webService.Route(webService.DELETE("/{id}").To(handler.RESTfulServiceHandler(sp, deletePodHandler)))
 	webService.Route(webService.GET("/").To(handler.RESTfulServiceHandler(sp, listPodHandler)))
 	webService.Route(webService.GET("/{id}").To(handler.RESTfulServiceHandler(sp, getPodHandler)))
+	webService.Route(webService.GET("/{id}/capture").To(handler.RESTfulServiceHandler(sp, capturePodHandler)))
 	return webService
 }
 
@@ -122,6 +153,12 @@ func newDeploymentService(sp *serviceprovider.Container) *restful.WebService {
 	webService.Filter(validateTokenMiddleware)
 	webService.Path("/v1/deployments").Consumes(restful.MIME_JSON, restful.MIME_JSON).Produces(restful.MIME_JSON, restful.MIME_JSON)
 	webService.Route(webService.POST("/").To(handler.RESTfulServiceHandler(sp, createDeploymentHandler)))
+	webService.Route(webService.PUT("/{id}").To(handler.RESTfulServiceHandler(sp, updateDeploymentHandler)))
+	webService.Route(webService.GET("/{id}/revisions").To(handler.RESTfulServiceHandler(sp, listDeploymentRevisionsHandler)))
+	webService.Route(webService.POST("/{id}/rollback").To(handler.RESTfulServiceHandler(sp, rollbackDeploymentHandler)))
+	webService.Route(webService.PUT("/{id}/scale").To(handler.RESTfulServiceHandler(sp, scaleDeploymentHandler)))
+	webService.Route(webService.PUT("/{id}/autoscaler").To(handler.RESTfulServiceHandler(sp, setDeploymentAutoscalerHandler)))
+	webService.Route(webService.DELETE("/{id}/autoscaler").To(handler.RESTfulServiceHandler(sp, deleteDeploymentAutoscalerHandler)))
 	webService.Route(webService.DELETE("/{id}").To(handler.RESTfulServiceHandler(sp, deleteDeploymentHandler)))
 	webService.Route(webService.GET("/").To(handler.RESTfulServiceHandler(sp, listDeploymentHandler)))
 	webService.Route(webService.GET("/{id}").To(handler.RESTfulServiceHandler(sp, getDeploymentHandler)))
@@ -184,5 +221,8 @@ func newOVSService(sp *serviceprovider.Container) *restful.WebService {
 	webService := new(restful.WebService)
 	webService.Path("/v1/ovs").Consumes(restful.MIME_JSON, restful.MIME_JSON).Produces(restful.MIME_JSON, restful.MIME_JSON)
 	webService.Route(webService.GET("/portinfos").To(handler.RESTfulServiceHandler(sp, getOVSPortInfoHandler)))
+	webService.Route(webService.GET("/portinfos/history").To(handler.RESTfulServiceHandler(sp, getOVSPortHistoryHandler)))
+	webService.Route(webService.GET("/ports").To(handler.RESTfulServiceHandler(sp, listOVSPortsHandler)))
+	webService.Route(webService.POST("/gc").Filter(validateTokenMiddleware).Filter(rootRole).To(handler.RESTfulServiceHandler(sp, collectOVSPortsHandler)))
 	return webService
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/linkernetworks/logger"
 	"github.com/linkernetworks/vortex/src/config"
+	"github.com/linkernetworks/vortex/src/networkcontroller"
 	"github.com/linkernetworks/vortex/src/prometheusprovider"
 
 	"github.com/linkernetworks/mongo"
@@ -21,11 +22,12 @@ import (
 
 // Container is the structure for container
 type Container struct {
-	Config     config.Config
-	Mongo      *mongo.Service
-	Prometheus *prometheusprovider.Service
-	KubeCtl    *kubeCtl.KubeCtl
-	Validator  *validator.Validate
+	Config             config.Config
+	Mongo              *mongo.Service
+	Prometheus         *prometheusprovider.Service
+	KubeCtl            *kubeCtl.KubeCtl
+	Validator          *validator.Validate
+	NetworkControllers *networkcontroller.Pool
 }
 
 // ServiceDiscoverResponse is the structure for Service Discover Response
@@ -58,16 +60,24 @@ func New(cf config.Config) *Container {
 
 	clientset := kubernetes.NewForConfigOrDie(k8s)
 
+	dialOpts, err := networkcontroller.DialOptions(cf.NetworkController)
+	if err != nil {
+		panic(err)
+	}
+
 	validate := validator.New()
 	// Register validation for kubernetes name
 	validate.RegisterValidation("k8sname", checkNameValidation)
+	// Register validation for the ipv4 netmask or the prefix length
+	validate.RegisterValidation("netmask", checkNetmaskValidation)
 
 	sp := &Container{
-		Config:     cf,
-		Mongo:      mongo,
-		Prometheus: prometheus,
-		KubeCtl:    kubeCtl.New(clientset),
-		Validator:  validate,
+		Config:             cf,
+		Mongo:              mongo,
+		Prometheus:         prometheus,
+		KubeCtl:            kubeCtl.New(clientset),
+		Validator:          validate,
+		NetworkControllers: networkcontroller.NewPool(networkcontroller.DefaultTimeout, dialOpts...),
 	}
 
 	if err := createDefaultUser(sp.Mongo); err != nil {
@@ -90,21 +100,34 @@ func NewForTesting(cf config.Config) *Container {
 
 	clientset := fakeclientset.NewSimpleClientset()
 
+	dialOpts, err := networkcontroller.DialOptions(cf.NetworkController)
+	if err != nil {
+		panic(err)
+	}
+
 	validate := validator.New()
 	// Register validation for kubernetes name
 	validate.RegisterValidation("k8sname", checkNameValidation)
+	// Register validation for the ipv4 netmask or the prefix length
+	validate.RegisterValidation("netmask", checkNetmaskValidation)
 
 	sp := &Container{
-		Config:     cf,
-		Mongo:      mongo,
-		Prometheus: prometheus,
-		KubeCtl:    kubeCtl.New(clientset),
-		Validator:  validate,
+		Config:             cf,
+		Mongo:              mongo,
+		Prometheus:         prometheus,
+		KubeCtl:            kubeCtl.New(clientset),
+		Validator:          validate,
+		NetworkControllers: networkcontroller.NewPool(networkcontroller.DefaultTimeout, dialOpts...),
 	}
 
 	return sp
 }
 
+// Close will release the connections held by the container
+func (sp *Container) Close() error {
+	return sp.NetworkControllers.Close()
+}
+
 // NewContainer will new a container
 func NewContainer(configPath string) *Container {
 	cf := config.MustRead(configPath)
@@ -1,6 +1,7 @@
 package serviceprovider
 
 import (
+	"github.com/linkernetworks/vortex/src/utils"
 	"gopkg.in/go-playground/validator.v9"
 	"regexp"
 )
@@ -9,3 +10,7 @@ func checkNameValidation(fl validator.FieldLevel) bool {
 	re := regexp.MustCompile(`[a-z0-9]([-a-z0-9]*[a-z0-9])`)
 	return re.MatchString(fl.Field().String())
 }
+
+func checkNetmaskValidation(fl validator.FieldLevel) bool {
+	return utils.IsNetmask(fl.Field().String())
+}
@@ -11,6 +11,7 @@ var validate *validator.Validate
 func init() {
 	validate = validator.New()
 	validate.RegisterValidation("k8sname", checkNameValidation)
+	validate.RegisterValidation("netmask", checkNetmaskValidation)
 }
 
 func TestCheckNameValidation(t *testing.T) {
@@ -28,3 +29,12 @@ func TestCheckNameValidationFail(t *testing.T) {
 	err := validate.Var(name, "required,k8sname")
 	assert.Error(t, err)
 }
+
+func TestCheckNetmaskValidation(t *testing.T) {
+	for _, netmask := range []string{"255.255.255.0", "24", "64", "128"} {
+		assert.NoError(t, validate.Var(netmask, "required,netmask"))
+	}
+	for _, netmask := range []string{"255.0.255.0", "129", "-1", "ffff::"} {
+		assert.Error(t, validate.Var(netmask, "required,netmask"))
+	}
+}
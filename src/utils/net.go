@@ -3,11 +3,65 @@ package utils
 import (
 	"fmt"
 	"net"
+	"strconv"
 )
 
 // IPToCIDR will do like 0.0.0.0/255.255.255.0 to 0.0.0.0/24
+// The netmask can also be the prefix length, e.g. 2001:db8::1 and 64 to 2001:db8::1/64
 func IPToCIDR(ip string, netmask string) string {
+	if size, err := strconv.Atoi(netmask); err == nil {
+		return fmt.Sprintf("%s/%d", ip, size)
+	}
 	mask := net.IPMask(net.ParseIP(netmask).To4())
 	size, _ := mask.Size()
 	return fmt.Sprintf("%s/%d", ip, size)
 }
+
+// IPFamily returns 4 or 6 by the ip address or the cidr, and 0 if it's invalid
+func IPFamily(addr string) int {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		var err error
+		if ip, _, err = net.ParseCIDR(addr); err != nil {
+			return 0
+		}
+	}
+	if ip.To4() != nil {
+		return 4
+	}
+	return 6
+}
+
+// IsNetmask checks the netmask is the dotted ipv4 netmask like 255.255.255.0 or the prefix length like 64
+func IsNetmask(netmask string) bool {
+	if size, err := strconv.Atoi(netmask); err == nil {
+		return size >= 0 && size <= 8*net.IPv6len
+	}
+	ip := net.ParseIP(netmask).To4()
+	if ip == nil {
+		return false
+	}
+	_, bits := net.IPMask(ip).Size()
+	return bits != 0
+}
+
+// CheckNetmask checks the netmask is for the address family of the ip address,
+// the ipv6 address only has the prefix length
+func CheckNetmask(ip string, netmask string) error {
+	if !IsNetmask(netmask) {
+		return fmt.Errorf("the netmask %s is invalid", netmask)
+	}
+	switch IPFamily(ip) {
+	case 4:
+		if size, err := strconv.Atoi(netmask); err == nil && size > 8*net.IPv4len {
+			return fmt.Errorf("the prefix length %s is too long for the ipv4 address %s", netmask, ip)
+		}
+	case 6:
+		if _, err := strconv.Atoi(netmask); err != nil {
+			return fmt.Errorf("the netmask of the ipv6 address %s should be the prefix length", ip)
+		}
+	default:
+		return fmt.Errorf("the ip address %s is invalid", ip)
+	}
+	return nil
+}
@@ -12,3 +12,28 @@ func TestIPToCIDR(t *testing.T) {
 	c := IPToCIDR(ip, netmask)
 	assert.Equal(t, c, "1.2.3.4/20")
 }
+
+func TestIPToCIDRWithPrefixLength(t *testing.T) {
+	assert.Equal(t, "1.2.3.4/20", IPToCIDR("1.2.3.4", "20"))
+	assert.Equal(t, "2001:db8::10/64", IPToCIDR("2001:db8::10", "64"))
+}
+
+func TestIPFamily(t *testing.T) {
+	assert.Equal(t, 4, IPFamily("1.2.3.4"))
+	assert.Equal(t, 4, IPFamily("10.0.0.0/8"))
+	assert.Equal(t, 6, IPFamily("2001:db8::1"))
+	assert.Equal(t, 6, IPFamily("2001:db8::/64"))
+	assert.Equal(t, 0, IPFamily("1.2.3"))
+}
+
+func TestCheckNetmask(t *testing.T) {
+	assert.NoError(t, CheckNetmask("1.2.3.4", "255.255.255.0"))
+	assert.NoError(t, CheckNetmask("1.2.3.4", "24"))
+	assert.NoError(t, CheckNetmask("2001:db8::10", "64"))
+
+	assert.Error(t, CheckNetmask("1.2.3.4", "255.0.255.0"))
+	assert.Error(t, CheckNetmask("1.2.3.4", "64"))
+	assert.Error(t, CheckNetmask("2001:db8::10", "255.255.255.0"))
+	assert.Error(t, CheckNetmask("2001:db8::10", "129"))
+	assert.Error(t, CheckNetmask("1.2.3", "24"))
+}